@@ -1,6 +1,7 @@
 package api_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -14,6 +15,8 @@ import (
 	"github.com/snyk/go-application-framework/internal/api"
 	"github.com/snyk/go-application-framework/internal/api/contract"
 	"github.com/snyk/go-application-framework/internal/constants"
+	"github.com/snyk/go-application-framework/pkg/configuration"
+	"github.com/snyk/go-application-framework/pkg/networking/middleware"
 )
 
 func Test_GetDefaultOrgId_ReturnsCorrectOrgId(t *testing.T) {
@@ -118,6 +121,45 @@ func Test_GetFeatureFlag_true(t *testing.T) {
 	assert.True(t, actual)
 }
 
+func Test_GetFeatureFlag_withResponseMiddleware(t *testing.T) {
+	t.Parallel()
+
+	org := "myOrg"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/cli-config/feature-flags/disabledFlag":
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = fmt.Fprint(w, `{"ok":false,"userMessage":"Org myOrg doesn't have 'disabledFlag' feature enabled","code":"403"}`)
+		case "/v1/cli-config/feature-flags/enabledFlag":
+			_, _ = fmt.Fprint(w, `{"ok":true}`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	config := configuration.NewInMemory()
+	config.Set(configuration.API_URL, server.URL)
+	errHandler := func(err error, _ context.Context) error {
+		return err
+	}
+	client := &http.Client{Transport: middleware.NewReponseMiddleware(http.DefaultTransport, config, errHandler)}
+	apiClient := api.NewApi(server.URL, client)
+
+	actual, err := apiClient.GetFeatureFlag("disabledFlag", org)
+	assert.NoError(t, err)
+	assert.False(t, actual)
+
+	actual, err = apiClient.GetFeatureFlag("enabledFlag", org)
+	assert.NoError(t, err)
+	assert.True(t, actual)
+
+	// other error responses are still mapped to errors
+	actual, err = apiClient.GetFeatureFlag("failingFlag", org)
+	assert.Error(t, err)
+	assert.False(t, actual)
+}
+
 func newMockOrgSlugResponse(t *testing.T) contract.GetOrganizationResponse {
 	t.Helper()
 	slugJson := `
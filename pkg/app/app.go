@@ -22,6 +22,8 @@ import (
 	"github.com/snyk/go-application-framework/pkg/auth"
 	"github.com/snyk/go-application-framework/pkg/configuration"
 	localworkflows "github.com/snyk/go-application-framework/pkg/local_workflows"
+	"github.com/snyk/go-application-framework/pkg/local_workflows/config_utils"
+	"github.com/snyk/go-application-framework/pkg/networking/middleware"
 	pkg_utils "github.com/snyk/go-application-framework/pkg/utils"
 	"github.com/snyk/go-application-framework/pkg/workflow"
 )
@@ -198,6 +200,11 @@ func initConfiguration(engine workflow.Engine, config configuration.Configuratio
 
 	// set default filesize threshold to 512MB
 	config.AddDefaultValue(configuration.IN_MEMORY_THRESHOLD_BYTES, configuration.StandardDefaultValueFunction(constants.SNYK_DEFAULT_IN_MEMORY_THRESHOLD_MB))
+	config.AddDefaultValue(configuration.MAX_RETRY_ATTEMPTS, configuration.StandardDefaultValueFunction(middleware.DefaultRetryOptions().MaxAttempts))
+	config.AddDefaultValue(configuration.CIRCUIT_BREAKER_THRESHOLD, configuration.StandardDefaultValueFunction(middleware.DefaultCircuitBreakerThreshold))
+	config.AddDefaultValue(configuration.CIRCUIT_BREAKER_COOLDOWN_SECS, configuration.StandardDefaultValueFunction(int(middleware.DefaultCircuitBreakerCooldown.Seconds())))
+	config.AddDefaultValue(configuration.OAUTH_REFRESH_WINDOW_SECS, configuration.StandardDefaultValueFunction(int(auth.DefaultRefreshWindow.Seconds())))
+	config.AddDefaultValue(configuration.MAX_IDLE_CONNECTIONS_PER_HOST, configuration.StandardDefaultValueFunction(middleware.DefaultMaxIdleConnectionsPerHost))
 	config.AddDefaultValue(configuration.API_URL, defaultFuncApiUrl(config, logger))
 	config.AddDefaultValue(configuration.TEMP_DIR_PATH, defaultTempDirectory(engine, config, logger))
 
@@ -209,10 +216,20 @@ func initConfiguration(engine workflow.Engine, config configuration.Configuratio
 		}
 
 		return appUrl, nil
-	})
-
-	config.AddDefaultValue(configuration.ORGANIZATION, defaultFuncOrganization(engine, config, logger, apiClientFactory))
-	config.AddDefaultValue(configuration.ORGANIZATION_SLUG, defaultFuncOrganizationSlug(engine, config, logger, apiClientFactory))
+	}, configuration.WithDependencies(configuration.API_URL))
+
+	config.AddDefaultValue(configuration.ORGANIZATION, defaultFuncOrganization(engine, config, logger, apiClientFactory),
+		configuration.WithMemoization(configuration.DefaultMemoizationTTL),
+		// the organization is derived from the account of the credentials
+		configuration.WithDependencies(configuration.API_URL, configuration.AUTHENTICATION_TOKEN, configuration.AUTHENTICATION_BEARER_TOKEN, auth.CONFIG_KEY_OAUTH_TOKEN),
+		configuration.WithPrefetch(),
+		configuration.WithOfflineFallback(),
+	)
+	config.AddDefaultValue(configuration.ORGANIZATION_SLUG, defaultFuncOrganizationSlug(engine, config, logger, apiClientFactory),
+		configuration.WithMemoization(configuration.DefaultMemoizationTTL),
+		configuration.WithDependencies(configuration.ORGANIZATION),
+		configuration.WithOfflineFallback(),
+	)
 
 	config.AddDefaultValue(configuration.FF_OAUTH_AUTH_FLOW_ENABLED, func(existingValue any) (any, error) {
 		if existingValue == nil {
@@ -228,13 +245,35 @@ func initConfiguration(engine workflow.Engine, config configuration.Configuratio
 		} else {
 			return existingValue, nil
 		}
-	})
+	}, configuration.WithDependencies(configuration.API_URL))
+
+	addEnvVarMappings(config)
 
 	config.AddDefaultValue(configuration.INPUT_DIRECTORY, defaultInputDirectory())
 	config.AddDefaultValue(configuration.PREVIEW_FEATURES_ENABLED, defaultPreviewFeaturesEnabled(engine, logger))
 	config.AddDefaultValue(configuration.CUSTOM_CONFIG_FILES, customConfigFiles(config))
 }
 
+// addEnvVarMappings registers the environment variables supported by the framework, see
+// configuration.EnvVarMappingsToMarkdown to generate their documentation.
+func addEnvVarMappings(config configuration.Configuration) {
+	config.AddEnvVarMapping("SNYK_TOKEN", configuration.AUTHENTICATION_TOKEN, "Snyk API token used for authentication")
+	config.AddEnvVarMapping("SNYK_CFG_API", configuration.AUTHENTICATION_TOKEN, "Alternative to SNYK_TOKEN")
+	config.AddEnvVarMapping("SNYK_OAUTH_TOKEN", configuration.AUTHENTICATION_BEARER_TOKEN, "OAuth access token used for authentication")
+	config.AddEnvVarMapping("SNYK_API", configuration.API_URL, "URL of the Snyk API")
+	config.AddEnvVarMapping("SNYK_CFG_ENDPOINT", configuration.API_URL, "Alternative to SNYK_API")
+	config.AddEnvVarMapping("SNYK_CFG_ORG", configuration.ORGANIZATION, "Organization ID or slug")
+	config.AddEnvVarMapping("SNYK_INTEGRATION_NAME", configuration.INTEGRATION_NAME, "Name of the integration")
+	config.AddEnvVarMapping("SNYK_INTEGRATION_VERSION", configuration.INTEGRATION_VERSION, "Version of the integration")
+	config.AddEnvVarMapping("SNYK_INTEGRATION_ENVIRONMENT", configuration.INTEGRATION_ENVIRONMENT, "Name of the environment the integration runs in, e.g. an IDE")
+	config.AddEnvVarMapping("SNYK_INTEGRATION_ENVIRONMENT_VERSION", configuration.INTEGRATION_ENVIRONMENT_VERSION, "Version of the environment the integration runs in")
+	config.AddEnvVarMapping("SNYK_DISABLE_ANALYTICS", configuration.ANALYTICS_DISABLED, "Disables sending analytics")
+	config.AddEnvVarMapping("SNYK_TMP_PATH", configuration.TEMP_DIR_PATH, "Directory for temporary files")
+	config.AddEnvVarMapping("SNYK_CACHE_PATH", configuration.CACHE_PATH, "Directory for cached files")
+	config.AddEnvVarMapping("SNYK_TIMEOUT_SECS", configuration.TIMEOUT, "Timeout in seconds")
+	config.AddEnvVarMapping("SNYK_LOG_LEVEL", configuration.LOG_LEVEL, "Log level, e.g. debug or trace")
+}
+
 func customConfigFiles(config configuration.Configuration) configuration.DefaultValueFunction {
 	return func(existingValue interface{}) (interface{}, error) {
 		var files []string
@@ -282,6 +321,7 @@ func CreateAppEngineWithOptions(opts ...Opts) workflow.Engine {
 		initConfiguration(engine, config, engine.GetLogger(), nil)
 	}
 
+	engine.AddExtensionInitializer(config_utils.InitPolicy)
 	engine.AddExtensionInitializer(localworkflows.Init)
 	return engine
 }
@@ -150,6 +150,41 @@ func Test_initConfiguration_updateDefaultOrgId(t *testing.T) {
 	assert.Equal(t, orgName, actualOrgSlug)
 }
 
+func Test_initConfiguration_envVarMappings(t *testing.T) {
+	orgName := "someOrgName"
+	orgId := "someOrgId"
+
+	ctrl := gomock.NewController(t)
+	mockApiClient := mocks.NewMockApiClient(ctrl)
+	mockApiClient.EXPECT().Init(gomock.Any(), gomock.Any()).AnyTimes()
+	mockApiClient.EXPECT().GetOrgIdFromSlug(orgName).Return(orgId, nil).AnyTimes()
+
+	t.Setenv("SNYK_CFG_ORG", orgName)
+
+	config := configuration.NewInMemory()
+	engine := workflow.NewWorkFlowEngine(config)
+	apiClientFactory := func(url string, client *http.Client) api.ApiClient {
+		return mockApiClient
+	}
+	initConfiguration(engine, config, &zlog.Logger, apiClientFactory)
+
+	key, ok := config.GetKeyForEnvVar("SNYK_CFG_ORG")
+	assert.True(t, ok)
+	assert.Equal(t, configuration.ORGANIZATION, key)
+	assert.Equal(t, orgId, config.GetString(configuration.ORGANIZATION))
+}
+
+func Test_initConfiguration_dependencies(t *testing.T) {
+	config := configuration.NewInMemory()
+	engine := workflow.NewWorkFlowEngine(config)
+	initConfiguration(engine, config, &zlog.Logger, nil)
+
+	graph := config.DependencyGraph()
+	assert.Contains(t, graph, fmt.Sprintf("%q -> %q;", configuration.WEB_APP_URL, configuration.API_URL))
+	assert.Contains(t, graph, fmt.Sprintf("%q -> %q;", configuration.IS_FEDRAMP, configuration.API_URL))
+	assert.NoError(t, configuration.ValidateDependencies(config))
+}
+
 func Test_initConfiguration_useDefaultOrg(t *testing.T) {
 	defaultOrgId := "someDefaultOrgId"
 	defaultOrgSlug := "someDefaultOrgSlug"
@@ -176,6 +211,39 @@ func Test_initConfiguration_useDefaultOrg(t *testing.T) {
 	assert.Equal(t, defaultOrgSlug, actualOrgSlug)
 }
 
+func Test_initConfiguration_defaultOrgFollowsCredentials(t *testing.T) {
+	// setup mock
+	ctrl := gomock.NewController(t)
+	mockApiClient := mocks.NewMockApiClient(ctrl)
+
+	// mock assertion
+	mockApiClient.EXPECT().Init(gomock.Any(), gomock.Any()).AnyTimes()
+	gomock.InOrder(
+		mockApiClient.EXPECT().GetDefaultOrgId().Return("firstAccountOrgId", nil).Times(1),
+		mockApiClient.EXPECT().GetDefaultOrgId().Return("secondAccountOrgId", nil).Times(1),
+		mockApiClient.EXPECT().GetDefaultOrgId().Return("oauthAccountOrgId", nil).Times(1),
+	)
+
+	config := configuration.NewInMemory()
+	engine := workflow.NewWorkFlowEngine(config)
+	apiClientFactory := func(url string, client *http.Client) api.ApiClient {
+		return mockApiClient
+	}
+	initConfiguration(engine, config, &zlog.Logger, apiClientFactory)
+
+	config.Set(configuration.AUTHENTICATION_TOKEN, "firstAccountToken")
+	assert.Equal(t, "firstAccountOrgId", config.GetString(configuration.ORGANIZATION))
+	assert.Equal(t, "firstAccountOrgId", config.GetString(configuration.ORGANIZATION))
+
+	// the memoized organization of the previous account is not used after switching accounts
+	config.Set(configuration.AUTHENTICATION_TOKEN, "secondAccountToken")
+	assert.Equal(t, "secondAccountOrgId", config.Clone().GetString(configuration.ORGANIZATION))
+
+	config.Unset(configuration.AUTHENTICATION_TOKEN)
+	config.Set(auth.CONFIG_KEY_OAUTH_TOKEN, `{"access_token":"oauthAccountToken"}`)
+	assert.Equal(t, "oauthAccountOrgId", config.GetString(configuration.ORGANIZATION))
+}
+
 func Test_initConfiguration_useDefaultOrgAsFallback(t *testing.T) {
 	orgName := "someOrgName"
 	defaultOrgId := "someDefaultOrgId"
@@ -19,6 +19,14 @@ type Authenticator interface {
 	IsSupported() bool
 }
 
+// RenewableAuthenticator is an Authenticator whose credentials can be renewed, e.g. after they were rejected.
+type RenewableAuthenticator interface {
+	Authenticator
+	// RenewAuthentication renews the credentials that the rejected request was sent with, so that
+	// AddAuthenticationHeader adds the renewed credentials afterward.
+	RenewAuthentication(rejectedRequest *http.Request) error
+}
+
 func CreateAuthenticator(config configuration.Configuration, httpClient *http.Client) Authenticator {
 	var authenticator Authenticator
 
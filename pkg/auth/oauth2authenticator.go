@@ -18,6 +18,7 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/browser"
@@ -42,6 +43,14 @@ const (
 	PARAMETER_CLIENT_SECRET        string = "client-secret"
 )
 
+// DefaultRefreshWindow is the recommended duration before the expiry of a token in which it is renewed ahead of time,
+// see configuration.OAUTH_REFRESH_WINDOW_SECS.
+const DefaultRefreshWindow = 2 * time.Minute
+
+// forcedRenewalBackoff is the minimum duration between renewals of rejected tokens, so that requests that are rejected
+// regardless of the token, or a failing token endpoint, don't cause a renewal per request.
+const forcedRenewalBackoff = 30 * time.Second
+
 type GrantType int
 
 const (
@@ -50,8 +59,12 @@ const (
 )
 
 var _ Authenticator = (*oAuth2Authenticator)(nil)
+var _ RenewableAuthenticator = (*oAuth2Authenticator)(nil)
 
 var acceptedCallbackPorts = []int{8080, 18081, 28082, 38083, 48084}
+
+// globalRefreshMutex serializes token renewals of all authenticators in the process, requests with a token that
+// doesn't need to be renewed don't acquire it.
 var globalRefreshMutex sync.Mutex
 
 //go:embed errorresponse.html
@@ -61,6 +74,7 @@ type oAuth2Authenticator struct {
 	httpClient         *http.Client
 	config             configuration.Configuration
 	oauthConfig        *oauth2.Config
+	tokenMutex         sync.RWMutex
 	token              *oauth2.Token
 	headless           bool
 	grantType          GrantType
@@ -68,6 +82,8 @@ type oAuth2Authenticator struct {
 	openBrowserFunc    func(authUrl string)
 	shutdownServerFunc func(server *http.Server)
 	tokenRefresherFunc func(ctx context.Context, oauthConfig *oauth2.Config, token *oauth2.Token) (*oauth2.Token, error)
+	// forcedRenewalAt is the time in Unix nanoseconds of the last renewal of a rejected token
+	forcedRenewalAt atomic.Int64
 }
 
 func OpenBrowser(authUrl string) {
@@ -247,7 +263,7 @@ func NewOAuth2AuthenticatorWithCustomFuncs(
 }
 
 func (o *oAuth2Authenticator) IsSupported() bool {
-	tokenExistent := o.token != nil
+	tokenExistent := o.getToken() != nil
 	featureEnabled := o.config.GetBool(configuration.FF_OAUTH_AUTH_FLOW_ENABLED)
 	return tokenExistent && featureEnabled
 }
@@ -258,10 +274,22 @@ func (o *oAuth2Authenticator) persistToken(token *oauth2.Token) error {
 		return err
 	}
 	o.config.Set(CONFIG_KEY_OAUTH_TOKEN, string(tokenstring))
-	o.token = token
+	o.setToken(token)
 	return nil
 }
 
+func (o *oAuth2Authenticator) getToken() *oauth2.Token {
+	o.tokenMutex.RLock()
+	defer o.tokenMutex.RUnlock()
+	return o.token
+}
+
+func (o *oAuth2Authenticator) setToken(token *oauth2.Token) {
+	o.tokenMutex.Lock()
+	defer o.tokenMutex.Unlock()
+	o.token = token
+}
+
 func (o *oAuth2Authenticator) Authenticate() error {
 	var err error
 
@@ -500,54 +528,40 @@ func (o *oAuth2Authenticator) AddAuthenticationHeader(request *http.Request) err
 	if request == nil {
 		return fmt.Errorf("request must not be nil")
 	}
-	if o.token == nil {
+
+	token := o.getToken()
+	if token == nil {
 		return fmt.Errorf("oauth token must not be nil to authorize")
 	}
 
-	ctx := request.Context()
-
-	if o.httpClient != nil {
-		ctx = context.WithValue(ctx, oauth2.HTTPClient, o.httpClient)
+	ctx := o.getContext(request)
+	refreshWindow := time.Duration(o.config.GetInt(configuration.OAUTH_REFRESH_WINDOW_SECS)) * time.Second
+	expiresSoon := func(token *oauth2.Token) bool {
+		return !token.Valid() || (refreshWindow > 0 && !token.Expiry.IsZero() && time.Until(token.Expiry) < refreshWindow)
 	}
 
-	// Also ensure this in-process across goroutines.
-	globalRefreshMutex.Lock()
-	defer globalRefreshMutex.Unlock()
+	if !token.Valid() {
+		// the request can't be sent without a valid token
+		globalRefreshMutex.Lock()
+		defer globalRefreshMutex.Unlock()
 
-	// if the current token is invalid
-	if !o.token.Valid() {
-		// Ensure oauth token refresh is atomic and does not operate on a stale
-		// token across concurrent processes.
-		cleanup, err := o.syncTokenRefresh(ctx)
-		defer cleanup()
+		var err error
+		token, err = o.renewToken(ctx, func(token *oauth2.Token) bool { return !token.Valid() })
 		if err != nil {
 			return err
 		}
-
-		// check if the token in the config is invalid as well
-		token, err := GetOAuthToken(o.config)
+	} else if expiresSoon(token) && globalRefreshMutex.TryLock() {
+		// the token is still valid, so a single request renews it ahead of its expiry and all others keep using it
+		renewedToken, err := o.renewToken(ctx, expiresSoon)
+		globalRefreshMutex.Unlock()
 		if err != nil {
-			return err
-		}
-
-		if !token.Valid() {
-			// use TokenSource to refresh the token
-			validToken, err := o.tokenRefresherFunc(ctx, o.oauthConfig, o.token)
-			if err != nil {
-				return err
-			}
-
-			if validToken != o.token {
-				if err := o.persistToken(validToken); err != nil {
-					return err
-				}
-			}
+			o.logger.Debug().Err(err).Msg("Failed to renew the oauth token ahead of its expiry")
 		} else {
-			o.token = token
+			token = renewedToken
 		}
 	}
 
-	accessToken := o.token.AccessToken
+	accessToken := token.AccessToken
 	if len(accessToken) > 0 {
 		value := fmt.Sprint("Bearer ", accessToken)
 		request.Header.Set("Authorization", value)
@@ -557,6 +571,87 @@ func (o *oAuth2Authenticator) AddAuthenticationHeader(request *http.Request) err
 	return nil
 }
 
+// RenewAuthentication forces a renewal of the token that the rejected request was sent with. If the token was
+// renewed meanwhile, e.g. by a concurrent request, the renewed token is used without another renewal. After a renewal,
+// successful or not, rejected tokens aren't renewed again for forcedRenewalBackoff.
+func (o *oAuth2Authenticator) RenewAuthentication(rejectedRequest *http.Request) error {
+	if rejectedRequest == nil {
+		return fmt.Errorf("request must not be nil")
+	}
+	token := o.getToken()
+	if token == nil {
+		return fmt.Errorf("oauth token must not be nil to authorize")
+	}
+
+	rejectedHeader := rejectedRequest.Header.Get("Authorization")
+	if rejectedHeader != fmt.Sprint("Bearer ", token.AccessToken) {
+		// renewed meanwhile, the locks aren't needed to use the current token
+		return nil
+	}
+
+	if elapsed := time.Since(time.Unix(0, o.forcedRenewalAt.Load())); elapsed < forcedRenewalBackoff {
+		return fmt.Errorf("the oauth token was renewed %s ago, not renewing it again", elapsed.Round(time.Second))
+	}
+
+	globalRefreshMutex.Lock()
+	defer globalRefreshMutex.Unlock()
+
+	_, err := o.renewToken(o.getContext(rejectedRequest), func(token *oauth2.Token) bool {
+		needsRenewal := !token.Valid() || rejectedHeader == fmt.Sprint("Bearer ", token.AccessToken)
+		if needsRenewal {
+			o.forcedRenewalAt.Store(time.Now().UnixNano())
+		}
+		return needsRenewal
+	})
+	return err
+}
+
+func (o *oAuth2Authenticator) getContext(request *http.Request) context.Context {
+	ctx := request.Context()
+	if o.httpClient != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, o.httpClient)
+	}
+	return ctx
+}
+
+// renewToken refreshes the token if it needs to be renewed. Tokens that were renewed by other processes or
+// authenticators are taken into account, so that a token is only renewed once. It must be called while holding
+// globalRefreshMutex.
+func (o *oAuth2Authenticator) renewToken(ctx context.Context, needsRenewal func(token *oauth2.Token) bool) (*oauth2.Token, error) {
+	// Ensure oauth token refresh is atomic and does not operate on a stale
+	// token across concurrent processes.
+	cleanup, err := o.syncTokenRefresh(ctx)
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	// check if the token in the config needs to be renewed as well
+	token, err := GetOAuthToken(o.config)
+	if err != nil {
+		return nil, err
+	}
+
+	if token != nil && !needsRenewal(token) {
+		o.setToken(token)
+		return token, nil
+	}
+
+	// the token source only refreshes tokens that are expired, which isn't the case for renewals ahead of time
+	expiredToken := *o.getToken()
+	expiredToken.Expiry = time.Now().Add(-time.Minute)
+
+	validToken, err := o.tokenRefresherFunc(ctx, o.oauthConfig, &expiredToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = o.persistToken(validToken); err != nil {
+		return nil, err
+	}
+	return validToken, nil
+}
+
 const syncTokenRefreshRetryDelay = time.Millisecond * 100
 
 // syncTokenRefresh ensures that an oauth token refresh and configuration file
@@ -588,13 +683,14 @@ func (o *oAuth2Authenticator) syncTokenRefresh(ctx context.Context) (func(), err
 		if err = storage.Refresh(o.config, CONFIG_KEY_OAUTH_TOKEN); err != nil {
 			return cleanup, err
 		}
-		o.token, err = GetOAuthToken(o.config)
+		token, err := GetOAuthToken(o.config)
 		if err != nil {
 			return cleanup, err
 		}
-		if o.token == nil {
+		if token == nil {
 			return cleanup, fmt.Errorf("oauth token must not be nil to authorize")
 		}
+		o.setToken(token)
 	}
 
 	return cleanup, nil
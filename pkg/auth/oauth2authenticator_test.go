@@ -475,3 +475,117 @@ func Test_Authenticate_AuthorizationCode(t *testing.T) {
 		assert.ErrorContains(t, err, "incorrect response state")
 	})
 }
+
+func Test_AddAuthenticationHeader_tokenWithinRefreshWindow(t *testing.T) {
+	expiringToken := &oauth2.Token{
+		AccessToken:  "expiring",
+		TokenType:    "b",
+		RefreshToken: "c",
+		Expiry:       time.Now().Add(60 * time.Second).UTC(),
+	}
+
+	newToken := &oauth2.Token{
+		AccessToken:  "a",
+		TokenType:    "b",
+		RefreshToken: "c",
+		Expiry:       time.Now().Add(time.Hour).UTC(),
+	}
+
+	config := configuration.NewInMemory()
+	config.Set(configuration.OAUTH_REFRESH_WINDOW_SECS, 120)
+	authenticator := NewOAuth2AuthenticatorWithOpts(config)
+	err := authenticator.(*oAuth2Authenticator).persistToken(expiringToken)
+	assert.NoError(t, err)
+
+	refreshErr := fmt.Errorf("refresh failed")
+	refreshCount := 0
+	authenticator.(*oAuth2Authenticator).tokenRefresherFunc = func(_ context.Context, _ *oauth2.Config, token *oauth2.Token) (*oauth2.Token, error) {
+		refreshCount++
+		assert.Equal(t, expiringToken.AccessToken, token.AccessToken)
+		// the token source only refreshes expired tokens
+		assert.False(t, token.Valid())
+		return newToken, refreshErr
+	}
+
+	// requests don't wait for a token that is still valid while another request renews it
+	globalRefreshMutex.Lock()
+	request := &http.Request{Header: http.Header{}}
+	err = authenticator.AddAuthenticationHeader(request)
+	globalRefreshMutex.Unlock()
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer expiring", request.Header.Get("Authorization"))
+	assert.Equal(t, 0, refreshCount)
+
+	// a failed renewal ahead of time doesn't fail the request
+	err = authenticator.AddAuthenticationHeader(request)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer expiring", request.Header.Get("Authorization"))
+	assert.Equal(t, 1, refreshCount)
+
+	refreshErr = nil
+	err = authenticator.AddAuthenticationHeader(request)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer a", request.Header.Get("Authorization"))
+	assert.Equal(t, 2, refreshCount)
+
+	// the renewed token is outside of the window
+	err = authenticator.AddAuthenticationHeader(request)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, refreshCount)
+
+	actualToken, err := GetOAuthToken(config)
+	assert.NoError(t, err)
+	assert.Equal(t, *newToken, *actualToken)
+}
+
+func Test_RenewAuthentication(t *testing.T) {
+	rejectedToken := &oauth2.Token{
+		AccessToken:  "rejected",
+		TokenType:    "b",
+		RefreshToken: "c",
+		Expiry:       time.Now().Add(time.Hour).UTC(),
+	}
+
+	newToken := &oauth2.Token{
+		AccessToken:  "a",
+		TokenType:    "b",
+		RefreshToken: "c",
+		Expiry:       time.Now().Add(time.Hour).UTC(),
+	}
+
+	config := configuration.NewInMemory()
+	authenticator := NewOAuth2AuthenticatorWithOpts(config)
+	err := authenticator.(*oAuth2Authenticator).persistToken(rejectedToken)
+	assert.NoError(t, err)
+
+	refreshCount := 0
+	authenticator.(*oAuth2Authenticator).tokenRefresherFunc = func(_ context.Context, _ *oauth2.Config, token *oauth2.Token) (*oauth2.Token, error) {
+		refreshCount++
+		assert.False(t, token.Valid())
+		return newToken, nil
+	}
+
+	rejectedRequest := &http.Request{Header: http.Header{}}
+	assert.NoError(t, authenticator.AddAuthenticationHeader(rejectedRequest))
+
+	renewableAuthenticator, ok := authenticator.(RenewableAuthenticator)
+	assert.True(t, ok)
+	assert.NoError(t, renewableAuthenticator.RenewAuthentication(rejectedRequest))
+	assert.Equal(t, 1, refreshCount)
+
+	// concurrent requests with the same rejected token don't renew the token again
+	assert.NoError(t, renewableAuthenticator.RenewAuthentication(rejectedRequest))
+	assert.Equal(t, 1, refreshCount)
+
+	request := &http.Request{Header: http.Header{}}
+	assert.NoError(t, authenticator.AddAuthenticationHeader(request))
+	assert.Equal(t, "Bearer a", request.Header.Get("Authorization"))
+
+	// a renewed token that is rejected as well isn't renewed again right away
+	assert.Error(t, renewableAuthenticator.RenewAuthentication(request))
+	assert.Equal(t, 1, refreshCount)
+
+	authenticator.(*oAuth2Authenticator).forcedRenewalAt.Store(time.Now().Add(-forcedRenewalBackoff).UnixNano())
+	assert.NoError(t, renewableAuthenticator.RenewAuthentication(request))
+	assert.Equal(t, 2, refreshCount)
+}
@@ -46,17 +46,35 @@ type Configuration interface {
 	GetWithError(key string) (interface{}, error)
 
 	AddFlagSet(flagset *pflag.FlagSet) error
+	// Scope returns a view on the configuration in which keys are local to the given namespace and fall back to
+	// global keys, e.g. Scope("code.test").
+	Scope(namespace string) Configuration
 	AllKeys() []string
-	AddDefaultValue(key string, defaultValue DefaultValueFunction)
+	AddDefaultValue(key string, defaultValue DefaultValueFunction, opts ...DefaultValueOption)
+	// PrefetchDefaultValues concurrently determines all default values registered WithPrefetch and WithMemoization.
+	// It should only be called once flags have been parsed and does nothing while OFFLINE is enabled.
+	PrefetchDefaultValues()
+	// AddDependencies declares that the value of key is derived from the given keys and fails on dependency cycles.
+	AddDependencies(key string, dependencies ...string) error
+	GetDependencies(key string) []string
+	// DependencyGraph renders all declared dependencies in the DOT language.
+	DependencyGraph() string
 	AddAlternativeKeys(key string, altKeys []string)
 	GetAlternativeKeys(key string) []string
 	GetAllKeysThatContainValues(key string) []string
 	GetKeyType(key string) KeyType
 
+	// LockValue enforces the value of a key, e.g. by an organization policy, only the allowed values may be set.
+	LockValue(key string, value interface{}, allowedValues ...interface{})
+	IsLocked(key string) bool
+	GetLockedKeys() []string
+
 	// PersistInStorage ensures that when Set is called with the given key, it will be persisted in the config file.
 	PersistInStorage(key string)
 	SetStorage(storage Storage)
 	GetStorage() Storage
+	// BeginTransaction batches several changes, which are persisted with a single storage write on commit.
+	BeginTransaction() Transaction
 
 	AutomaticEnv()
 	GetAutomaticEnv() bool
@@ -64,9 +82,17 @@ type Configuration interface {
 	GetSupportedEnvVars() []string
 	SetSupportedEnvVarPrefixes(prefixes ...string)
 	GetSupportedEnvVarPrefixes() []string
+	// AddEnvVarMapping registers an environment variable as source for a key, e.g. SNYK_CFG_ORG for ORGANIZATION.
+	AddEnvVarMapping(envVar string, key string, description string)
+	GetEnvVarMappings() []EnvVarMapping
+	GetKeyForEnvVar(envVar string) (string, bool)
 	SetFiles(files ...string)
 	GetFiles() []string
 	ReloadConfig() error
+
+	// SetProjectConfigFile reads a project local YAML or TOML configuration file, see FindProjectConfigFile.
+	SetProjectConfigFile(file string) error
+	GetProjectConfigFile() string
 }
 
 // extendedViper is a wrapper around the viper library.
@@ -74,14 +100,24 @@ type Configuration interface {
 type extendedViper struct {
 	viper               *viper.Viper
 	alternativeKeys     map[string][]string
-	defaultValues       map[string]DefaultValueFunction
+	defaultValues       map[string]defaultValueEntry
+	dependencies        map[string][]string
 	configType          configType
 	flagsets            []*pflag.FlagSet
+	scopedFlagsets      map[string][]*pflag.FlagSet
+	lockedValues        map[string]lockedValue
+	envVarMappings      map[string]EnvVarMapping
+	envVarsByKey        map[string][]string
 	storage             Storage
 	mutex               sync.RWMutex
 	automaticEnvEnabled bool
 	configFiles         []string
 
+	// projectConfig holds the values of the project local configuration file, e.g. .snyk/config.yaml.
+	// It is merged into viper's config layer and only read afterward.
+	projectConfig     *viper.Viper
+	projectConfigFile string
+
 	// persistedKeys stores the keys that need to be persisted to storage when Set is called.
 	// Only specific keys are persisted, so viper's native functionality is not used.
 	persistedKeys map[string]bool
@@ -207,7 +243,12 @@ func createViperDefaultConfig(opts ...Opts) *extendedViper {
 	config := &extendedViper{
 		viper:           viper.New(),
 		alternativeKeys: make(map[string][]string),
-		defaultValues:   make(map[string]DefaultValueFunction),
+		defaultValues:   make(map[string]defaultValueEntry),
+		dependencies:    make(map[string][]string),
+		scopedFlagsets:  make(map[string][]*pflag.FlagSet),
+		lockedValues:    make(map[string]lockedValue),
+		envVarMappings:  make(map[string]EnvVarMapping),
+		envVarsByKey:    make(map[string][]string),
 		persistedKeys:   make(map[string]bool),
 	}
 	config.viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
@@ -249,25 +290,49 @@ func (ev *extendedViper) Clone() Configuration {
 	defer ev.mutex.RUnlock()
 
 	// manually clone the Configuration instance
-	var clone Configuration
+	var clone *extendedViper
 	if ev.configType == jsonFile {
 		configFileUsed := ev.viper.ConfigFileUsed()
-		clone = NewFromFiles(configFileUsed)
+		clone = createViperDefaultConfig(WithFiles(configFileUsed), WithAutomaticEnv())
 	} else {
-		clone = NewInMemory()
+		clone = createViperDefaultConfig(WithAutomaticEnv())
 	}
 
+	// the project configuration is shared, since it is only read after loading
+	clone.projectConfigFile = ev.projectConfigFile
+	clone.projectConfig = ev.projectConfig
+	//nolint:errcheck // the values have been merged successfully before
+	_ = clone.mergeProjectConfig()
+
 	clone.SetStorage(ev.storage)
 	keys := ev.viper.AllKeys()
 	for i := range keys {
 		if isSet := ev.viper.IsSet(keys[i]); isSet {
 			value := ev.viper.Get(keys[i])
+			// values from the project configuration are part of the clone's config layer already, setting them
+			// explicitly would make them take precedence over env vars and flags
+			if ev.isProjectConfigValue(keys[i], value) {
+				continue
+			}
 			clone.Set(keys[i], value)
 		}
 	}
 
+	// entries are copied, but caches of memoized default values are shared
 	for k, v := range ev.defaultValues {
-		clone.AddDefaultValue(k, v)
+		clone.defaultValues[k] = v
+	}
+	for k, v := range ev.dependencies {
+		clone.dependencies[k] = slices.Clone(v)
+	}
+	for k, v := range ev.lockedValues {
+		clone.lockedValues[k] = v
+	}
+	for _, envVars := range ev.envVarsByKey {
+		for _, envVar := range envVars {
+			v := ev.envVarMappings[envVar]
+			clone.AddEnvVarMapping(v.EnvVar, v.Key, v.Description)
+		}
 	}
 
 	if ev.automaticEnvEnabled {
@@ -286,6 +351,13 @@ func (ev *extendedViper) Clone() Configuration {
 		clone.AddFlagSet(v)
 	}
 
+	for namespace, flagsets := range ev.scopedFlagsets {
+		for _, v := range flagsets {
+			//nolint:errcheck // binding succeeded for the original configuration
+			_ = clone.addScopedFlagSet(namespace, v)
+		}
+	}
+
 	return clone
 }
 
@@ -320,6 +392,12 @@ func (ev *extendedViper) get(key string) (result interface{}, err error) {
 		index++
 	}
 
+	if !isSet {
+		if value, found := ev.lookupMappedEnvVar(key); found {
+			result = value
+		}
+	}
+
 	return result, err
 }
 
@@ -341,12 +419,20 @@ func (ev *extendedViper) IsSet(key string) bool {
 	ev.mutex.RLock()
 	defer ev.mutex.RUnlock()
 
+	return ev.isSet(key)
+}
+
+// isSet must be called while holding the lock.
+func (ev *extendedViper) isSet(key string) bool {
 	isSet := ev.viper.IsSet(key)
 	if !isSet {
 		for _, altKey := range ev.alternativeKeys[key] {
 			isSet = ev.viper.IsSet(altKey)
 		}
 	}
+	if !isSet {
+		_, isSet = ev.lookupMappedEnvVar(key)
+	}
 	return isSet
 }
 
@@ -382,113 +468,68 @@ func (ev *extendedViper) Get(key string) interface{} {
 // GetWithError returns a configuration value and and the potential error returned by the DefaultValueFunction for the configuration value.
 func (ev *extendedViper) GetWithError(key string) (value interface{}, err error) {
 	ev.mutex.Lock()
+	if lock, locked := ev.lockedValues[key]; locked {
+		value, err = ev.getLocked(key, lock)
+		ev.mutex.Unlock()
+		return value, err
+	}
+
 	value, err = ev.get(key)
-	defaultFunc, ok := ev.defaultValues[key]
+	entry, ok := ev.defaultValues[key]
+	var fingerprint string
+	if ok && (entry.cache != nil || entry.offlineFallback) {
+		fingerprint = ev.defaultValueFingerprint(key, value)
+	}
 	ev.mutex.Unlock()
 
-	if ok && defaultFunc != nil {
+	if ok && entry.function != nil {
 		var defErr error
-		value, defErr = defaultFunc(value)
+		existingValue := value
+		determine := func() (interface{}, error) {
+			return entry.function(existingValue)
+		}
+		if entry.offlineFallback {
+			determine = ev.withOfflineFallback(key, fingerprint, determine)
+		}
+
+		if entry.cache != nil {
+			value, defErr = entry.cache.get(fingerprint, determine)
+		} else {
+			value, defErr = determine()
+		}
 		err = errors.Join(err, defErr)
 	}
 
+	if ok {
+		err = errors.Join(err, entry.err)
+	}
+
 	return value, err
 }
 
 // GetString returns a configuration value as string.
 func (ev *extendedViper) GetString(key string) string {
-	result := ev.Get(key)
-	if result == nil {
-		return ""
-	}
-	if s, ok := result.(string); ok {
-		return s
-	}
-	return ""
+	return toString(ev.Get(key))
 }
 
 // GetBool returns a configuration value as bool.
 func (ev *extendedViper) GetBool(key string) bool {
-	result := ev.Get(key)
-	if result == nil {
-		return false
-	}
-
-	switch v := result.(type) {
-	case bool:
-		return v
-	case string:
-		boolResult, err := strconv.ParseBool(v)
-		if err != nil {
-			return false
-		}
-		return boolResult
-	}
-
-	return false
+	return toBool(ev.Get(key))
 }
 
 // GetInt returns a configuration value as int.
 func (ev *extendedViper) GetInt(key string) int {
-	result := ev.Get(key)
-	if result == nil {
-		return 0
-	}
-
-	switch v := result.(type) {
-	case string:
-		stringResult := v
-		i, err := strconv.ParseInt(stringResult, 10, 32)
-		if err != nil {
-			return 0
-		}
-		return int(i)
-	case float32:
-		return int(v)
-	case float64:
-		return int(v)
-	case int:
-		return v
-	}
-
-	return 0
+	return toInt(ev.Get(key))
 }
 
 // GetFloat64 returns a configuration value as float64.
 func (ev *extendedViper) GetFloat64(key string) float64 {
-	result := ev.Get(key)
-	if result == nil {
-		return 0
-	}
-
-	switch v := result.(type) {
-	case string:
-		stringResult := v
-		f, err := strconv.ParseFloat(stringResult, 64)
-		if err != nil {
-			return 0
-		}
-		return f
-	case float32:
-		return float64(v)
-	case float64:
-		return v
-	case int:
-		return float64(v)
-	}
-
-	return 0
+	return toFloat64(ev.Get(key))
 }
 
 // GetUrl returns a configuration value as url.URL.
 func (ev *extendedViper) GetUrl(key string) *url.URL {
-	urlString := ev.GetString(key)
-	u, err := url.Parse(urlString)
-	if err == nil {
-		return u
-	} else {
-		return nil
-	}
+	return toUrl(ev.GetString(key))
 }
 
 // AddFlagSet adds a flag set to the configuration.
@@ -501,19 +542,7 @@ func (ev *extendedViper) AddFlagSet(flagset *pflag.FlagSet) error {
 
 // GetStringSlice returns a configuration value as []string.
 func (ev *extendedViper) GetStringSlice(key string) []string {
-	output := []string{}
-
-	result := ev.Get(key)
-	if result == nil {
-		return output
-	}
-
-	switch v := result.(type) {
-	case []string:
-		return v
-	}
-
-	return output
+	return toStringSlice(ev.Get(key))
 }
 
 // AllKeys returns all keys of the configuration.
@@ -530,12 +559,22 @@ func (ev *extendedViper) AllKeys() []string {
 	return keys
 }
 
-// AddDefaultValue adds a default value to the configuration.
-func (ev *extendedViper) AddDefaultValue(key string, defaultValue DefaultValueFunction) {
+// AddDefaultValue adds a default value to the configuration. Options allow to memoize expensive default values,
+// e.g. those requiring network access. Dependencies that would introduce a cycle are not added, the
+// DependencyCycleError is returned by GetWithError and ValidateDependencies.
+func (ev *extendedViper) AddDefaultValue(key string, defaultValue DefaultValueFunction, opts ...DefaultValueOption) {
+	entry := defaultValueEntry{
+		function: defaultValue,
+	}
+	for _, opt := range opts {
+		opt(&entry)
+	}
+
 	ev.mutex.Lock()
 	defer ev.mutex.Unlock()
 
-	ev.defaultValues[key] = defaultValue
+	entry.err = ev.addDependencies(key, entry.dependencies)
+	ev.defaultValues[key] = entry
 }
 
 // AddAlternativeKeys adds alternative keys to the configuration.
@@ -703,5 +742,110 @@ func (ev *extendedViper) GetFiles() []string {
 }
 
 func (ev *extendedViper) ReloadConfig() error {
-	return ev.viper.ReadInConfig()
+	ev.mutex.Lock()
+	defer ev.mutex.Unlock()
+
+	err := ev.viper.ReadInConfig()
+	return errors.Join(err, ev.mergeProjectConfig())
+}
+
+func toString(result interface{}) string {
+	if result == nil {
+		return ""
+	}
+	if s, ok := result.(string); ok {
+		return s
+	}
+	return ""
+}
+
+func toBool(result interface{}) bool {
+	if result == nil {
+		return false
+	}
+
+	switch v := result.(type) {
+	case bool:
+		return v
+	case string:
+		boolResult, err := strconv.ParseBool(v)
+		if err != nil {
+			return false
+		}
+		return boolResult
+	}
+
+	return false
+}
+
+func toInt(result interface{}) int {
+	if result == nil {
+		return 0
+	}
+
+	switch v := result.(type) {
+	case string:
+		stringResult := v
+		i, err := strconv.ParseInt(stringResult, 10, 32)
+		if err != nil {
+			return 0
+		}
+		return int(i)
+	case float32:
+		return int(v)
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+
+	return 0
+}
+
+func toFloat64(result interface{}) float64 {
+	if result == nil {
+		return 0
+	}
+
+	switch v := result.(type) {
+	case string:
+		stringResult := v
+		f, err := strconv.ParseFloat(stringResult, 64)
+		if err != nil {
+			return 0
+		}
+		return f
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	}
+
+	return 0
+}
+
+func toUrl(urlString string) *url.URL {
+	u, err := url.Parse(urlString)
+	if err == nil {
+		return u
+	} else {
+		return nil
+	}
+}
+
+func toStringSlice(result interface{}) []string {
+	output := []string{}
+
+	if result == nil {
+		return output
+	}
+
+	switch v := result.(type) {
+	case []string:
+		return v
+	}
+
+	return output
 }
@@ -8,11 +8,14 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/snyk/go-application-framework/internal/utils"
 )
 
 const (
@@ -790,3 +793,794 @@ func Test_Configuration_envVarSupport(t *testing.T) {
 		cleanUpEnvVars()
 	})
 }
+
+func writeProjectConfig(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+	file := filepath.Join(dir, ".snyk", name)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+	assert.NoError(t, os.WriteFile(file, []byte(content), 0644))
+	return file
+}
+
+func Test_Configuration_ProjectConfig(t *testing.T) {
+	const yamlContent = `
+org: project-org
+severity-threshold: high
+exclude:
+  - vendor
+  - node_modules
+output:
+  json: true
+`
+
+	t.Run("finds the config file walking up from a sub directory", func(t *testing.T) {
+		root := t.TempDir()
+		expected := writeProjectConfig(t, root, "config.yaml", yamlContent)
+		subDir := filepath.Join(root, "a", "b")
+		assert.NoError(t, os.MkdirAll(subDir, 0755))
+
+		assert.Equal(t, expected, FindProjectConfigFile(subDir))
+		assert.Empty(t, FindProjectConfigFile(""))
+	})
+
+	t.Run("reads yaml values", func(t *testing.T) {
+		file := writeProjectConfig(t, t.TempDir(), "config.yaml", yamlContent)
+
+		config := NewWithOpts(WithAutomaticEnv())
+		assert.NoError(t, config.SetProjectConfigFile(file))
+
+		assert.Equal(t, file, config.GetProjectConfigFile())
+		assert.Equal(t, "project-org", config.GetString(ORGANIZATION))
+		assert.Equal(t, "high", config.GetString(FLAG_SEVERITY_THRESHOLD))
+		assert.Equal(t, []interface{}{"vendor", "node_modules"}, config.Get("exclude"))
+		assert.True(t, config.GetBool("output.json"))
+		assert.True(t, config.IsSet(ORGANIZATION))
+	})
+
+	t.Run("reads toml values", func(t *testing.T) {
+		file := writeProjectConfig(t, t.TempDir(), "config.toml", "org = \"toml-org\"\n")
+
+		config := NewWithOpts()
+		assert.NoError(t, config.SetProjectConfigFile(file))
+		assert.Equal(t, "toml-org", config.GetString(ORGANIZATION))
+	})
+
+	t.Run("ignores unsupported keys", func(t *testing.T) {
+		file := writeProjectConfig(t, t.TempDir(), "config.yaml", yamlContent+`
+snyk_api: https://attacker.example.com
+insecure: true
+`)
+
+		config := NewWithOpts()
+		config.Set(API_URL, "https://api.snyk.io")
+		err := config.SetProjectConfigFile(file)
+		assert.ErrorIs(t, err, ErrUnsupportedProjectConfigKeys)
+		assert.ErrorContains(t, err, "insecure, snyk_api")
+
+		assert.Equal(t, "project-org", config.GetString(ORGANIZATION))
+		assert.Equal(t, "https://api.snyk.io", config.GetString(API_URL))
+		assert.False(t, config.GetBool(INSECURE_HTTPS))
+		assert.False(t, config.IsSet(INSECURE_HTTPS))
+		assert.Equal(t, "https://api.snyk.io", config.Clone().GetString(API_URL))
+	})
+
+	t.Run("fails for invalid files", func(t *testing.T) {
+		file := writeProjectConfig(t, t.TempDir(), "config.yaml", "org: [")
+
+		config := NewWithOpts()
+		assert.Error(t, config.SetProjectConfigFile(file))
+		assert.Empty(t, config.GetProjectConfigFile())
+	})
+
+	t.Run("precedence", func(t *testing.T) {
+		file := writeProjectConfig(t, t.TempDir(), "config.yaml", yamlContent)
+		assert.NoError(t, prepareConfigstore(`{"org": "global-org", "snyk_api": "https://api.snyk.io"}`))
+		defer cleanupConfigstore(t)
+
+		flagset := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		flagset.String(FLAG_SEVERITY_THRESHOLD, "low", "")
+
+		config := NewWithOpts(WithFiles(TEST_FILENAME), WithAutomaticEnv())
+		assert.NoError(t, config.AddFlagSet(flagset))
+		assert.NoError(t, config.SetProjectConfigFile(file))
+
+		// project config overrides the global config file and flag defaults
+		assert.Equal(t, "project-org", config.GetString(ORGANIZATION))
+		assert.Equal(t, "high", config.GetString(FLAG_SEVERITY_THRESHOLD))
+		assert.Equal(t, "https://api.snyk.io", config.GetString(API_URL))
+
+		// env vars and flags override the project config
+		t.Setenv("ORG", "env-org")
+		assert.NoError(t, flagset.Set(FLAG_SEVERITY_THRESHOLD, "critical"))
+		assert.Equal(t, "env-org", config.GetString(ORGANIZATION))
+		assert.Equal(t, "critical", config.GetString(FLAG_SEVERITY_THRESHOLD))
+
+		// the same is true for clones
+		clone := config.Clone()
+		assert.Equal(t, file, clone.GetProjectConfigFile())
+		assert.Equal(t, "env-org", clone.GetString(ORGANIZATION))
+		assert.Equal(t, "critical", clone.GetString(FLAG_SEVERITY_THRESHOLD))
+		assert.True(t, clone.GetBool("output.json"))
+
+		// the project config survives reloading the global config file
+		assert.NoError(t, config.ReloadConfig())
+		assert.True(t, config.GetBool("output.json"))
+	})
+}
+
+func Test_Configuration_Snapshot(t *testing.T) {
+	config := NewWithOpts()
+	config.Set(ORGANIZATION, "my-org")
+	config.Set(MAX_THREADS, 4)
+	config.Set(AUTHENTICATION_TOKEN, "secret")
+	config.AddDefaultValue(API_URL, StandardDefaultValueFunction("https://api.snyk.io"))
+	config.AddDefaultValue(ORGANIZATION_SLUG, func(existingValue interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("no network")
+	})
+
+	redact := func(key string, value interface{}) interface{} {
+		if key == AUTHENTICATION_TOKEN {
+			return "***"
+		}
+		return value
+	}
+
+	_, err := CreateSnapshot(config, nil)
+	assert.ErrorIs(t, err, ErrSnapshotRedactFunctionRequired)
+	assert.Equal(t, "secret", CreateUnredactedSnapshot(config).Values[AUTHENTICATION_TOKEN])
+
+	snapshot, err := CreateSnapshot(config, redact)
+	assert.NoError(t, err)
+	assert.Equal(t, "my-org", snapshot.Values[ORGANIZATION])
+	assert.Equal(t, 4, snapshot.Values[MAX_THREADS])
+	assert.Equal(t, "***", snapshot.Values[AUTHENTICATION_TOKEN])
+	assert.Equal(t, "https://api.snyk.io", snapshot.Values[API_URL])
+	assert.Nil(t, snapshot.Values[ORGANIZATION_SLUG])
+	assert.Equal(t, "no network", snapshot.Errors[ORGANIZATION_SLUG])
+
+	file := filepath.Join(t.TempDir(), "snapshot.json")
+	assert.NoError(t, snapshot.Save(file))
+
+	loaded, err := LoadSnapshot(file)
+	assert.NoError(t, err)
+	assert.Equal(t, snapshot.Errors, loaded.Errors)
+
+	restored := NewFromSnapshot(loaded)
+	assert.Equal(t, "my-org", restored.GetString(ORGANIZATION))
+	assert.Equal(t, 4, restored.GetInt(MAX_THREADS))
+	assert.Equal(t, "***", restored.GetString(AUTHENTICATION_TOKEN))
+	assert.Equal(t, "https://api.snyk.io", restored.GetString(API_URL))
+	assert.False(t, restored.IsSet(ORGANIZATION_SLUG))
+
+	_, err = LoadSnapshot(filepath.Join(t.TempDir(), "does-not-exist.json"))
+	assert.Error(t, err)
+}
+
+func Test_Configuration_MemoizedDefaultValues(t *testing.T) {
+	t.Run("caches results until a dependency changes", func(t *testing.T) {
+		config := NewWithOpts()
+		var calls atomic.Int32
+		config.AddDefaultValue(ORGANIZATION, func(existingValue interface{}) (interface{}, error) {
+			calls.Add(1)
+			return "org-for-" + config.GetString(API_URL), nil
+		}, WithMemoization(0), WithDependencies(API_URL))
+
+		config.Set(API_URL, "https://api.snyk.io")
+		assert.Equal(t, "org-for-https://api.snyk.io", config.GetString(ORGANIZATION))
+		assert.Equal(t, "org-for-https://api.snyk.io", config.GetString(ORGANIZATION))
+		assert.Equal(t, int32(1), calls.Load())
+
+		config.Set(API_URL, "https://api.eu.snyk.io")
+		assert.Equal(t, "org-for-https://api.eu.snyk.io", config.GetString(ORGANIZATION))
+		assert.Equal(t, int32(2), calls.Load())
+
+		// the cache is shared with clones
+		clone := config.Clone()
+		assert.Equal(t, "org-for-https://api.eu.snyk.io", clone.GetString(ORGANIZATION))
+		assert.Equal(t, int32(2), calls.Load())
+
+		// the existing value is part of the cache key
+		config.Set(ORGANIZATION, "explicit")
+		assert.Equal(t, "org-for-https://api.eu.snyk.io", config.GetString(ORGANIZATION))
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("expires after ttl", func(t *testing.T) {
+		config := NewWithOpts()
+		var calls atomic.Int32
+		config.AddDefaultValue(ORGANIZATION, func(existingValue interface{}) (interface{}, error) {
+			calls.Add(1)
+			return "org", nil
+		}, WithMemoization(time.Millisecond))
+
+		assert.Equal(t, "org", config.GetString(ORGANIZATION))
+		time.Sleep(5 * time.Millisecond)
+		assert.Equal(t, "org", config.GetString(ORGANIZATION))
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("does not cache errors and empty values", func(t *testing.T) {
+		config := NewWithOpts()
+		var calls atomic.Int32
+		config.AddDefaultValue(ORGANIZATION, func(existingValue interface{}) (interface{}, error) {
+			if calls.Add(1) == 1 {
+				return "", fmt.Errorf("no network")
+			}
+			return "", nil
+		}, WithMemoization(0))
+
+		_, err := config.GetWithError(ORGANIZATION)
+		assert.Error(t, err)
+		_, err = config.GetWithError(ORGANIZATION)
+		assert.NoError(t, err)
+		assert.Empty(t, config.GetString(ORGANIZATION))
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("prefetch determines values once", func(t *testing.T) {
+		config := NewWithOpts()
+		var calls atomic.Int32
+		config.AddDefaultValue(ORGANIZATION, func(existingValue interface{}) (interface{}, error) {
+			calls.Add(1)
+			time.Sleep(10 * time.Millisecond)
+			return "org", nil
+		}, WithMemoization(0), WithPrefetch())
+		config.AddDefaultValue(ORGANIZATION_SLUG, func(existingValue interface{}) (interface{}, error) {
+			t.Error("default values without WithPrefetch must not be prefetched")
+			return nil, nil
+		}, WithMemoization(0))
+
+		go config.PrefetchDefaultValues()
+		config.PrefetchDefaultValues()
+
+		assert.Equal(t, "org", config.GetString(ORGANIZATION))
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("keeps a bounded number of values", func(t *testing.T) {
+		config := NewWithOpts()
+		config.AddDefaultValue(ORGANIZATION, func(existingValue interface{}) (interface{}, error) {
+			return "org-for-" + config.GetString(API_URL), nil
+		}, WithMemoization(0), WithDependencies(API_URL))
+
+		for i := 0; i < 2*maxMemoizedValues; i++ {
+			config.Set(API_URL, fmt.Sprintf("https://api-%d.snyk.io", i))
+			assert.Equal(t, fmt.Sprintf("org-for-https://api-%d.snyk.io", i), config.GetString(ORGANIZATION))
+		}
+
+		cache := config.(*extendedViper).defaultValues[ORGANIZATION].cache
+		assert.Len(t, cache.entries, maxMemoizedValues)
+	})
+
+	t.Run("prefetch does nothing while offline", func(t *testing.T) {
+		config := NewWithOpts()
+		config.Set(OFFLINE, true)
+		config.AddDefaultValue(ORGANIZATION, func(existingValue interface{}) (interface{}, error) {
+			t.Error("default values must not be prefetched while offline")
+			return nil, nil
+		}, WithMemoization(0), WithPrefetch())
+
+		config.PrefetchDefaultValues()
+	})
+}
+
+func Test_Configuration_OfflineFallback(t *testing.T) {
+	cachePath := t.TempDir()
+	newConfig := func(calls *atomic.Int32) Configuration {
+		config := NewWithOpts()
+		config.Set(CACHE_PATH, cachePath)
+		config.AddDefaultValue(ORGANIZATION, func(existingValue interface{}) (interface{}, error) {
+			calls.Add(1)
+			if config.GetBool(OFFLINE) {
+				return "", nil
+			}
+			return "org-for-" + config.GetString(API_URL), nil
+		}, WithDependencies(API_URL), WithOfflineFallback())
+		return config
+	}
+
+	var calls atomic.Int32
+	config := newConfig(&calls)
+	config.Set(API_URL, "https://api.snyk.io")
+	assert.Equal(t, "org-for-https://api.snyk.io", config.GetString(ORGANIZATION))
+	assert.Equal(t, int32(1), calls.Load())
+
+	// the last known value is used by other processes while offline
+	calls.Store(0)
+	offlineConfig := newConfig(&calls)
+	offlineConfig.Set(API_URL, "https://api.snyk.io")
+	offlineConfig.Set(OFFLINE, true)
+	assert.Equal(t, "org-for-https://api.snyk.io", offlineConfig.GetString(ORGANIZATION))
+	assert.Equal(t, int32(0), calls.Load())
+
+	// values are only used for the same inputs
+	offlineConfig.Set(API_URL, "https://api.eu.snyk.io")
+	assert.Empty(t, offlineConfig.GetString(ORGANIZATION))
+	assert.Equal(t, int32(1), calls.Load())
+
+	t.Run("values are only used for the same credentials", func(t *testing.T) {
+		var calls atomic.Int32
+		config := newConfig(&calls)
+		config.Set(API_URL, "https://api.snyk.io")
+		config.Set(AUTHENTICATION_TOKEN, "token-1")
+		assert.Equal(t, "org-for-https://api.snyk.io", config.GetString(ORGANIZATION))
+
+		offlineConfig := newConfig(&calls)
+		offlineConfig.Set(API_URL, "https://api.snyk.io")
+		offlineConfig.Set(AUTHENTICATION_TOKEN, "token-2")
+		offlineConfig.Set(OFFLINE, true)
+		assert.Empty(t, offlineConfig.GetString(ORGANIZATION))
+
+		offlineConfig.Set(AUTHENTICATION_TOKEN, "token-1")
+		assert.Equal(t, "org-for-https://api.snyk.io", offlineConfig.GetString(ORGANIZATION))
+	})
+
+	t.Run("types are preserved", func(t *testing.T) {
+		cachePath := t.TempDir()
+		newConfig := func(offline bool) Configuration {
+			config := NewWithOpts()
+			config.Set(CACHE_PATH, cachePath)
+			config.Set(OFFLINE, offline)
+			config.AddDefaultValue("flag", func(existingValue interface{}) (interface{}, error) {
+				return !offline, nil
+			}, WithOfflineFallback())
+			config.AddDefaultValue("number", func(existingValue interface{}) (interface{}, error) {
+				if offline {
+					return nil, nil
+				}
+				return 42, nil
+			}, WithOfflineFallback())
+			return config
+		}
+
+		config := newConfig(false)
+		assert.Equal(t, true, config.Get("flag"))
+		assert.Equal(t, 42, config.Get("number"))
+
+		offlineConfig := newConfig(true)
+		assert.Equal(t, true, offlineConfig.Get("flag"))
+		// numbers would be restored as float64, so they are not persisted
+		assert.Nil(t, offlineConfig.Get("number"))
+	})
+}
+
+func Test_Configuration_Dependencies(t *testing.T) {
+	t.Run("invalidates memoized values on transitive dependency changes", func(t *testing.T) {
+		config := NewWithOpts()
+		var calls atomic.Int32
+		config.AddDefaultValue(ORGANIZATION, StandardDefaultValueFunction("org"), WithDependencies(API_URL))
+		config.AddDefaultValue(ORGANIZATION_SLUG, func(existingValue interface{}) (interface{}, error) {
+			calls.Add(1)
+			return "slug", nil
+		}, WithMemoization(0), WithDependencies(ORGANIZATION))
+
+		assert.Equal(t, []string{API_URL}, config.GetDependencies(ORGANIZATION))
+		assert.Equal(t, "slug", config.GetString(ORGANIZATION_SLUG))
+		assert.Equal(t, "slug", config.GetString(ORGANIZATION_SLUG))
+		assert.Equal(t, int32(1), calls.Load())
+
+		config.Set(API_URL, "https://api.eu.snyk.io")
+		assert.Equal(t, "slug", config.GetString(ORGANIZATION_SLUG))
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("detects cycles", func(t *testing.T) {
+		config := NewWithOpts()
+		assert.NoError(t, config.AddDependencies(ORGANIZATION_SLUG, ORGANIZATION))
+		assert.NoError(t, config.AddDependencies(ORGANIZATION, API_URL))
+
+		err := config.AddDependencies(API_URL, ORGANIZATION_SLUG)
+		var cycleErr *DependencyCycleError
+		assert.ErrorAs(t, err, &cycleErr)
+		assert.Equal(t, []string{API_URL, ORGANIZATION_SLUG, ORGANIZATION, API_URL}, cycleErr.Cycle)
+		assert.Empty(t, config.GetDependencies(API_URL))
+
+		assert.ErrorAs(t, config.AddDependencies(API_URL, API_URL), &cycleErr)
+
+		// default values with cyclic dependencies are reported by the validation and surface the error when accessed
+		assert.NoError(t, ValidateDependencies(config))
+		config.AddDefaultValue(API_URL, StandardDefaultValueFunction("https://api.snyk.io"), WithDependencies(ORGANIZATION))
+		assert.ErrorContains(t, ValidateDependencies(config.Scope("test")), "configuration dependency cycle detected: snyk_api -> org -> snyk_api")
+		value, err := config.GetWithError(API_URL)
+		assert.Equal(t, "https://api.snyk.io", value)
+		assert.ErrorAs(t, err, &cycleErr)
+	})
+
+	t.Run("renders the graph", func(t *testing.T) {
+		config := NewWithOpts()
+		assert.NoError(t, config.AddDependencies(ORGANIZATION_SLUG, ORGANIZATION))
+		assert.NoError(t, config.AddDependencies(ORGANIZATION, API_URL))
+		assert.NoError(t, config.AddDependencies(WEB_APP_URL, API_URL))
+
+		expected := "digraph configuration {\n" +
+			"  \"internal_org_slug\" -> \"org\";\n" +
+			"  \"internal_snyk_app\" -> \"snyk_api\";\n" +
+			"  \"org\" -> \"snyk_api\";\n" +
+			"}\n"
+		assert.Equal(t, expected, config.Clone().DependencyGraph())
+	})
+}
+
+func Test_Configuration_Scope(t *testing.T) {
+	const key = "severity-threshold"
+
+	t.Run("local values fall back to global ones", func(t *testing.T) {
+		config := NewWithOpts()
+		scoped := config.Scope("code.test")
+		scoped.AddDefaultValue(key, StandardDefaultValueFunction(nil))
+
+		assert.False(t, scoped.IsSet(key))
+		config.Set(key, "low")
+		assert.True(t, scoped.IsSet(key))
+		assert.Equal(t, "low", scoped.GetString(key))
+
+		scoped.Set(key, "high")
+		assert.Equal(t, "high", scoped.GetString(key))
+		assert.Equal(t, "low", config.GetString(key))
+		assert.Equal(t, "high", config.GetString(ScopedKey("code.test", key)))
+		assert.Equal(t, "low", config.Scope("output").GetString(key))
+
+		scoped.Unset(key)
+		assert.Equal(t, "low", scoped.GetString(key))
+	})
+
+	t.Run("scopes created from other scopes see their explicitly set local values", func(t *testing.T) {
+		config := NewWithOpts()
+		codeFlags := pflag.NewFlagSet("code.test", pflag.ContinueOnError)
+		codeFlags.Bool("json", false, "")
+		outputFlags := pflag.NewFlagSet("output", pflag.ContinueOnError)
+		outputFlags.Bool("json", false, "")
+		outputFlags.String(key, "medium", "")
+		assert.NoError(t, config.Scope("code.test").AddFlagSet(codeFlags))
+		assert.NoError(t, config.Scope("output").AddFlagSet(outputFlags))
+		assert.NoError(t, codeFlags.Set("json", "true"))
+
+		output := ScopeFrom(config.Scope("code.test"), "output")
+		assert.True(t, output.GetBool("json"))
+		assert.True(t, output.IsSet("json"))
+		assert.True(t, output.Clone().GetBool("json"))
+		assert.Equal(t, "medium", output.GetString(key))
+		assert.False(t, config.Scope("output").GetBool("json"))
+
+		// local values of the new scope take precedence
+		output.Set("json", false)
+		assert.False(t, output.GetBool("json"))
+
+		codeScope := config.Scope("code.test")
+		assert.Same(t, codeScope, ScopeFrom(codeScope, "code.test"))
+	})
+
+	t.Run("values of keys that are not local are set globally", func(t *testing.T) {
+		config := NewWithOpts()
+		scoped := config.Scope("code.test")
+
+		scoped.Set(RAW_CMD_ARGS, []string{"--debug"})
+		assert.Equal(t, []string{"--debug"}, config.GetStringSlice(RAW_CMD_ARGS))
+		assert.Equal(t, []string{"--debug"}, config.Scope("legacycli").GetStringSlice(RAW_CMD_ARGS))
+
+		transaction := scoped.BeginTransaction()
+		transaction.Unset(RAW_CMD_ARGS)
+		assert.NoError(t, transaction.Commit())
+		assert.Empty(t, config.GetStringSlice(RAW_CMD_ARGS))
+	})
+
+	t.Run("configuration wide methods act on the global configuration", func(t *testing.T) {
+		config := NewWithOpts()
+		storageFile := filepath.Join(t.TempDir(), "snyk.json")
+		storage := NewJsonStorage(storageFile)
+		scoped := config.Scope("code.test")
+		scoped.AddDefaultValue(key, StandardDefaultValueFunction("low"))
+		scoped.SetStorage(storage)
+		scoped.PersistInStorage(ORGANIZATION)
+		scoped.LockValue(API_URL, "https://api.eu.snyk.io")
+
+		assert.Equal(t, storage, config.GetStorage())
+		assert.True(t, config.IsLocked(API_URL))
+		assert.Equal(t, "https://api.eu.snyk.io", scoped.GetString(API_URL))
+		assert.Same(t, config, Unscoped(scoped))
+		assert.Same(t, config, Unscoped(config))
+
+		// local values are never persisted
+		transaction := scoped.BeginTransaction()
+		transaction.Set(key, "medium")
+		transaction.Set(ORGANIZATION, "my-org")
+		assert.NoError(t, transaction.Commit())
+		assert.Equal(t, "medium", scoped.GetString(key))
+		assert.Nil(t, config.Get(key))
+		content, err := os.ReadFile(storageFile)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"org":"my-org"}`, string(content))
+	})
+
+	t.Run("local defaults have lower precedence than explicit global values", func(t *testing.T) {
+		config := NewWithOpts()
+		config.AddDefaultValue(key, StandardDefaultValueFunction("low"))
+		scoped := config.Scope("code").Scope("test")
+		scoped.AddDefaultValue(key, StandardDefaultValueFunction("medium"))
+
+		assert.Equal(t, "medium", scoped.GetString(key))
+		assert.Equal(t, "low", config.GetString(key))
+
+		config.Set(key, "critical")
+		assert.Equal(t, "critical", scoped.GetString(key))
+	})
+
+	t.Run("flags with the same name", func(t *testing.T) {
+		config := NewWithOpts()
+		codeFlags := pflag.NewFlagSet("code", pflag.ContinueOnError)
+		codeFlags.Int("threads", 4, "")
+		outputFlags := pflag.NewFlagSet("output", pflag.ContinueOnError)
+		outputFlags.Int("threads", 1, "")
+
+		assert.NoError(t, config.Scope("code.test").AddFlagSet(codeFlags))
+		assert.NoError(t, config.Scope("output").AddFlagSet(outputFlags))
+		assert.NoError(t, codeFlags.Parse([]string{"--threads=8"}))
+
+		clone := config.Clone()
+		for _, c := range []Configuration{config, clone} {
+			assert.Equal(t, 8, c.Scope("code.test").GetInt("threads"))
+			assert.Equal(t, 1, c.Scope("output").GetInt("threads"))
+		}
+
+		scopedClone := config.Scope("output").Clone()
+		assert.Equal(t, 1, scopedClone.GetInt("threads"))
+	})
+}
+
+func Test_Configuration_LockValue(t *testing.T) {
+	config := NewWithOpts()
+	config.AddDefaultValue(FLAG_SEVERITY_THRESHOLD, StandardDefaultValueFunction("low"))
+	config.LockValue(FLAG_SEVERITY_THRESHOLD, "high", "critical")
+	config.LockValue(INSECURE_HTTPS, false)
+
+	assert.True(t, config.IsLocked(INSECURE_HTTPS))
+	assert.False(t, config.IsLocked(ORGANIZATION))
+	assert.Equal(t, []string{INSECURE_HTTPS, FLAG_SEVERITY_THRESHOLD}, config.GetLockedKeys())
+	assert.Equal(t, "high", config.GetString(FLAG_SEVERITY_THRESHOLD))
+	assert.NoError(t, ValidateLockedValues(config))
+
+	// allowed values can be set
+	config.Set(FLAG_SEVERITY_THRESHOLD, "critical")
+	assert.Equal(t, "critical", config.GetString(FLAG_SEVERITY_THRESHOLD))
+	config.Set(INSECURE_HTTPS, "false")
+	assert.NoError(t, ValidateLockedValues(config))
+
+	// other values are rejected
+	config.Set(INSECURE_HTTPS, true)
+	clone := config.Clone()
+	value, err := clone.GetWithError(INSECURE_HTTPS)
+	assert.Equal(t, false, value)
+	var lockedErr *LockedValueError
+	assert.ErrorAs(t, err, &lockedErr)
+	assert.Equal(t, INSECURE_HTTPS, lockedErr.Key)
+	assert.Equal(t, true, lockedErr.RejectedValue)
+	assert.ErrorAs(t, ValidateLockedValues(clone), &lockedErr)
+
+	// locks apply to scopes as well
+	config.Set(INSECURE_HTTPS, false)
+	scoped := config.Scope("code.test")
+	scoped.AddDefaultValue(FLAG_SEVERITY_THRESHOLD, StandardDefaultValueFunction("medium"))
+	assert.Equal(t, "critical", scoped.GetString(FLAG_SEVERITY_THRESHOLD))
+	scoped.Set(FLAG_SEVERITY_THRESHOLD, "low")
+	assert.Equal(t, "high", scoped.GetString(FLAG_SEVERITY_THRESHOLD))
+	assert.ErrorAs(t, ValidateLockedValues(scoped), &lockedErr)
+	assert.Equal(t, "low", lockedErr.RejectedValue)
+
+	scoped.Set(FLAG_SEVERITY_THRESHOLD, "critical")
+	assert.Equal(t, "critical", scoped.GetString(FLAG_SEVERITY_THRESHOLD))
+}
+
+func Test_sameValue(t *testing.T) {
+	assert.True(t, sameValue(false, false))
+	assert.True(t, sameValue(false, "false"))
+	assert.False(t, sameValue(false, "no"))
+	assert.False(t, sameValue(false, nil))
+	assert.True(t, sameValue("high", "high"))
+	assert.False(t, sameValue("true", true))
+	assert.False(t, sameValue("<nil>", nil))
+	assert.True(t, sameValue(float64(5), 5))
+	assert.True(t, sameValue(float64(5), "5"))
+	assert.False(t, sameValue(float64(5), "five"))
+	assert.True(t, sameValue([]interface{}{"a"}, []interface{}{"a"}))
+	assert.False(t, sameValue([]interface{}{"a"}, "[a]"))
+}
+
+func Test_JsonStorage_SetMany(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	storage := NewJsonStorage(path)
+
+	assert.NoError(t, storage.Set("a", "1"))
+	assert.NoError(t, storage.Set("b", "2"))
+	assert.NoError(t, storage.SetMany(map[string]any{"a": keyDeleted, "c": "3"}))
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"b":"2","c":"3"}`, string(content))
+
+	// new files get the same permissions as with os.WriteFile, existing permissions are kept
+	referencePath := filepath.Join(t.TempDir(), "reference.json")
+	assert.NoError(t, os.WriteFile(referencePath, []byte("{}"), utils.FILEPERM_666))
+	reference, err := os.Stat(referencePath)
+	assert.NoError(t, err)
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, reference.Mode().Perm(), info.Mode().Perm())
+
+	assert.NoError(t, os.Chmod(path, 0o640))
+	assert.NoError(t, storage.Set("e", "5"))
+	info, err = os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+
+	// no temporary files remain
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	for _, entry := range entries {
+		assert.False(t, strings.HasSuffix(entry.Name(), ".tmp"), entry.Name())
+	}
+
+	// an already held lock is kept
+	assert.NoError(t, storage.Lock(context.Background(), time.Millisecond))
+	assert.NoError(t, storage.SetMany(map[string]any{"d": "4"}))
+	assert.True(t, storage.fileLock.Locked())
+	assert.NoError(t, storage.Unlock())
+}
+
+func Test_Configuration_Transaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	config := NewWithOpts()
+	config.SetStorage(NewJsonStorage(path, WithConfiguration(config)))
+	config.PersistInStorage(ORGANIZATION)
+	config.PersistInStorage(API_URL)
+	config.Set(ORGANIZATION, "my-org")
+	config.Set(API_URL, "https://api.snyk.io")
+
+	transaction := config.BeginTransaction()
+	transaction.Unset(ORGANIZATION)
+	transaction.Set(API_URL, "https://api.eu.snyk.io")
+	transaction.Set(MAX_THREADS, 2)
+
+	// changes are not visible before commit
+	assert.Equal(t, "https://api.snyk.io", config.GetString(API_URL))
+
+	assert.NoError(t, transaction.Commit())
+	assert.Equal(t, "https://api.eu.snyk.io", config.GetString(API_URL))
+	assert.Equal(t, 2, config.GetInt(MAX_THREADS))
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"snyk_api":"https://api.eu.snyk.io"}`, string(content))
+
+	transaction = config.BeginTransaction()
+	transaction.Set(API_URL, "https://api.us.snyk.io")
+	transaction.Rollback()
+	assert.NoError(t, transaction.Commit())
+	assert.Equal(t, "https://api.eu.snyk.io", config.GetString(API_URL))
+
+	t.Run("changes are not applied if they can't be persisted", func(t *testing.T) {
+		// the parent of the storage file is a file, so that it can't be written
+		config.SetStorage(NewJsonStorage(filepath.Join(path, "config.json"), WithConfiguration(config)))
+
+		transaction = config.BeginTransaction()
+		transaction.Set(API_URL, "https://api.us.snyk.io")
+		transaction.Set(MAX_THREADS, 4)
+		transaction.Unset(ORGANIZATION)
+		assert.Error(t, transaction.Commit())
+		assert.Equal(t, "https://api.eu.snyk.io", config.GetString(API_URL))
+		assert.Equal(t, 2, config.GetInt(MAX_THREADS))
+	})
+
+	t.Run("storages without SetMany store the values one by one", func(t *testing.T) {
+		sequentialPath := filepath.Join(t.TempDir(), "config.json")
+		// only the methods of the Storage interface are promoted
+		config.SetStorage(struct{ Storage }{NewJsonStorage(sequentialPath, WithConfiguration(config))})
+
+		transaction = config.BeginTransaction()
+		transaction.Set(API_URL, "https://api.us.snyk.io")
+		transaction.Set(ORGANIZATION, "other-org")
+		assert.NoError(t, transaction.Commit())
+		assert.Equal(t, "https://api.us.snyk.io", config.GetString(API_URL))
+
+		content, err := os.ReadFile(sequentialPath)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"snyk_api":"https://api.us.snyk.io","org":"other-org"}`, string(content))
+	})
+
+	t.Run("locked values are rejected", func(t *testing.T) {
+		config.SetStorage(NewJsonStorage(path, WithConfiguration(config)))
+		config.LockValue(MAX_THREADS, 2, 3)
+
+		transaction = config.BeginTransaction()
+		transaction.Set(API_URL, "https://api.eu.snyk.io")
+		transaction.Set(MAX_THREADS, 8)
+		var lockedErr *LockedValueError
+		assert.ErrorAs(t, transaction.Commit(), &lockedErr)
+		assert.Equal(t, MAX_THREADS, lockedErr.Key)
+		assert.Equal(t, "https://api.us.snyk.io", config.GetString(API_URL))
+		assert.Equal(t, 2, config.GetInt(MAX_THREADS))
+
+		// allowed values can be set
+		transaction = config.BeginTransaction()
+		transaction.Set(MAX_THREADS, 3)
+		assert.NoError(t, transaction.Commit())
+		assert.Equal(t, 3, config.GetInt(MAX_THREADS))
+	})
+}
+
+func Test_Configuration_EnvVarMappings(t *testing.T) {
+	config := NewWithOpts()
+	config.AddEnvVarMapping("SNYK_CFG_ORG", ORGANIZATION, "Organization ID or slug")
+	config.AddEnvVarMapping("snyk_api", API_URL, "URL of the Snyk API | endpoint")
+	config.AddEnvVarMapping("SNYK_CFG_ORG", API_URL, "ignored duplicate")
+
+	t.Setenv("SNYK_CFG_ORG", "my-org")
+	t.Setenv("SNYK_API", "https://api.eu.snyk.io")
+	t.Setenv("SNYK_UNKNOWN_SETTING", "1")
+	t.Setenv("SNYK_DISABLE_ANALYTICS", "1")
+
+	assert.Equal(t, "my-org", config.GetString(ORGANIZATION))
+	assert.Equal(t, "my-org", config.Clone().GetString(ORGANIZATION))
+	assert.Equal(t, "https://api.eu.snyk.io", config.GetString(API_URL))
+
+	// explicitly set values take precedence
+	config.Set(ORGANIZATION, "other-org")
+	assert.Equal(t, "other-org", config.GetString(ORGANIZATION))
+
+	key, ok := config.GetKeyForEnvVar("snyk_cfg_org")
+	assert.True(t, ok)
+	assert.Equal(t, ORGANIZATION, key)
+	_, ok = config.GetKeyForEnvVar("SNYK_UNKNOWN_SETTING")
+	assert.False(t, ok)
+
+	mappings := config.GetEnvVarMappings()
+	assert.Equal(t, []EnvVarMapping{
+		{EnvVar: "SNYK_API", Key: API_URL, Description: "URL of the Snyk API | endpoint"},
+		{EnvVar: "SNYK_CFG_ORG", Key: ORGANIZATION, Description: "Organization ID or slug"},
+	}, mappings)
+
+	config.AddDefaultValue(ANALYTICS_DISABLED, StandardDefaultValueFunction(false))
+	environ := []string{"SNYK_CFG_ORG=my-org", "SNYK_UNKNOWN_SETTING=1", "snyk_disable_analytics=1", "HOME=/home/user"}
+	assert.Equal(t, []string{"SNYK_UNKNOWN_SETTING"}, FindUnrecognizedEnvVars(config, environ))
+
+	expectedMarkdown := "| Environment Variable | Configuration Key | Description |\n" +
+		"|---|---|---|\n" +
+		"| `SNYK_API` | `snyk_api` | URL of the Snyk API \\| endpoint |\n" +
+		"| `SNYK_CFG_ORG` | `org` | Organization ID or slug |\n"
+	assert.Equal(t, expectedMarkdown, EnvVarMappingsToMarkdown(mappings))
+
+	jsonBytes, err := EnvVarMappingsToJson(mappings)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"env_var": "SNYK_API", "key": "snyk_api", "description": "URL of the Snyk API | endpoint"},
+		{"env_var": "SNYK_CFG_ORG", "key": "org", "description": "Organization ID or slug"}
+	]`, string(jsonBytes))
+}
+
+func Test_Configuration_EnvVarMappings_KeepEnvVarOfKey(t *testing.T) {
+	config := NewWithOpts(WithSupportedEnvVars(ORGANIZATION), WithSupportedEnvVarPrefixes("snyk_"))
+	config.AddAlternativeKeys(API_URL, []string{"snyk_cfg_api_url"})
+	config.AddEnvVarMapping("SNYK_CFG_ORG", ORGANIZATION, "Organization ID or slug")
+	config.AddEnvVarMapping("SNYK_CFG_ENDPOINT", API_URL, "Alternative to SNYK_API")
+	config.AddEnvVarMapping("SNYK_ENDPOINT", API_URL, "Alternative to SNYK_API")
+
+	// the mapped env var is used if the env var of the key isn't set
+	t.Setenv("SNYK_CFG_ORG", "org-mapped")
+	assert.Equal(t, "org-mapped", config.GetString(ORGANIZATION))
+	assert.True(t, config.IsSet(ORGANIZATION))
+
+	// the env var of the key takes precedence over mapped env vars
+	t.Setenv("ORG", "org-env")
+	assert.Equal(t, "org-env", config.GetString(ORGANIZATION))
+	assert.Equal(t, "org-env", config.Clone().GetString(ORGANIZATION))
+
+	// mapped env vars are used in the order of registration, after alternative keys
+	t.Setenv("SNYK_ENDPOINT", "https://api.endpoint.snyk.io")
+	t.Setenv("SNYK_CFG_ENDPOINT", "https://api.cfg-endpoint.snyk.io")
+	assert.Equal(t, "https://api.cfg-endpoint.snyk.io", config.GetString(API_URL))
+	assert.Equal(t, "https://api.cfg-endpoint.snyk.io", config.Clone().GetString(API_URL))
+	t.Setenv("SNYK_CFG_API_URL", "https://api.alternative.snyk.io")
+	assert.Equal(t, "https://api.alternative.snyk.io", config.GetString(API_URL))
+	t.Setenv("SNYK_API", "https://api.eu.snyk.io")
+	assert.Equal(t, "https://api.eu.snyk.io", config.GetString(API_URL))
+}
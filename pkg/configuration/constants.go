@@ -6,6 +6,9 @@ const (
 	DEBUG_FORMAT                   string = "debug_format"
 	INSECURE_HTTPS                 string = "insecure"
 	PROXY_AUTHENTICATION_MECHANISM string = "proxy_auth"
+	PROXY_URL                      string = "proxy_url"      // explicit http, https or socks5 proxy URL for all requests, takes precedence over PROXY_PAC and the environment
+	PROXY_NO_PROXY                 string = "proxy_no_proxy" // array of hosts, domains and CIDR ranges that are connected to directly
+	PROXY_PAC                      string = "proxy_pac"      // file path or URL of a proxy auto-config (PAC) script
 	INPUT_DIRECTORY                string = "targetDirectory"
 	FIPS_ENABLED                   string = "gofips"
 
@@ -28,6 +31,7 @@ const (
 	CACHE_PATH                      string = "snyk_cache_path"
 	TIMEOUT                         string = "snyk_timeout_secs"
 	LOG_LEVEL                       string = "snyk_log_level" // string that defines the log level based on zerolog levels (trace,debug,info,...)
+	OFFLINE                         string = "snyk_offline"   // boolean to fail network requests fast and to use the last known values of network based defaults
 
 	// internal constants
 	CUSTOM_CONFIG_FILES            string = "internal_custom_config_files"
@@ -38,12 +42,40 @@ const (
 	WORKING_DIRECTORY              string = "internal_working_dir"
 	IS_FEDRAMP                     string = "internal_is_fedramp"
 	ORGANIZATION_SLUG              string = "internal_org_slug"
-	AUTHENTICATION_SUBDOMAINS      string = "internal_auth_subdomain"             // array of additional subdomains to add authentication for
-	AUTHENTICATION_ADDITIONAL_URLS string = "internal_additional_auth_urls"       // array of additional urls to add authentication for
-	ADD_TRUSTED_CA_FILE            string = "internal_additional_trusted_ca_file" // pem file location containing additional CAs to trust
-	PREVIEW_FEATURES_ENABLED       string = "internal_preview_features_enabled"   // boolean indicates if preview features shall be enabled
-	UNKNOWN_ARGS                   string = "internal_unknown_arguments"          // arguments unknown to the current application but maybe relevant for delegated application calls
-	IN_MEMORY_THRESHOLD_BYTES      string = "internal_in_memory_threshold_bytes"  // threshold to determine where to store workflow.Data
+	AUTHENTICATION_SUBDOMAINS      string = "internal_auth_subdomain"                  // array of additional subdomains to add authentication for
+	AUTHENTICATION_ADDITIONAL_URLS string = "internal_additional_auth_urls"            // array of additional urls to add authentication for
+	ADD_TRUSTED_CA_FILE            string = "internal_additional_trusted_ca_file"      // pem file or directory location containing additional CAs to trust
+	ADD_TRUSTED_CA_LOCATIONS       string = "internal_additional_trusted_ca_locations" // array of further pem file or directory locations containing CAs to trust
+	TRUSTED_CA_EXCLUSIVE           string = "internal_trusted_ca_exclusive"            // boolean to only trust the CAs of ADD_TRUSTED_CA_FILE and ADD_TRUSTED_CA_LOCATIONS instead of adding them to the system CAs, the system CAs are used if none are configured
+	API_SPKI_PINS                  string = "internal_api_spki_pins"                   // array of base64 encoded SHA-256 hashes of public keys, one of which must be presented by the API host
+	PREVIEW_FEATURES_ENABLED       string = "internal_preview_features_enabled"        // boolean indicates if preview features shall be enabled
+	UNKNOWN_ARGS                   string = "internal_unknown_arguments"               // arguments unknown to the current application but maybe relevant for delegated application calls
+	IN_MEMORY_THRESHOLD_BYTES      string = "internal_in_memory_threshold_bytes"       // threshold to determine where to store workflow.Data
+	MAX_RETRY_ATTEMPTS             string = "internal_max_retry_attempts"              // number of attempts for failed idempotent network requests, values below 2 disable retries
+	RATE_LIMIT_REQUESTS_PER_SECOND string = "internal_rate_limit_rps"                  // client side rate limit for requests to Snyk hosts, values below or equal 0 disable the limit
+	RATE_LIMIT_BURST               string = "internal_rate_limit_burst"                // number of requests to Snyk hosts that can be sent at once before the rate limit applies
+	RATE_LIMITS                    string = "internal_rate_limits"                     // array of "prefix=rps" entries limiting the requests to a host or URL prefix, with a burst of RATE_LIMIT_BURST
+	CIRCUIT_BREAKER_THRESHOLD      string = "internal_circuit_breaker_threshold"       // number of consecutive failures after which requests to a host fail fast, values below 1, the default, disable the circuit breaker
+	CIRCUIT_BREAKER_COOLDOWN_SECS  string = "internal_circuit_breaker_cooldown"        // seconds to wait before a host with an open circuit is probed again
+	CONNECT_TIMEOUT_SECS           string = "internal_connect_timeout"                 // seconds to wait for a connection to be established, including proxy authentication
+	TLS_HANDSHAKE_TIMEOUT_SECS     string = "internal_tls_handshake_timeout"           // seconds to wait for the TLS handshake
+	RESPONSE_HEADER_TIMEOUT_SECS   string = "internal_response_header_timeout"         // seconds to wait for the response headers once the request was sent
+	HOST_TIMEOUTS                  string = "internal_host_timeouts"                   // array of "host=seconds" entries overriding TIMEOUT for individual hosts
+	MAX_IDLE_CONNECTIONS           string = "internal_max_idle_conns"                  // number of idle connections kept for reuse across all hosts
+	MAX_IDLE_CONNECTIONS_PER_HOST  string = "internal_max_idle_conns_per_host"         // number of idle connections kept for reuse per host
+	IDLE_CONNECTION_TIMEOUT_SECS   string = "internal_idle_conn_timeout"               // seconds after which an unused connection is closed
+	HTTP2_DISABLED                 string = "internal_http2_disabled"                  // boolean to restrict connections to HTTP/1.1
+	HTTP2_READ_IDLE_TIMEOUT_SECS   string = "internal_http2_read_idle_timeout"         // seconds without received frames after which an HTTP/2 connection is health checked, 0 disables health checks
+	HTTP2_PING_TIMEOUT_SECS        string = "internal_http2_ping_timeout"              // seconds after which an HTTP/2 connection failing the health check is closed
+	OAUTH_REFRESH_WINDOW_SECS      string = "internal_oauth_refresh_window"            // seconds before the expiry of an oauth token in which it is renewed ahead of time, 0 only renews expired tokens
+	RECORD_REPLAY_MODE             string = "internal_record_replay_mode"              // "record" or "replay" to capture network interactions into or answer them from RECORD_REPLAY_FILE, e.g. for tests
+	RECORD_REPLAY_FILE             string = "internal_record_replay_file"              // fixture file used by RECORD_REPLAY_MODE
+	HTTP_CACHE_ENABLED             string = "internal_http_cache_enabled"              // boolean to cache GET responses below CACHE_PATH
+	HAR_FILE                       string = "internal_har_file"                        // file location to write all network traffic to in HAR 1.2 format, sensitive values are scrubbed
+	TLS_CLIENT_CERTIFICATE_FILE    string = "internal_tls_client_certificate_file"     // pem file location of the client certificate chain used for mutual TLS
+	TLS_CLIENT_KEY_FILE            string = "internal_tls_client_key_file"             // pem file location of the private key of TLS_CLIENT_CERTIFICATE_FILE, optionally an encrypted PKCS#8 key
+	//nolint:gosec // not a password value, a configuration key
+	TLS_CLIENT_KEY_PASSWORD string = "internal_tls_client_key_password" // password of an encrypted TLS_CLIENT_KEY_FILE
 	// feature flags
 	FF_OAUTH_AUTH_FLOW_ENABLED string = "internal_snyk_oauth_enabled"
 	FF_CODE_CONSISTENT_IGNORES string = "internal_snyk_code_ignores_enabled"
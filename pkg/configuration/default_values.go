@@ -0,0 +1,173 @@
+package configuration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultMemoizationTTL is the recommended duration to memoize default values that are determined via network requests.
+const DefaultMemoizationTTL = 10 * time.Minute
+
+// maxMemoizedValues limits the number of memoized results per default value, e.g. for different accounts or API URLs.
+const maxMemoizedValues = 32
+
+// DefaultValueOption configures how a DefaultValueFunction is handled, see AddDefaultValue.
+type DefaultValueOption func(entry *defaultValueEntry)
+
+// defaultValueEntry holds a DefaultValueFunction and its options.
+type defaultValueEntry struct {
+	function     DefaultValueFunction
+	dependencies []string
+	prefetch     bool
+
+	// err is set if the entry could not be registered as requested, e.g. due to a dependency cycle.
+	err error
+
+	// cache is shared between clones of a configuration, it is nil if memoization is not enabled.
+	cache *defaultValueCache
+
+	// offlineFallback persists the results for OFFLINE mode, see WithOfflineFallback.
+	offlineFallback bool
+}
+
+// WithMemoization caches the results of the DefaultValueFunction for the given duration, a ttl of zero caches them
+// forever. The cache is shared between clones of the configuration and is invalidated whenever the existing value
+// or the value of any dependency changes, see WithDependencies.
+// Errors, nil and empty string results are not cached.
+func WithMemoization(ttl time.Duration) DefaultValueOption {
+	return func(entry *defaultValueEntry) {
+		entry.cache = newDefaultValueCache(ttl)
+	}
+}
+
+// WithDependencies declares which configuration keys the DefaultValueFunction uses to determine its result,
+// see AddDependencies.
+func WithDependencies(keys ...string) DefaultValueOption {
+	return func(entry *defaultValueEntry) {
+		entry.dependencies = append(entry.dependencies, keys...)
+	}
+}
+
+// WithPrefetch marks the default value to be determined ahead of time by PrefetchDefaultValues.
+// It only has an effect in combination with WithMemoization.
+func WithPrefetch() DefaultValueOption {
+	return func(entry *defaultValueEntry) {
+		entry.prefetch = true
+	}
+}
+
+type cachedDefaultValue struct {
+	value     interface{}
+	storedAt  time.Time
+	expiresAt time.Time
+}
+
+// defaultValueCache memoizes the results of a DefaultValueFunction. Concurrent requests for the same fingerprint
+// are deduplicated, so that a value is only determined once. Expired results are removed and at most
+// maxMemoizedValues results are kept.
+type defaultValueCache struct {
+	ttl     time.Duration
+	mutex   sync.Mutex
+	entries map[string]cachedDefaultValue
+	group   singleflight.Group
+}
+
+func newDefaultValueCache(ttl time.Duration) *defaultValueCache {
+	return &defaultValueCache{
+		ttl:     ttl,
+		entries: map[string]cachedDefaultValue{},
+	}
+}
+
+// get returns the cached value for the fingerprint or invokes determine to compute and cache it.
+func (c *defaultValueCache) get(fingerprint string, determine func() (interface{}, error)) (interface{}, error) {
+	c.mutex.Lock()
+	cached, ok := c.entries[fingerprint]
+	c.mutex.Unlock()
+
+	if ok && (c.ttl == 0 || time.Now().Before(cached.expiresAt)) {
+		return cached.value, nil
+	}
+
+	value, err, _ := c.group.Do(fingerprint, func() (interface{}, error) {
+		result, err := determine()
+		if err == nil && result != nil && result != "" {
+			c.store(fingerprint, result)
+		}
+		return result, err
+	})
+	return value, err
+}
+
+func (c *defaultValueCache) store(fingerprint string, value interface{}) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	for key, cached := range c.entries {
+		if c.ttl != 0 && !now.Before(cached.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+
+	delete(c.entries, fingerprint)
+	for len(c.entries) >= maxMemoizedValues {
+		oldest := ""
+		for key, cached := range c.entries {
+			if len(oldest) == 0 || cached.storedAt.Before(c.entries[oldest].storedAt) {
+				oldest = key
+			}
+		}
+		delete(c.entries, oldest)
+	}
+
+	c.entries[fingerprint] = cachedDefaultValue{value: value, storedAt: now, expiresAt: now.Add(c.ttl)}
+}
+
+// defaultValueFingerprint identifies the inputs of a DefaultValueFunction, it must be called while holding the lock.
+// The inputs are hashed, since dependencies might be credentials and fingerprints are persisted for the offline mode.
+func (ev *extendedViper) defaultValueFingerprint(key string, existingValue interface{}) string {
+	inputs := []interface{}{existingValue}
+	for _, dependency := range ev.transitiveDependencies(key) {
+		//nolint:errcheck // binding env vars is best effort, the value is used as is
+		value, _ := ev.get(dependency)
+		inputs = append(inputs, value)
+	}
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%#v", inputs)))
+	return hex.EncodeToString(hash[:])
+}
+
+// PrefetchDefaultValues concurrently determines all memoized default values that were registered with WithPrefetch.
+// It blocks until all values have been determined; later calls to Get are served from the cache. Callers need to
+// parse flags before, since the values depend on them, e.g. on the API URL. Nothing is prefetched while OFFLINE is
+// enabled, since the values would be served from the offline fallback anyway.
+func (ev *extendedViper) PrefetchDefaultValues() {
+	if ev.GetBool(OFFLINE) {
+		return
+	}
+
+	ev.mutex.RLock()
+	keys := []string{}
+	for key, entry := range ev.defaultValues {
+		if entry.prefetch && entry.cache != nil {
+			keys = append(keys, key)
+		}
+	}
+	ev.mutex.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, key := range keys {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			//nolint:errcheck // errors are not cached and surface when the value is accessed
+			_, _ = ev.GetWithError(key)
+		}(key)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,131 @@
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DependencyCycleError is returned when declaring a dependency would introduce a cycle between configuration keys.
+type DependencyCycleError struct {
+	Cycle []string
+}
+
+func (e *DependencyCycleError) Error() string {
+	return fmt.Sprintf("configuration dependency cycle detected: %s", strings.Join(e.Cycle, " -> "))
+}
+
+// AddDependencies declares that the value of key is derived from the given dependencies. Memoized default values are
+// invalidated whenever the value of a direct or transitive dependency changes, see WithMemoization.
+// If one of the dependencies would introduce a cycle, none of them are added and a DependencyCycleError is returned.
+func (ev *extendedViper) AddDependencies(key string, dependencies ...string) error {
+	ev.mutex.Lock()
+	defer ev.mutex.Unlock()
+
+	return ev.addDependencies(key, dependencies)
+}
+
+// addDependencies must be called while holding the lock.
+func (ev *extendedViper) addDependencies(key string, dependencies []string) error {
+	for _, dependency := range dependencies {
+		if path := ev.dependencyPath(dependency, key); path != nil {
+			return &DependencyCycleError{Cycle: append([]string{key}, path...)}
+		}
+	}
+
+	for _, dependency := range dependencies {
+		if !slices.Contains(ev.dependencies[key], dependency) {
+			ev.dependencies[key] = append(ev.dependencies[key], dependency)
+		}
+	}
+	return nil
+}
+
+// dependencyPath returns the path from one key to another following the declared dependencies, nil if there is none.
+func (ev *extendedViper) dependencyPath(from string, to string) []string {
+	if from == to {
+		return []string{to}
+	}
+
+	for _, dependency := range ev.dependencies[from] {
+		if path := ev.dependencyPath(dependency, to); path != nil {
+			return append([]string{from}, path...)
+		}
+	}
+	return nil
+}
+
+// ValidateDependencies returns an error for every default value whose dependencies could not be added, e.g. a
+// DependencyCycleError. Such errors otherwise only surface when accessing the value, see AddDefaultValue.
+func ValidateDependencies(config Configuration) error {
+	ev, ok := Unscoped(config).(*extendedViper)
+	if !ok {
+		return nil
+	}
+
+	ev.mutex.RLock()
+	defer ev.mutex.RUnlock()
+
+	keys := make([]string, 0, len(ev.defaultValues))
+	for key := range ev.defaultValues {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var err error
+	for _, key := range keys {
+		err = errors.Join(err, ev.defaultValues[key].err)
+	}
+	return err
+}
+
+// GetDependencies returns the direct dependencies of the given key.
+func (ev *extendedViper) GetDependencies(key string) []string {
+	ev.mutex.RLock()
+	defer ev.mutex.RUnlock()
+
+	return slices.Clone(ev.dependencies[key])
+}
+
+// transitiveDependencies returns all direct and indirect dependencies of the given key, it must be called while
+// holding the lock.
+func (ev *extendedViper) transitiveDependencies(key string) []string {
+	result := []string{}
+	queue := slices.Clone(ev.dependencies[key])
+	for len(queue) > 0 {
+		dependency := queue[0]
+		queue = queue[1:]
+		if slices.Contains(result, dependency) {
+			continue
+		}
+		result = append(result, dependency)
+		queue = append(queue, ev.dependencies[dependency]...)
+	}
+	return result
+}
+
+// DependencyGraph renders the declared dependencies between configuration keys in the DOT language, e.g. to be
+// visualized with Graphviz.
+func (ev *extendedViper) DependencyGraph() string {
+	ev.mutex.RLock()
+	defer ev.mutex.RUnlock()
+
+	keys := make([]string, 0, len(ev.dependencies))
+	for key := range ev.dependencies {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var sb strings.Builder
+	sb.WriteString("digraph configuration {\n")
+	for _, key := range keys {
+		dependencies := slices.Clone(ev.dependencies[key])
+		slices.Sort(dependencies)
+		for _, dependency := range dependencies {
+			sb.WriteString(fmt.Sprintf("  %q -> %q;\n", key, dependency))
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
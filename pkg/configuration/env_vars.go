@@ -0,0 +1,114 @@
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// EnvVarMapping documents which configuration key an environment variable sets.
+type EnvVarMapping struct {
+	EnvVar      string `json:"env_var"`
+	Key         string `json:"key"`
+	Description string `json:"description,omitempty"`
+}
+
+// unrecognizedEnvVarPrefix is the prefix of env vars that are expected to be handled by the configuration.
+const unrecognizedEnvVarPrefix = "SNYK_"
+
+// AddEnvVarMapping registers an environment variable as a source for the given key, e.g. SNYK_CFG_ORG for
+// ORGANIZATION. Mapped env vars are only used if neither the key nor one of its alternative keys is set, including
+// via their own env vars. If several env vars are registered for the same key, the first one that is defined is used.
+func (ev *extendedViper) AddEnvVarMapping(envVar string, key string, description string) {
+	ev.mutex.Lock()
+	defer ev.mutex.Unlock()
+
+	envVar = strings.ToUpper(envVar)
+	if _, exists := ev.envVarMappings[envVar]; exists {
+		return
+	}
+
+	// the mappings are not bound in viper, since this would register the key and thereby prevent bindEnv from
+	// binding the env var of the key itself
+	ev.envVarMappings[envVar] = EnvVarMapping{EnvVar: envVar, Key: key, Description: description}
+	ev.envVarsByKey[key] = append(ev.envVarsByKey[key], envVar)
+}
+
+// lookupMappedEnvVar returns the value of the first defined env var registered for the key, it must be called while
+// holding the lock.
+func (ev *extendedViper) lookupMappedEnvVar(key string) (string, bool) {
+	for _, envVar := range ev.envVarsByKey[key] {
+		if value, found := os.LookupEnv(envVar); found {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// GetEnvVarMappings returns all registered environment variables sorted by name.
+func (ev *extendedViper) GetEnvVarMappings() []EnvVarMapping {
+	ev.mutex.RLock()
+	defer ev.mutex.RUnlock()
+
+	mappings := make([]EnvVarMapping, 0, len(ev.envVarMappings))
+	for _, mapping := range ev.envVarMappings {
+		mappings = append(mappings, mapping)
+	}
+	slices.SortFunc(mappings, func(a, b EnvVarMapping) int {
+		return strings.Compare(a.EnvVar, b.EnvVar)
+	})
+	return mappings
+}
+
+// GetKeyForEnvVar returns the configuration key the given environment variable is registered for.
+func (ev *extendedViper) GetKeyForEnvVar(envVar string) (string, bool) {
+	ev.mutex.RLock()
+	defer ev.mutex.RUnlock()
+
+	mapping, ok := ev.envVarMappings[strings.ToUpper(envVar)]
+	return mapping.Key, ok
+}
+
+// FindUnrecognizedEnvVars returns the names of all SNYK_ environment variables in environ, e.g. os.Environ(), that are
+// neither registered via AddEnvVarMapping nor match a known configuration key.
+func FindUnrecognizedEnvVars(config Configuration, environ []string) []string {
+	knownKeys := map[string]bool{}
+	for _, key := range config.AllKeys() {
+		knownKeys[strings.ToUpper(key)] = true
+	}
+
+	result := []string{}
+	for _, entry := range environ {
+		name, _, _ := strings.Cut(entry, "=")
+		upperName := strings.ToUpper(name)
+		if !strings.HasPrefix(upperName, unrecognizedEnvVarPrefix) || knownKeys[upperName] {
+			continue
+		}
+
+		if _, ok := config.GetKeyForEnvVar(upperName); !ok {
+			result = append(result, name)
+		}
+	}
+
+	slices.Sort(result)
+	return result
+}
+
+// EnvVarMappingsToMarkdown renders the mappings as a markdown table, e.g. to generate documentation.
+func EnvVarMappingsToMarkdown(mappings []EnvVarMapping) string {
+	var sb strings.Builder
+	sb.WriteString("| Environment Variable | Configuration Key | Description |\n")
+	sb.WriteString("|---|---|---|\n")
+	for _, mapping := range mappings {
+		description := strings.ReplaceAll(mapping.Description, "|", "\\|")
+		sb.WriteString(fmt.Sprintf("| `%s` | `%s` | %s |\n", mapping.EnvVar, mapping.Key, description))
+	}
+	return sb.String()
+}
+
+// EnvVarMappingsToJson renders the mappings as a JSON array.
+func EnvVarMappingsToJson(mappings []EnvVarMapping) ([]byte, error) {
+	return json.MarshalIndent(mappings, "", "  ")
+}
@@ -0,0 +1,162 @@
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// LockedValueError is returned when a locked configuration value has been overridden with a value that is not allowed.
+type LockedValueError struct {
+	Key           string
+	Value         interface{}
+	RejectedValue interface{}
+}
+
+func (e *LockedValueError) Error() string {
+	return fmt.Sprintf("the configuration value \"%s\" is enforced to be \"%v\" by a policy, \"%v\" is not allowed", e.Key, e.Value, e.RejectedValue)
+}
+
+// lockedValue is the value enforced for a key and the values a user is still allowed to set.
+type lockedValue struct {
+	value         interface{}
+	allowedValues []interface{}
+}
+
+func (l lockedValue) allows(value interface{}) bool {
+	if sameValue(l.value, value) {
+		return true
+	}
+	return slices.ContainsFunc(l.allowedValues, func(allowed interface{}) bool {
+		return sameValue(allowed, value)
+	})
+}
+
+// sameValue compares a value set by the user to an enforced value of a policy. Values read from env vars or flags
+// are strings, so they are parsed as the type of the enforced value. Numbers are compared by value, since policies
+// are decoded from JSON.
+func sameValue(enforced interface{}, actual interface{}) bool {
+	switch expected := enforced.(type) {
+	case bool:
+		switch v := actual.(type) {
+		case bool:
+			return v == expected
+		case string:
+			parsed, err := strconv.ParseBool(v)
+			return err == nil && parsed == expected
+		}
+		return false
+	case string:
+		v, ok := actual.(string)
+		return ok && v == expected
+	case int, int32, int64, float32, float64:
+		expectedNumber, _ := toNumber(expected)
+		actualNumber, ok := toNumber(actual)
+		return ok && actualNumber == expectedNumber
+	}
+	return reflect.DeepEqual(enforced, actual)
+}
+
+// toNumber converts numbers and numeric strings to float64.
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		parsed, err := strconv.ParseFloat(v, 64)
+		return parsed, err == nil
+	}
+	return 0, false
+}
+
+// LockValue enforces the value of the given key, e.g. as defined by an organization policy. Values set by the user
+// are only used if they are one of the allowed values, otherwise GetWithError returns the locked value together with
+// a LockedValueError. Default value functions are not invoked for locked keys.
+func (ev *extendedViper) LockValue(key string, value interface{}, allowedValues ...interface{}) {
+	ev.mutex.Lock()
+	defer ev.mutex.Unlock()
+
+	ev.lockedValues[key] = lockedValue{
+		value:         value,
+		allowedValues: allowedValues,
+	}
+}
+
+// IsLocked returns true if the value of the given key is enforced, see LockValue.
+func (ev *extendedViper) IsLocked(key string) bool {
+	ev.mutex.RLock()
+	defer ev.mutex.RUnlock()
+
+	_, locked := ev.lockedValues[key]
+	return locked
+}
+
+// GetLockedKeys returns all keys with an enforced value in alphabetical order.
+func (ev *extendedViper) GetLockedKeys() []string {
+	ev.mutex.RLock()
+	defer ev.mutex.RUnlock()
+
+	keys := make([]string, 0, len(ev.lockedValues))
+	for key := range ev.lockedValues {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// getLocked returns the effective value of a locked key, it must be called while holding the lock.
+func (ev *extendedViper) getLocked(key string, lock lockedValue) (interface{}, error) {
+	value, err := ev.get(key)
+	if !ev.isSet(key) {
+		return lock.value, err
+	}
+	return lock.enforce(key, value, err)
+}
+
+// enforce returns the value if it is allowed, otherwise the locked value and a LockedValueError.
+func (l lockedValue) enforce(key string, value interface{}, err error) (interface{}, error) {
+	if value == nil {
+		return l.value, err
+	}
+	if !l.allows(value) {
+		return l.value, errors.Join(err, &LockedValueError{Key: key, Value: l.value, RejectedValue: value})
+	}
+	return value, err
+}
+
+// enforceLock returns the enforced value of the locked key if the given value is not allowed, e.g. a value set locally
+// in a scope. Values of keys that are not locked are returned unchanged.
+func (ev *extendedViper) enforceLock(key string, value interface{}, err error) (interface{}, error) {
+	ev.mutex.RLock()
+	lock, locked := ev.lockedValues[key]
+	ev.mutex.RUnlock()
+
+	if !locked {
+		return value, err
+	}
+	return lock.enforce(key, value, err)
+}
+
+// ValidateLockedValues returns an error for every locked key that has been overridden with a value that is not allowed.
+// For scoped configurations, local values like the flags of a workflow are validated as well, see Scope.
+func ValidateLockedValues(config Configuration) error {
+	var err error
+	for _, key := range config.GetLockedKeys() {
+		_, getErr := config.GetWithError(key)
+		var lockedErr *LockedValueError
+		if errors.As(getErr, &lockedErr) {
+			err = errors.Join(err, lockedErr)
+		}
+	}
+	return err
+}
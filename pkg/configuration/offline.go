@@ -0,0 +1,128 @@
+package configuration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/snyk/go-application-framework/internal/utils"
+)
+
+// offlineValuesFile is the file below CACHE_PATH that stores the last known results of default values registered
+// with WithOfflineFallback.
+const offlineValuesFile = "offline_default_values.json"
+
+// offlineCredentialKeys identify the account whose values are persisted, the last one is auth.CONFIG_KEY_OAUTH_TOKEN
+// which can't be imported here.
+var offlineCredentialKeys = []string{AUTHENTICATION_TOKEN, AUTHENTICATION_BEARER_TOKEN, "INTERNAL_OAUTH_TOKEN_STORAGE"}
+
+// offlineValuesMutex serializes access to the offline values file of all configurations of the process.
+var offlineValuesMutex sync.Mutex
+
+// WithOfflineFallback persists the results of the DefaultValueFunction below CACHE_PATH. While OFFLINE is enabled, the
+// last persisted result for the same inputs and credentials is returned instead of invoking the function, which would
+// fail without network access. Only non-empty string and bool results are persisted, since other types don't survive
+// the JSON round trip unchanged.
+func WithOfflineFallback() DefaultValueOption {
+	return func(entry *defaultValueEntry) {
+		entry.offlineFallback = true
+	}
+}
+
+// withOfflineFallback wraps determine to persist its results or, while OFFLINE is enabled, to return the persisted
+// result. The fingerprint identifies the inputs of the default value, see defaultValueFingerprint.
+func (ev *extendedViper) withOfflineFallback(key string, fingerprint string, determine func() (interface{}, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		cachePath := ev.GetString(CACHE_PATH)
+		if len(cachePath) == 0 {
+			return determine()
+		}
+
+		path := filepath.Join(cachePath, offlineValuesFile)
+		storedKey := key + "|" + fingerprint + "|" + ev.credentialFingerprint()
+		if ev.GetBool(OFFLINE) {
+			if value, found := loadOfflineValues(path)[storedKey]; found && isOfflineValue(value) {
+				return value, nil
+			}
+			return determine()
+		}
+
+		value, err := determine()
+		if err == nil && isOfflineValue(value) {
+			storeOfflineValue(path, storedKey, value)
+		}
+		return value, err
+	}
+}
+
+// credentialFingerprint hashes the credentials, so that values of one account are never served to another one, even if
+// the default value doesn't declare a dependency on them.
+func (ev *extendedViper) credentialFingerprint() string {
+	credentials := []interface{}{}
+	for _, key := range offlineCredentialKeys {
+		credentials = append(credentials, ev.Get(key))
+	}
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%#v", credentials)))
+	return hex.EncodeToString(hash[:])
+}
+
+// isOfflineValue returns true for the types that are persisted, see WithOfflineFallback.
+func isOfflineValue(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return len(v) > 0
+	case bool:
+		return true
+	default:
+		return false
+	}
+}
+
+func loadOfflineValues(path string) map[string]interface{} {
+	offlineValuesMutex.Lock()
+	defer offlineValuesMutex.Unlock()
+	return readOfflineValues(path)
+}
+
+func readOfflineValues(path string) map[string]interface{} {
+	values := map[string]interface{}{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return values
+	}
+
+	// a corrupt file is replaced by the next stored value
+	_ = json.Unmarshal(data, &values) //nolint:errcheck // see above
+	return values
+}
+
+// storeOfflineValue persists the value on a best effort basis, failing to do so only affects the offline mode.
+func storeOfflineValue(path string, key string, value interface{}) {
+	offlineValuesMutex.Lock()
+	defer offlineValuesMutex.Unlock()
+
+	values := readOfflineValues(path)
+	existing, err := json.Marshal(values[key])
+	if err != nil {
+		return
+	}
+	updated, err := json.Marshal(value)
+	if err != nil || string(existing) == string(updated) {
+		return
+	}
+
+	values[key] = value
+	data, err := json.Marshal(values)
+	if err != nil {
+		return
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), utils.FILEPERM_755); err != nil {
+		return
+	}
+	_ = os.WriteFile(path, data, utils.FILEPERM_600) //nolint:errcheck // best effort, see above
+}
@@ -0,0 +1,147 @@
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// projectConfigFileNames are the project configuration files that are looked up in every directory, in order of
+// precedence. Paths are relative to the directory being searched.
+var projectConfigFileNames = []string{
+	filepath.Join(".snyk", "config.yaml"),
+	filepath.Join(".snyk", "config.yml"),
+	filepath.Join(".snyk", "config.toml"),
+}
+
+// projectConfigKeys are the keys that can be set by a project configuration file. The file might originate from an
+// untrusted repository, so keys that affect where requests and credentials go, e.g. the API URL, proxy or TLS
+// settings, are not supported. Nested keys are allowed by their top level key, e.g. output.json.
+var projectConfigKeys = []string{
+	ORGANIZATION,
+	FLAG_SEVERITY_THRESHOLD,
+	"exclude",
+	"output",
+	"json",
+	"json-file-output",
+	"sarif",
+	"sarif-file-output",
+}
+
+// ErrUnsupportedProjectConfigKeys is returned by SetProjectConfigFile if the file contains keys that are not supported
+// in project configurations. These keys are ignored, the remaining values are used nevertheless.
+var ErrUnsupportedProjectConfigKeys = errors.New("unsupported keys in the project configuration are ignored")
+
+// FindProjectConfigFile walks up the directory tree, starting at startDirectory, and returns the path of the first
+// project configuration file found (e.g. .snyk/config.yaml). An empty string is returned if no file was found.
+func FindProjectConfigFile(startDirectory string) string {
+	if len(startDirectory) == 0 {
+		return ""
+	}
+
+	dir, err := filepath.Abs(startDirectory)
+	if err != nil {
+		return ""
+	}
+
+	// if the start is a file, begin the search in its parent folder
+	if info, statErr := os.Stat(dir); statErr == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+
+	for {
+		for _, name := range projectConfigFileNames {
+			candidate := filepath.Join(dir, name)
+			if info, statErr := os.Stat(candidate); statErr == nil && !info.IsDir() {
+				return candidate
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// readProjectConfigFile reads a YAML or TOML file, the format is determined by the file extension. Only the values of
+// projectConfigKeys are returned, the other keys are returned as unsupported.
+func readProjectConfigFile(file string) (*viper.Viper, []string, error) {
+	v := viper.New()
+	v.SetConfigFile(file)
+	err := v.ReadInConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	supported := map[string]interface{}{}
+	unsupported := []string{}
+	for key, value := range v.AllSettings() {
+		if slices.Contains(projectConfigKeys, key) {
+			supported[key] = value
+		} else {
+			unsupported = append(unsupported, key)
+		}
+	}
+	slices.Sort(unsupported)
+
+	filtered := viper.New()
+	if err = filtered.MergeConfigMap(supported); err != nil {
+		return nil, nil, err
+	}
+	return filtered, unsupported, nil
+}
+
+// SetProjectConfigFile reads the given YAML or TOML file and adds its values to the configuration.
+// Values from the project configuration take precedence over the global configuration file and defaults, but
+// environment variables, flags and explicitly set values take precedence over the project configuration.
+// Keys that are not supported in project configurations are ignored and reported by ErrUnsupportedProjectConfigKeys.
+func (ev *extendedViper) SetProjectConfigFile(file string) error {
+	projectConfig, unsupported, err := readProjectConfigFile(file)
+	if err != nil {
+		return err
+	}
+
+	ev.mutex.Lock()
+	defer ev.mutex.Unlock()
+	ev.projectConfigFile = file
+	ev.projectConfig = projectConfig
+	if err = ev.mergeProjectConfig(); err != nil {
+		return err
+	}
+
+	if len(unsupported) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnsupportedProjectConfigKeys, strings.Join(unsupported, ", "))
+	}
+	return nil
+}
+
+func (ev *extendedViper) GetProjectConfigFile() string {
+	ev.mutex.RLock()
+	defer ev.mutex.RUnlock()
+	return ev.projectConfigFile
+}
+
+// mergeProjectConfig merges the project configuration into viper's config layer, so that it overrides values from
+// the global configuration file while keeping viper's precedence of env vars and flags.
+func (ev *extendedViper) mergeProjectConfig() error {
+	if ev.projectConfig == nil {
+		return nil
+	}
+	return ev.viper.MergeConfigMap(ev.projectConfig.AllSettings())
+}
+
+// isProjectConfigValue returns true if the given value for the key originates from the project configuration.
+func (ev *extendedViper) isProjectConfigValue(key string, value interface{}) bool {
+	if ev.projectConfig == nil {
+		return false
+	}
+	return ev.projectConfig.IsSet(key) && reflect.DeepEqual(ev.projectConfig.Get(key), value)
+}
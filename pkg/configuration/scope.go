@@ -0,0 +1,390 @@
+package configuration
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+const scopeKeyPrefix = "scope:"
+
+// ScopedKey returns the key under which a scoped configuration stores the given key. Dots are replaced, since viper
+// interprets them as nesting.
+func ScopedKey(namespace string, key string) string {
+	return strings.ReplaceAll(scopeKeyPrefix+namespace+":"+key, ".", ":")
+}
+
+// scopedConfiguration is a view on a configuration that keeps keys local to a namespace, e.g. a workflow.
+// Local values take precedence, otherwise the global value is used:
+//  1. explicitly set local value, e.g. via Set or a changed flag
+//  2. explicitly set local value of the parent scope, e.g. of the workflow invoking this one, see ScopeFrom
+//  3. explicitly set global value
+//  4. local default value, e.g. registered via AddDefaultValue or a flag default
+//  5. global value including its default
+//
+// Methods dealing with individual keys map them to local keys. Methods concerning the configuration as a whole, e.g.
+// AllKeys, locks, storage, env vars and files, are forwarded to the global configuration.
+type scopedConfiguration struct {
+	global    *extendedViper
+	namespace string
+	// parent is the optional scope whose explicitly set local values are visible in this scope, see ScopeFrom.
+	parent *scopedConfiguration
+}
+
+var _ Configuration = (*scopedConfiguration)(nil)
+
+// Scope returns a view on the configuration in which keys are local to the given namespace, e.g. "code.test".
+// Local keys fall back to global ones, so that workflows can define the same flag with different defaults.
+func (ev *extendedViper) Scope(namespace string) Configuration {
+	return &scopedConfiguration{
+		global:    ev,
+		namespace: namespace,
+	}
+}
+
+// ScopeFrom returns the scope of the namespace for the given configuration. If the configuration is the scope of
+// another namespace, e.g. of a workflow invoking another workflow, its explicitly set local values, e.g. changed
+// flags, remain visible in the new scope unless they are set locally as well.
+func ScopeFrom(config Configuration, namespace string) Configuration {
+	scoped, ok := config.(*scopedConfiguration)
+	if !ok {
+		return config.Scope(namespace)
+	}
+	if scoped.namespace == namespace {
+		return scoped
+	}
+	return &scopedConfiguration{
+		global:    scoped.global,
+		namespace: namespace,
+		parent:    scoped,
+	}
+}
+
+// Unscoped returns the global configuration of a view created via Scope, other configurations are returned unchanged.
+func Unscoped(config Configuration) Configuration {
+	if scoped, ok := config.(*scopedConfiguration); ok {
+		return scoped.global
+	}
+	return config
+}
+
+// addScopedFlagSet binds all flags of the given flag set to keys local to the namespace.
+func (ev *extendedViper) addScopedFlagSet(namespace string, flagset *pflag.FlagSet) error {
+	ev.mutex.Lock()
+	defer ev.mutex.Unlock()
+
+	ev.scopedFlagsets[namespace] = append(ev.scopedFlagsets[namespace], flagset)
+
+	var err error
+	flagset.VisitAll(func(flag *pflag.Flag) {
+		if err == nil {
+			err = ev.viper.BindPFlag(ScopedKey(namespace, flag.Name), flag)
+		}
+	})
+	return err
+}
+
+// hasLocalValue returns true if there is a default value or a flag for the scoped key.
+func (ev *extendedViper) hasLocalValue(scopedKey string) bool {
+	ev.mutex.RLock()
+	defer ev.mutex.RUnlock()
+
+	_, hasDefault := ev.defaultValues[scopedKey]
+	return hasDefault || slices.Contains(ev.viper.AllKeys(), scopedKey)
+}
+
+func (s *scopedConfiguration) key(key string) string {
+	return ScopedKey(s.namespace, key)
+}
+
+// isLocal returns true if the key is local to the scope, i.e. if there is a local flag or default value for it.
+func (s *scopedConfiguration) isLocal(key string) bool {
+	return s.global.hasLocalValue(s.key(key))
+}
+
+// effectiveKey returns the local key if the key is local to the scope, otherwise the global key.
+func (s *scopedConfiguration) effectiveKey(key string) string {
+	if s.isLocal(key) {
+		return s.key(key)
+	}
+	return key
+}
+
+func (s *scopedConfiguration) Clone() Configuration {
+	global, ok := s.global.Clone().(*extendedViper)
+	if !ok {
+		return s.global.Clone().Scope(s.namespace)
+	}
+	return s.withGlobal(global)
+}
+
+// withGlobal returns a copy of the scope and its parents for the given global configuration.
+func (s *scopedConfiguration) withGlobal(global *extendedViper) *scopedConfiguration {
+	clone := &scopedConfiguration{
+		global:    global,
+		namespace: s.namespace,
+	}
+	if s.parent != nil {
+		clone.parent = s.parent.withGlobal(global)
+	}
+	return clone
+}
+
+// explicitKey returns the local key of the scope or its parents that has been explicitly set, e.g. via a changed flag.
+func (s *scopedConfiguration) explicitKey(key string) (string, bool) {
+	for scope := s; scope != nil; scope = scope.parent {
+		if scopedKey := scope.key(key); s.global.IsSet(scopedKey) {
+			return scopedKey, true
+		}
+	}
+	return "", false
+}
+
+// Scope returns a nested scope, e.g. "code" and "test" result in "code.test".
+func (s *scopedConfiguration) Scope(namespace string) Configuration {
+	return s.global.Scope(s.namespace + "." + namespace)
+}
+
+// Set sets the local value of keys that are local to the scope, e.g. flags of a workflow, and the global value of all
+// other keys. This way values set by a workflow, e.g. before invoking another workflow, are visible in other scopes.
+func (s *scopedConfiguration) Set(key string, value interface{}) {
+	if s.isLocal(key) {
+		s.global.Set(s.key(key), value)
+		return
+	}
+	s.global.Set(key, value)
+}
+
+// Unset removes the local value of local keys, so that the global value is used again, and the global value of all
+// other keys. Local values are never persisted.
+func (s *scopedConfiguration) Unset(key string) {
+	if s.isLocal(key) {
+		s.global.Set(s.key(key), nil)
+		return
+	}
+	s.global.Unset(key)
+}
+
+func (s *scopedConfiguration) IsSet(key string) bool {
+	_, isSetLocally := s.explicitKey(key)
+	return isSetLocally || s.global.IsSet(key)
+}
+
+func (s *scopedConfiguration) Get(key string) interface{} {
+	//nolint:errcheck // discarded error for callers who don't care
+	value, _ := s.GetWithError(key)
+	return value
+}
+
+func (s *scopedConfiguration) GetWithError(key string) (interface{}, error) {
+	// locked values are enforced for local values as well, e.g. for the flags of a workflow
+	if s.global.IsLocked(key) {
+		if explicitKey, isSetLocally := s.explicitKey(key); isSetLocally {
+			value, err := s.global.GetWithError(explicitKey)
+			return s.global.enforceLock(key, value, err)
+		}
+		return s.global.GetWithError(key)
+	}
+
+	if explicitKey, isSetLocally := s.explicitKey(key); isSetLocally {
+		return s.global.GetWithError(explicitKey)
+	}
+
+	scopedKey := s.key(key)
+	if !s.global.IsSet(key) && s.global.hasLocalValue(scopedKey) {
+		return s.global.GetWithError(scopedKey)
+	}
+
+	return s.global.GetWithError(key)
+}
+
+func (s *scopedConfiguration) GetString(key string) string {
+	return toString(s.Get(key))
+}
+
+func (s *scopedConfiguration) GetStringSlice(key string) []string {
+	return toStringSlice(s.Get(key))
+}
+
+func (s *scopedConfiguration) GetBool(key string) bool {
+	return toBool(s.Get(key))
+}
+
+func (s *scopedConfiguration) GetInt(key string) int {
+	return toInt(s.Get(key))
+}
+
+func (s *scopedConfiguration) GetFloat64(key string) float64 {
+	return toFloat64(s.Get(key))
+}
+
+func (s *scopedConfiguration) GetUrl(key string) *url.URL {
+	return toUrl(s.GetString(key))
+}
+
+func (s *scopedConfiguration) AddFlagSet(flagset *pflag.FlagSet) error {
+	return s.global.addScopedFlagSet(s.namespace, flagset)
+}
+
+// AllKeys returns the keys of the global configuration, local keys are included in their scoped form, see ScopedKey.
+func (s *scopedConfiguration) AllKeys() []string {
+	return s.global.AllKeys()
+}
+
+func (s *scopedConfiguration) AddDefaultValue(key string, defaultValue DefaultValueFunction, opts ...DefaultValueOption) {
+	s.global.AddDefaultValue(s.key(key), defaultValue, opts...)
+}
+
+func (s *scopedConfiguration) PrefetchDefaultValues() {
+	s.global.PrefetchDefaultValues()
+}
+
+// AddDependencies declares the dependencies of the local key, the dependencies themselves are global keys.
+func (s *scopedConfiguration) AddDependencies(key string, dependencies ...string) error {
+	return s.global.AddDependencies(s.key(key), dependencies...)
+}
+
+func (s *scopedConfiguration) GetDependencies(key string) []string {
+	return s.global.GetDependencies(s.effectiveKey(key))
+}
+
+func (s *scopedConfiguration) DependencyGraph() string {
+	return s.global.DependencyGraph()
+}
+
+// AddAlternativeKeys registers alternatives for the local key, the alternatives themselves are global keys, e.g.
+// env vars.
+func (s *scopedConfiguration) AddAlternativeKeys(key string, altKeys []string) {
+	s.global.AddAlternativeKeys(s.key(key), altKeys)
+}
+
+func (s *scopedConfiguration) GetAlternativeKeys(key string) []string {
+	return append(s.global.GetAlternativeKeys(s.key(key)), s.global.GetAlternativeKeys(key)...)
+}
+
+func (s *scopedConfiguration) GetAllKeysThatContainValues(key string) []string {
+	return append(s.global.GetAllKeysThatContainValues(s.key(key)), s.global.GetAllKeysThatContainValues(key)...)
+}
+
+func (s *scopedConfiguration) GetKeyType(key string) KeyType {
+	return s.global.GetKeyType(key)
+}
+
+// LockValue locks the global key, locks are enforced in all scopes.
+func (s *scopedConfiguration) LockValue(key string, value interface{}, allowedValues ...interface{}) {
+	s.global.LockValue(key, value, allowedValues...)
+}
+
+func (s *scopedConfiguration) IsLocked(key string) bool {
+	return s.global.IsLocked(key)
+}
+
+func (s *scopedConfiguration) GetLockedKeys() []string {
+	return s.global.GetLockedKeys()
+}
+
+// PersistInStorage marks the global key to be persisted, local values are never persisted.
+func (s *scopedConfiguration) PersistInStorage(key string) {
+	s.global.PersistInStorage(key)
+}
+
+func (s *scopedConfiguration) SetStorage(storage Storage) {
+	s.global.SetStorage(storage)
+}
+
+func (s *scopedConfiguration) GetStorage() Storage {
+	return s.global.GetStorage()
+}
+
+// BeginTransaction starts a transaction that stages changes like Set and Unset of the scope.
+func (s *scopedConfiguration) BeginTransaction() Transaction {
+	return &scopedTransaction{
+		global: s.global.BeginTransaction(),
+		scope:  s,
+	}
+}
+
+func (s *scopedConfiguration) AutomaticEnv() {
+	s.global.AutomaticEnv()
+}
+
+func (s *scopedConfiguration) GetAutomaticEnv() bool {
+	return s.global.GetAutomaticEnv()
+}
+
+func (s *scopedConfiguration) SetSupportedEnvVars(envVars ...string) {
+	s.global.SetSupportedEnvVars(envVars...)
+}
+
+func (s *scopedConfiguration) GetSupportedEnvVars() []string {
+	return s.global.GetSupportedEnvVars()
+}
+
+func (s *scopedConfiguration) SetSupportedEnvVarPrefixes(prefixes ...string) {
+	s.global.SetSupportedEnvVarPrefixes(prefixes...)
+}
+
+func (s *scopedConfiguration) GetSupportedEnvVarPrefixes() []string {
+	return s.global.GetSupportedEnvVarPrefixes()
+}
+
+// AddEnvVarMapping registers the env var as source for the local key.
+func (s *scopedConfiguration) AddEnvVarMapping(envVar string, key string, description string) {
+	s.global.AddEnvVarMapping(envVar, s.key(key), description)
+}
+
+func (s *scopedConfiguration) GetEnvVarMappings() []EnvVarMapping {
+	return s.global.GetEnvVarMappings()
+}
+
+func (s *scopedConfiguration) GetKeyForEnvVar(envVar string) (string, bool) {
+	return s.global.GetKeyForEnvVar(envVar)
+}
+
+func (s *scopedConfiguration) SetFiles(files ...string) {
+	s.global.SetFiles(files...)
+}
+
+func (s *scopedConfiguration) GetFiles() []string {
+	return s.global.GetFiles()
+}
+
+func (s *scopedConfiguration) ReloadConfig() error {
+	return s.global.ReloadConfig()
+}
+
+func (s *scopedConfiguration) SetProjectConfigFile(file string) error {
+	return s.global.SetProjectConfigFile(file)
+}
+
+func (s *scopedConfiguration) GetProjectConfigFile() string {
+	return s.global.GetProjectConfigFile()
+}
+
+// scopedTransaction stages changes like Set and Unset of a scope.
+type scopedTransaction struct {
+	global Transaction
+	scope  *scopedConfiguration
+}
+
+func (t *scopedTransaction) Set(key string, value interface{}) {
+	t.global.Set(t.scope.effectiveKey(key), value)
+}
+
+func (t *scopedTransaction) Unset(key string) {
+	if t.scope.isLocal(key) {
+		t.global.Set(t.scope.key(key), nil)
+		return
+	}
+	t.global.Unset(key)
+}
+
+func (t *scopedTransaction) Commit() error {
+	return t.global.Commit()
+}
+
+func (t *scopedTransaction) Rollback() {
+	t.global.Rollback()
+}
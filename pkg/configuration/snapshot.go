@@ -0,0 +1,117 @@
+package configuration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/snyk/go-application-framework/internal/utils"
+)
+
+// SnapshotRedactFunction is invoked for every value of a Snapshot and returns the value that will be stored.
+// It can be used to remove secrets before a Snapshot is shared.
+type SnapshotRedactFunction func(key string, value interface{}) interface{}
+
+// Snapshot is a serializable copy of the effective values of a Configuration, including default values.
+type Snapshot struct {
+	CreatedAt time.Time              `json:"created_at"`
+	Values    map[string]interface{} `json:"values"`
+	// Errors contains the errors returned by default value functions, keyed by configuration key.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// ErrSnapshotRedactFunctionRequired is returned by CreateSnapshot if no redact function was given.
+var ErrSnapshotRedactFunctionRequired = errors.New("a redact function is required to create a snapshot")
+
+// CreateSnapshot determines the effective value of every key of the given configuration. Since default values are
+// evaluated, this might cause network requests. The redact function is required to remove secrets, see
+// logging.CreateSnapshot, which scrubs them with the scrub dictionary of the configuration.
+func CreateSnapshot(config Configuration, redact SnapshotRedactFunction) (*Snapshot, error) {
+	if redact == nil {
+		return nil, ErrSnapshotRedactFunctionRequired
+	}
+	return createSnapshot(config, redact), nil
+}
+
+// CreateUnredactedSnapshot is like CreateSnapshot, but keeps secrets like tokens in plain text. The snapshot must not
+// be shared or written to files that might be shared, e.g. when reporting issues.
+func CreateUnredactedSnapshot(config Configuration) *Snapshot {
+	return createSnapshot(config, nil)
+}
+
+func createSnapshot(config Configuration, redact SnapshotRedactFunction) *Snapshot {
+	snapshot := &Snapshot{
+		CreatedAt: time.Now().UTC(),
+		Values:    map[string]interface{}{},
+		Errors:    map[string]string{},
+	}
+
+	// work on a clone to get a consistent view while default values are being evaluated
+	clone := config.Clone()
+	keys := clone.AllKeys()
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	for _, key := range keys {
+		value, err := clone.GetWithError(key)
+		if err != nil {
+			snapshot.Errors[key] = err.Error()
+		}
+
+		if redact != nil {
+			value = redact(key, value)
+		}
+
+		// ensure that the snapshot can be serialized
+		if _, marshalErr := json.Marshal(value); marshalErr != nil {
+			value = fmt.Sprintf("%v", value)
+		}
+
+		snapshot.Values[key] = value
+	}
+
+	return snapshot
+}
+
+// Save writes the snapshot as JSON to the given file.
+func (s *Snapshot) Save(file string) error {
+	bytes, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, bytes, utils.FILEPERM_600)
+}
+
+// LoadSnapshot reads a snapshot from the given JSON file.
+func LoadSnapshot(file string) (*Snapshot, error) {
+	bytes, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{}
+	err = json.Unmarshal(bytes, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// NewFromSnapshot creates a new Configuration with all values of the given snapshot explicitly set.
+// Since default values are part of the snapshot, no default value functions are registered.
+func NewFromSnapshot(snapshot *Snapshot, opts ...Opts) Configuration {
+	config := NewWithOpts(opts...)
+	if snapshot == nil {
+		return config
+	}
+
+	for key, value := range snapshot.Values {
+		if value != nil {
+			config.Set(key, value)
+		}
+	}
+	return config
+}
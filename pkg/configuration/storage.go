@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/gofrs/flock"
+	"github.com/google/uuid"
 
 	"github.com/snyk/go-application-framework/internal/utils"
 )
@@ -22,12 +23,26 @@ type Storage interface {
 	Unlock() error
 }
 
+// TransactionalStorage is a Storage that can store several values at once, see Transaction. Changes of transactions
+// are stored value by value in storages that don't implement it.
+type TransactionalStorage interface {
+	Storage
+	// SetMany stores all given values at once, either all or none of them are stored.
+	SetMany(values map[string]any) error
+}
+
+var _ TransactionalStorage = (*JsonStorage)(nil)
+
 type EmptyStorage struct{}
 
 func (*EmptyStorage) Set(string, any) error {
 	return nil
 }
 
+func (*EmptyStorage) SetMany(map[string]any) error {
+	return nil
+}
+
 func (*EmptyStorage) Refresh(Configuration, string) error {
 	return nil
 }
@@ -40,6 +55,12 @@ func (*EmptyStorage) Unlock() error {
 	return nil
 }
 
+// lockRetryDelay is the delay between attempts to acquire the file lock.
+const lockRetryDelay = 100 * time.Millisecond
+
+// storageLockTimeout is the maximum time SetMany waits for other processes to release the storage.
+const storageLockTimeout = 10 * time.Second
+
 // keyDeleted is a marker value which, when set, causes a key to be deleted from
 // stored configuration.
 var keyDeleted = struct{}{}
@@ -95,6 +116,40 @@ func (s *JsonStorage) getNonEnvVarKey(key string) string {
 func (s *JsonStorage) Set(key string, value any) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
+
+	return s.update(map[string]any{key: value})
+}
+
+// SetMany stores all given values with a single write, while holding the file lock. If the lock is already held,
+// e.g. via Lock, it is not acquired again.
+func (s *JsonStorage) SetMany(values map[string]any) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.fileLock.Locked() {
+		ctx, cancel := context.WithTimeout(context.Background(), storageLockTimeout)
+		defer cancel()
+
+		// Check if path to file exists, since the lock file is located next to it
+		err := os.MkdirAll(filepath.Dir(s.path), utils.FILEPERM_755)
+		if err != nil {
+			return err
+		}
+
+		if _, err = s.fileLock.TryLockContext(ctx, lockRetryDelay); err != nil {
+			return err
+		}
+		defer func() {
+			_ = s.fileLock.Unlock() //nolint:errcheck // the values have been written already
+		}()
+	}
+
+	return s.update(values)
+}
+
+// update reads the file, applies the given values and writes it atomically by renaming a temporary file.
+// The caller must hold the mutex.
+func (s *JsonStorage) update(values map[string]any) error {
 	// Check if path to file exists
 	err := os.MkdirAll(filepath.Dir(s.path), utils.FILEPERM_755)
 	if err != nil {
@@ -113,23 +168,51 @@ func (s *JsonStorage) Set(key string, value any) error {
 		return err
 	}
 
-	if tmpKey := s.getNonEnvVarKey(key); len(tmpKey) > 0 {
-		key = tmpKey
-	}
+	for key, value := range values {
+		if tmpKey := s.getNonEnvVarKey(key); len(tmpKey) > 0 {
+			key = tmpKey
+		}
 
-	if _, ok := value.(struct{}); ok {
-		// See implementation of Configuration.Unset; when marker value is set,
-		// key is deleted from config before writing.
-		delete(config, key)
-	} else {
-		config[key] = value
+		if _, ok := value.(struct{}); ok {
+			// See implementation of Configuration.Unset; when marker value is set,
+			// key is deleted from config before writing.
+			delete(config, key)
+		} else {
+			config[key] = value
+		}
 	}
+
 	configJson, err := json.Marshal(config)
 	if err != nil {
 		return err
 	}
-	err = os.WriteFile(s.path, configJson, utils.FILEPERM_666)
 
+	return writeFileAtomically(s.path, configJson)
+}
+
+// writeFileAtomically writes the data to a temporary file in the same directory and renames it afterward, so that
+// readers never see a partially written file. The permissions of an existing file are kept, new files are created
+// like os.WriteFile would, i.e. FILEPERM_666 restricted by the umask.
+func writeFileAtomically(path string, data []byte) error {
+	tmpPath := path + "." + uuid.NewString() + ".tmp"
+	tmpFile, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, utils.FILEPERM_666)
+	if err != nil {
+		return err
+	}
+
+	_, err = tmpFile.Write(data)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if info, statErr := os.Stat(path); err == nil && statErr == nil {
+		err = os.Chmod(tmpPath, info.Mode().Perm())
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath) //nolint:errcheck // best effort cleanup
+	}
 	return err
 }
 
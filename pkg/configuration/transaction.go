@@ -0,0 +1,113 @@
+package configuration
+
+import (
+	"slices"
+)
+
+// Transaction batches changes to a configuration, so that they are applied at once and persisted with a single
+// storage write, see TransactionalStorage.
+type Transaction interface {
+	// Set stages a value, it is persisted on commit if PersistInStorage was called for the key.
+	Set(key string, value interface{})
+	// Unset stages the removal of a key and its alternative keys, the removal is always persisted.
+	Unset(key string)
+	// Commit applies all staged changes. If one of them sets a locked key to a value that is not allowed, or if they
+	// could not be persisted, an error is returned and none of them is applied. Storages that are not a
+	// TransactionalStorage might have persisted some of the changes.
+	Commit() error
+	// Rollback discards all staged changes.
+	Rollback()
+}
+
+type transactionChange struct {
+	key     string
+	value   interface{}
+	persist bool
+}
+
+type transaction struct {
+	config  *extendedViper
+	changes []transactionChange
+}
+
+// BeginTransaction starts a new Transaction, changes are not visible until they are committed.
+func (ev *extendedViper) BeginTransaction() Transaction {
+	return &transaction{config: ev}
+}
+
+func (t *transaction) Set(key string, value interface{}) {
+	t.changes = append(t.changes, transactionChange{key: key, value: value})
+}
+
+func (t *transaction) Unset(key string) {
+	t.changes = append(t.changes, transactionChange{key: key, value: keyDeleted, persist: true})
+	for _, otherKey := range t.config.GetAlternativeKeys(key) {
+		t.changes = append(t.changes, transactionChange{key: otherKey, value: keyDeleted, persist: true})
+	}
+}
+
+func (t *transaction) Commit() error {
+	ev := t.config
+	changes := t.changes
+	t.changes = nil
+
+	// the changes are persisted before they are applied, so that a failed write doesn't leave the configuration
+	// different from the storage
+	ev.mutex.RLock()
+	localStorage := ev.storage
+	persistedKeys := map[string]bool{}
+	persisted := map[string]any{}
+	for _, change := range changes {
+		if lock, locked := ev.lockedValues[change.key]; locked && change.value != keyDeleted && !lock.allows(change.value) {
+			ev.mutex.RUnlock()
+			return &LockedValueError{Key: change.key, Value: lock.value, RejectedValue: change.value}
+		}
+		if change.persist || ev.persistedKeys[change.key] {
+			persistedKeys[change.key] = true
+		}
+		if persistedKeys[change.key] {
+			persisted[change.key] = change.value
+		}
+	}
+	ev.mutex.RUnlock()
+
+	if localStorage != nil && len(persisted) > 0 {
+		if err := storeAll(localStorage, persisted); err != nil {
+			return err
+		}
+	}
+
+	ev.mutex.Lock()
+	defer ev.mutex.Unlock()
+	for _, change := range changes {
+		if change.persist {
+			ev.persistedKeys[change.key] = true
+		}
+		ev.viper.Set(change.key, change.value)
+	}
+	return nil
+}
+
+func (t *transaction) Rollback() {
+	t.changes = nil
+}
+
+// storeAll stores the values at once if the storage supports it, otherwise one by one in alphabetical order.
+func storeAll(storage Storage, values map[string]any) error {
+	if transactionalStorage, ok := storage.(TransactionalStorage); ok {
+		return transactionalStorage.SetMany(values)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		if err := storage.Set(key, values[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
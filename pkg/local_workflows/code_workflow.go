@@ -68,13 +68,20 @@ func getSastEnabled(engine workflow.Engine) configuration.DefaultValueFunction {
 func InitCodeWorkflow(engine workflow.Engine) error {
 	// register workflow with engine
 	flags := GetCodeFlagSet()
-	_, err := engine.Register(WORKFLOWID_CODE, workflow.ConfigurationOptionsFromFlagset(flags), codeWorkflowEntryPoint)
+	entry, err := engine.Register(WORKFLOWID_CODE, workflow.ConfigurationOptionsFromFlagset(flags), codeWorkflowEntryPoint)
 
 	if err != nil {
 		return err
 	}
-
-	engine.GetConfiguration().AddDefaultValue(ConfigurationSastEnabled, getSastEnabled(engine))
+	requireNetworkAccess(entry)
+
+	engine.GetConfiguration().AddDefaultValue(ConfigurationSastEnabled, getSastEnabled(engine),
+		configuration.WithMemoization(configuration.DefaultMemoizationTTL),
+		configuration.WithDependencies(configuration.API_URL, configuration.ORGANIZATION),
+		configuration.WithDependencies(config_utils.CredentialKeys...),
+		configuration.WithPrefetch(),
+		configuration.WithOfflineFallback(),
+	)
 	engine.GetConfiguration().AddDefaultValue(code_workflow.ConfigurationTestFLowName, configuration.StandardDefaultValueFunction("cli_test"))
 	config_utils.AddFeatureFlagToConfig(engine, configuration.FF_CODE_CONSISTENT_IGNORES, "snykCodeConsistentIgnores")
 
@@ -362,30 +362,60 @@ func Test_Code_FF_CODE_CONSISTENT_IGNORES(t *testing.T) {
 	}))
 	defer ts.Close()
 
-	orgId := "aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee"
-	config := configuration.NewInMemory()
-	config.Set(configuration.ORGANIZATION, orgId)
-	config.Set(configuration.API_URL, ts.URL)
-
-	engine := workflow.NewWorkFlowEngine(config)
-	err := InitCodeWorkflow(engine)
-	assert.NoError(t, err)
+	// feature flags are memoized, so every test case uses its own configuration
+	newConfig := func(t *testing.T) configuration.Configuration {
+		t.Helper()
+		orgId := "aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee"
+		config := configuration.NewInMemory()
+		config.Set(configuration.ORGANIZATION, orgId)
+		config.Set(configuration.API_URL, ts.URL)
+
+		engine := workflow.NewWorkFlowEngine(config)
+		err := InitCodeWorkflow(engine)
+		assert.NoError(t, err)
+		return config
+	}
 
 	t.Run("Feature Flag set", func(t *testing.T) {
+		config := newConfig(t)
 		response = contract.OrgFeatureFlagResponse{Code: http.StatusOK, Ok: true}
 		consistentIgnores := config.GetBool(configuration.FF_CODE_CONSISTENT_IGNORES)
 		assert.True(t, consistentIgnores)
 	})
 
 	t.Run("Feature Flag NOT set", func(t *testing.T) {
+		config := newConfig(t)
 		response = contract.OrgFeatureFlagResponse{Code: http.StatusForbidden}
 		consistentIgnores := config.GetBool(configuration.FF_CODE_CONSISTENT_IGNORES)
 		assert.False(t, consistentIgnores)
 	})
 
+	t.Run("Feature Flag follows the credentials", func(t *testing.T) {
+		config := newConfig(t)
+		config.Set(configuration.AUTHENTICATION_TOKEN, "first-account-token")
+		response = contract.OrgFeatureFlagResponse{Code: http.StatusOK, Ok: true}
+		assert.True(t, config.GetBool(configuration.FF_CODE_CONSISTENT_IGNORES))
+
+		config.Set(configuration.AUTHENTICATION_TOKEN, "second-account-token")
+		response = contract.OrgFeatureFlagResponse{Code: http.StatusForbidden}
+		assert.False(t, config.GetBool(configuration.FF_CODE_CONSISTENT_IGNORES))
+	})
+
 	t.Run("Feature Flag not available due to error", func(t *testing.T) {
+		config := newConfig(t)
 		config.Unset(configuration.ORGANIZATION)
 		consistentIgnores := config.GetBool(configuration.FF_CODE_CONSISTENT_IGNORES)
 		assert.False(t, consistentIgnores)
 	})
 }
+
+func Test_Code_RequiresNetworkAccess(t *testing.T) {
+	engine := workflow.NewWorkFlowEngine(configuration.NewWithOpts())
+	assert.NoError(t, InitCodeWorkflow(engine))
+
+	entry, ok := engine.GetWorkflow(WORKFLOWID_CODE)
+	assert.True(t, ok)
+	offlineEntry, ok := entry.(workflow.OfflineCapableEntry)
+	assert.True(t, ok)
+	assert.False(t, offlineEntry.IsOfflineCapable())
+}
@@ -2,10 +2,15 @@ package config_utils
 
 import (
 	"github.com/snyk/go-application-framework/internal/api"
+	"github.com/snyk/go-application-framework/pkg/auth"
 	"github.com/snyk/go-application-framework/pkg/configuration"
 	"github.com/snyk/go-application-framework/pkg/workflow"
 )
 
+// CredentialKeys are the configuration keys of the credentials. Default values that are determined for the
+// authenticated account depend on them, so that memoized values are not shared between accounts.
+var CredentialKeys = []string{configuration.AUTHENTICATION_TOKEN, configuration.AUTHENTICATION_BEARER_TOKEN, auth.CONFIG_KEY_OAUTH_TOKEN}
+
 func AddFeatureFlagToConfig(engine workflow.Engine, configKey string, featureFlagName string) {
 	config := engine.GetConfiguration()
 
@@ -26,5 +31,11 @@ func AddFeatureFlagToConfig(engine workflow.Engine, configKey string, featureFla
 		}
 	}
 
-	config.AddDefaultValue(configKey, callback)
+	config.AddDefaultValue(configKey, callback,
+		configuration.WithMemoization(configuration.DefaultMemoizationTTL),
+		configuration.WithDependencies(configuration.API_URL, configuration.ORGANIZATION),
+		configuration.WithDependencies(CredentialKeys...),
+		configuration.WithPrefetch(),
+		configuration.WithOfflineFallback(),
+	)
 }
@@ -0,0 +1,204 @@
+package config_utils
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/snyk/go-application-framework/internal/utils"
+	"github.com/snyk/go-application-framework/pkg/configuration"
+	"github.com/snyk/go-application-framework/pkg/workflow"
+)
+
+// The policy source is defined at build time, so that users can't replace it via configuration values or env vars,
+// e.g. -ldflags "-X github.com/snyk/go-application-framework/pkg/local_workflows/config_utils.policyUrl=https://...".
+var (
+	// policyUrl is the endpoint serving the signed organization policy, policies are disabled if empty.
+	policyUrl string
+	// policyPublicKey is the base64 encoded ed25519 public key used to verify the policy signature.
+	policyPublicKey string
+)
+
+// policyCacheDuration defines for how long a cached policy is used without fetching it again.
+const policyCacheDuration = time.Hour
+
+// policyFetchTimeout limits how long the initialization waits for the policy endpoint.
+var policyFetchTimeout = 10 * time.Second
+
+// PolicyRule enforces the value of a configuration key. Users may still set any of the allowed values,
+// e.g. a stricter severity threshold.
+type PolicyRule struct {
+	Key           string        `json:"key"`
+	Value         interface{}   `json:"value"`
+	AllowedValues []interface{} `json:"allowed_values,omitempty"`
+}
+
+// Policy is a set of rules defined by an organization admin.
+type Policy struct {
+	Rules []PolicyRule `json:"rules"`
+}
+
+// SignedPolicy is the document served by the policy endpoint. Payload is the base64 encoded JSON Policy and
+// Signature the base64 encoded ed25519 signature of the decoded payload.
+type SignedPolicy struct {
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+// Verify checks the signature of the policy and returns the decoded Policy.
+func (s *SignedPolicy) Verify(publicKey ed25519.PublicKey) (*Policy, error) {
+	payload, err := base64.StdEncoding.DecodeString(s.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode policy payload: %w", err)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(s.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode policy signature: %w", err)
+	}
+
+	if !ed25519.Verify(publicKey, payload, signature) {
+		return nil, fmt.Errorf("invalid policy signature")
+	}
+
+	policy := &Policy{}
+	err = json.Unmarshal(payload, policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+	return policy, nil
+}
+
+// ApplyPolicy locks all configuration values defined by the policy, see configuration.Configuration.LockValue.
+func ApplyPolicy(config configuration.Configuration, policy *Policy) {
+	for _, rule := range policy.Rules {
+		config.LockValue(rule.Key, rule.Value, rule.AllowedValues...)
+	}
+}
+
+// InitPolicy fetches the organization policy if a policy URL was defined at build time and applies it to the engine's
+// configuration. If the policy can't be fetched, a previously cached policy is used.
+func InitPolicy(engine workflow.Engine) error {
+	config := engine.GetConfiguration()
+	logger := engine.GetLogger()
+
+	url := policyUrl
+	if len(url) == 0 {
+		return nil
+	}
+
+	publicKey, err := base64.StdEncoding.DecodeString(policyPublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		logger.Warn().Msg("Policy not applied, no valid public key configured to verify it.")
+		return nil
+	}
+
+	policy, err := loadPolicy(engine, url, publicKey)
+	if err != nil {
+		logger.Warn().Err(err).Str("url", url).Msg("Policy not applied.")
+		return nil
+	}
+
+	ApplyPolicy(config, policy)
+	logger.Debug().Str("url", url).Int("rules", len(policy.Rules)).Msg("Policy applied")
+	return nil
+}
+
+// loadPolicy returns the cached policy if it is recent enough, otherwise it fetches the policy and updates the cache.
+// A stale cached policy is used if fetching fails.
+func loadPolicy(engine workflow.Engine, url string, publicKey ed25519.PublicKey) (*Policy, error) {
+	logger := engine.GetLogger()
+	cacheFile := policyCacheFile(engine.GetConfiguration(), url)
+
+	cachedPolicy, cachedErr := readPolicy(cacheFile, publicKey)
+	if cachedErr == nil {
+		if info, statErr := os.Stat(cacheFile); statErr == nil && time.Since(info.ModTime()) < policyCacheDuration {
+			return cachedPolicy, nil
+		}
+	}
+
+	data, err := fetchPolicy(engine, url)
+	if err != nil {
+		if cachedErr == nil {
+			logger.Warn().Err(err).Msg("Failed to fetch policy, using cached policy.")
+			return cachedPolicy, nil
+		}
+		return nil, err
+	}
+
+	policy, err := verifyPolicy(data, publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cacheFile) > 0 {
+		err = os.MkdirAll(filepath.Dir(cacheFile), utils.FILEPERM_755)
+		if err == nil {
+			err = os.WriteFile(cacheFile, data, utils.FILEPERM_600)
+		}
+		if err != nil {
+			logger.Debug().Err(err).Msg("Failed to cache policy")
+		}
+	}
+
+	return policy, nil
+}
+
+func fetchPolicy(engine workflow.Engine, url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), policyFetchTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch policy: %w", err)
+	}
+
+	response, err := engine.GetNetworkAccess().GetHttpClient().Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch policy: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch policy: unexpected status code %d", response.StatusCode)
+	}
+
+	return io.ReadAll(response.Body)
+}
+
+func readPolicy(file string, publicKey ed25519.PublicKey) (*Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return verifyPolicy(data, publicKey)
+}
+
+func verifyPolicy(data []byte, publicKey ed25519.PublicKey) (*Policy, error) {
+	signedPolicy := &SignedPolicy{}
+	err := json.Unmarshal(data, signedPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signed policy: %w", err)
+	}
+	return signedPolicy.Verify(publicKey)
+}
+
+// policyCacheFile returns the location of the cached policy, it is empty if no cache directory is configured.
+func policyCacheFile(config configuration.Configuration, url string) string {
+	cacheDirectory := config.GetString(configuration.CACHE_PATH)
+	if len(cacheDirectory) == 0 {
+		return ""
+	}
+
+	hash := sha256.Sum256([]byte(url))
+	return filepath.Join(cacheDirectory, "policy", hex.EncodeToString(hash[:8])+".json")
+}
@@ -0,0 +1,140 @@
+package config_utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/snyk/go-application-framework/pkg/configuration"
+	"github.com/snyk/go-application-framework/pkg/workflow"
+)
+
+func signPolicy(t *testing.T, privateKey ed25519.PrivateKey, policy Policy) []byte {
+	t.Helper()
+	payload, err := json.Marshal(policy)
+	assert.NoError(t, err)
+
+	data, err := json.Marshal(SignedPolicy{
+		Payload:   base64.StdEncoding.EncodeToString(payload),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, payload)),
+	})
+	assert.NoError(t, err)
+	return data
+}
+
+func Test_InitPolicy(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	policy := Policy{Rules: []PolicyRule{
+		{Key: configuration.INSECURE_HTTPS, Value: false},
+		{Key: configuration.FLAG_SEVERITY_THRESHOLD, Value: "high", AllowedValues: []interface{}{"critical"}},
+	}}
+	signedPolicy := signPolicy(t, privateKey, policy)
+
+	available := true
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if !available {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(signedPolicy)
+	}))
+	defer ts.Close()
+
+	setPolicySource(t, ts.URL, base64.StdEncoding.EncodeToString(publicKey))
+
+	cacheDirectory := t.TempDir()
+	newEngine := func() workflow.Engine {
+		config := configuration.NewInMemory()
+		config.Set(configuration.CACHE_PATH, cacheDirectory)
+		return workflow.NewWorkFlowEngine(config)
+	}
+
+	t.Run("fetches and applies the policy", func(t *testing.T) {
+		engine := newEngine()
+		config := engine.GetConfiguration()
+		config.Set(configuration.INSECURE_HTTPS, true)
+
+		assert.NoError(t, InitPolicy(engine))
+		assert.Equal(t, 1, requests)
+		assert.Equal(t, []string{configuration.INSECURE_HTTPS, configuration.FLAG_SEVERITY_THRESHOLD}, config.GetLockedKeys())
+		assert.False(t, config.GetBool(configuration.INSECURE_HTTPS))
+		assert.Equal(t, "high", config.GetString(configuration.FLAG_SEVERITY_THRESHOLD))
+
+		config.Set(configuration.FLAG_SEVERITY_THRESHOLD, "critical")
+		assert.Equal(t, "critical", config.GetString(configuration.FLAG_SEVERITY_THRESHOLD))
+
+		_, err := config.GetWithError(configuration.INSECURE_HTTPS)
+		var lockedErr *configuration.LockedValueError
+		assert.ErrorAs(t, err, &lockedErr)
+	})
+
+	t.Run("uses the cached policy", func(t *testing.T) {
+		available = false
+		engine := newEngine()
+
+		assert.NoError(t, InitPolicy(engine))
+		assert.Equal(t, 1, requests)
+		assert.True(t, engine.GetConfiguration().IsLocked(configuration.INSECURE_HTTPS))
+	})
+
+	t.Run("ignores policies with invalid signatures", func(t *testing.T) {
+		otherPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+		assert.NoError(t, err)
+
+		setPolicySource(t, ts.URL, base64.StdEncoding.EncodeToString(otherPublicKey))
+		engine := newEngine()
+		config := engine.GetConfiguration()
+
+		assert.NoError(t, InitPolicy(engine))
+		assert.Empty(t, config.GetLockedKeys())
+	})
+
+	t.Run("the policy source can't be configured", func(t *testing.T) {
+		setPolicySource(t, "", "")
+		engine := newEngine()
+		config := engine.GetConfiguration()
+		config.Set("internal_policy_url", ts.URL)
+		t.Setenv("INTERNAL_POLICY_URL", ts.URL)
+
+		assert.NoError(t, InitPolicy(engine))
+		assert.Empty(t, config.GetLockedKeys())
+	})
+
+	t.Run("fetching the policy times out", func(t *testing.T) {
+		blocked := make(chan struct{})
+		blockingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-blocked
+		}))
+		defer blockingServer.Close()
+		defer close(blocked)
+
+		setPolicySource(t, blockingServer.URL, base64.StdEncoding.EncodeToString(publicKey))
+		originalTimeout := policyFetchTimeout
+		policyFetchTimeout = 10 * time.Millisecond
+		t.Cleanup(func() { policyFetchTimeout = originalTimeout })
+
+		engine := newEngine()
+		assert.NoError(t, InitPolicy(engine))
+		assert.Empty(t, engine.GetConfiguration().GetLockedKeys())
+	})
+}
+
+func setPolicySource(t *testing.T, url string, publicKey string) {
+	t.Helper()
+	originalUrl, originalPublicKey := policyUrl, policyPublicKey
+	policyUrl, policyPublicKey = url, publicKey
+	t.Cleanup(func() {
+		policyUrl, policyPublicKey = originalUrl, originalPublicKey
+	})
+}
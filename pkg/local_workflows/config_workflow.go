@@ -180,17 +180,22 @@ func configEnvironmentWorkflowEntryPoint(invocationCtx workflow.InvocationContex
 		return result, err
 	}
 
-	config.Unset(configuration.ORGANIZATION)
-	config.Unset(configuration.AUTHENTICATION_TOKEN)
-	config.Unset(auth.CONFIG_KEY_OAUTH_TOKEN)
+	// change all values at once, credentials of the previous environment must not remain in the configuration
+	config.PersistInStorage(configuration.API_URL)
+	transaction := config.BeginTransaction()
+	transaction.Unset(configuration.ORGANIZATION)
+	transaction.Unset(configuration.AUTHENTICATION_TOKEN)
+	transaction.Unset(auth.CONFIG_KEY_OAUTH_TOKEN)
+	transaction.Set(configuration.API_URL, newEnvUrl)
+	err = transaction.Commit()
+	if err != nil {
+		return result, err
+	}
 
 	uiErr := userInterface.Output(fmt.Sprintf("You are now using the environment \"%s\".", newEnvUrl))
 	if uiErr != nil {
 		logger.Print(uiErr)
 	}
 
-	config.PersistInStorage(configuration.API_URL)
-	config.Set(configuration.API_URL, newEnvUrl)
-
 	return result, err
 }
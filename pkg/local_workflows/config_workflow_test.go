@@ -57,13 +57,17 @@ func Test_ConfigEnvironment_entryPoint_changeEnv(t *testing.T) {
 	config.Set(configuration.API_URL, "random")
 
 	mockctl := gomock.NewController(t)
-	storage := mocks.NewMockStorage(mockctl)
+	storage := mocks.NewMockTransactionalStorage(mockctl)
 	config.SetStorage(storage)
 
-	storage.EXPECT().Set(configuration.API_URL, gomock.Any())
-	storage.EXPECT().Set(configuration.AUTHENTICATION_TOKEN, gomock.Any())
-	storage.EXPECT().Set(auth.CONFIG_KEY_OAUTH_TOKEN, gomock.Any())
-	storage.EXPECT().Set(configuration.ORGANIZATION, gomock.Any())
+	storage.EXPECT().SetMany(gomock.Any()).DoAndReturn(func(values map[string]any) error {
+		assert.Len(t, values, 4)
+		assert.Contains(t, values, configuration.API_URL)
+		assert.Contains(t, values, configuration.AUTHENTICATION_TOKEN)
+		assert.Contains(t, values, auth.CONFIG_KEY_OAUTH_TOKEN)
+		assert.Contains(t, values, configuration.ORGANIZATION)
+		return nil
+	})
 
 	invocationCtx := mocks.NewMockInvocationContext(mockctl)
 	invocationCtx.EXPECT().GetConfiguration().Return(config)
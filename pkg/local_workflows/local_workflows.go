@@ -17,6 +17,7 @@ func Init(engine workflow.Engine) error {
 		InitConfigWorkflow,
 		InitDataTransformationWorkflow,
 		InitFilterFindingsWorkflow,
+		InitNetworkDiagnosticsWorkflow,
 	}
 
 	for i := range initMethods {
@@ -28,3 +29,11 @@ func Init(engine workflow.Engine) error {
 
 	return err
 }
+
+// requireNetworkAccess declares that the workflow of the entry can't run without network access, so that it isn't
+// invoked while the offline mode is enabled.
+func requireNetworkAccess(entry workflow.Entry) {
+	if offlineEntry, ok := entry.(workflow.OfflineCapableEntry); ok {
+		offlineEntry.SetOfflineCapable(false)
+	}
+}
@@ -0,0 +1,447 @@
+package localworkflows
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/snyk/go-httpauth/pkg/httpauth"
+	"github.com/spf13/pflag"
+
+	"github.com/snyk/go-application-framework/internal/api"
+	"github.com/snyk/go-application-framework/pkg/configuration"
+	"github.com/snyk/go-application-framework/pkg/networking"
+	"github.com/snyk/go-application-framework/pkg/networking/certs"
+	"github.com/snyk/go-application-framework/pkg/networking/fips"
+	"github.com/snyk/go-application-framework/pkg/workflow"
+)
+
+const (
+	networkDiagnosticsWorkflowName = "diagnostics.network"
+	networkDiagnosticsTimeout      = 15 * time.Second
+	// maxClockSkew is the difference to the server time above which tokens might be considered expired or not yet valid
+	maxClockSkew = 5 * time.Minute
+)
+
+var WORKFLOWID_NETWORK_DIAGNOSTICS workflow.Identifier = workflow.NewWorkflowIdentifier(networkDiagnosticsWorkflowName)
+
+// DiagnosticStatus is the outcome of a single diagnostic check.
+type DiagnosticStatus string
+
+const (
+	DiagnosticStatusOk      DiagnosticStatus = "ok"
+	DiagnosticStatusWarning DiagnosticStatus = "warning"
+	DiagnosticStatusError   DiagnosticStatus = "error"
+)
+
+// DiagnosticCheck is the result of a single check of the network diagnostics.
+type DiagnosticCheck struct {
+	Name    string           `json:"name"`
+	Target  string           `json:"target,omitempty"`
+	Status  DiagnosticStatus `json:"status"`
+	Message string           `json:"message"`
+	Details map[string]any   `json:"details,omitempty"`
+}
+
+// NetworkDiagnosticsReport is the output of the network diagnostics workflow.
+type NetworkDiagnosticsReport struct {
+	Checks []DiagnosticCheck `json:"checks"`
+}
+
+func (r *NetworkDiagnosticsReport) add(check DiagnosticCheck) {
+	r.Checks = append(r.Checks, check)
+}
+
+// String renders the report for humans.
+func (r *NetworkDiagnosticsReport) String() string {
+	var sb strings.Builder
+	sb.WriteString("Network diagnostics\n\n")
+	for _, check := range r.Checks {
+		status := fmt.Sprintf("[%s]", strings.ToUpper(string(check.Status)))
+		name := check.Name
+		if len(check.Target) > 0 {
+			name += " (" + check.Target + ")"
+		}
+		sb.WriteString(fmt.Sprintf("%-10s %s: %s\n", status, name, check.Message))
+	}
+	return sb.String()
+}
+
+// InitNetworkDiagnosticsWorkflow initializes the network diagnostics workflow before registering it with the engine.
+func InitNetworkDiagnosticsWorkflow(engine workflow.Engine) error {
+	flags := pflag.NewFlagSet(networkDiagnosticsWorkflowName, pflag.ExitOnError)
+	flags.Bool(jsonFlag, false, "output in json format")
+
+	_, err := engine.Register(WORKFLOWID_NETWORK_DIAGNOSTICS, workflow.ConfigurationOptionsFromFlagset(flags), networkDiagnosticsWorkflowEntryPoint)
+	return err
+}
+
+// networkDiagnosticsWorkflowEntryPoint checks the network setup, i.e. FIPS, CA certificates, proxy, DNS, TLS, clock
+// skew and authentication, and returns a report in human readable or JSON format.
+func networkDiagnosticsWorkflowEntryPoint(invocationCtx workflow.InvocationContext, _ []workflow.Data) ([]workflow.Data, error) {
+	config := invocationCtx.GetConfiguration()
+	logger := invocationCtx.GetEnhancedLogger()
+	networkAccess := invocationCtx.GetNetworkAccess()
+
+	report := runNetworkDiagnostics(config, networkAccess)
+
+	var data workflow.Data
+	typeId := workflow.NewTypeIdentifier(WORKFLOWID_NETWORK_DIAGNOSTICS, networkDiagnosticsWorkflowName)
+	if config.GetBool(jsonFlag) {
+		reportBytes, err := json.Marshal(report)
+		if err != nil {
+			return nil, err
+		}
+		data = workflow.NewData(typeId, "application/json", reportBytes, workflow.WithLogger(logger), workflow.WithConfiguration(config))
+	} else {
+		data = workflow.NewData(typeId, "text/plain", report.String(), workflow.WithLogger(logger), workflow.WithConfiguration(config))
+	}
+
+	return []workflow.Data{data}, nil
+}
+
+func runNetworkDiagnostics(config configuration.Configuration, networkAccess networking.NetworkAccess) *NetworkDiagnosticsReport {
+	networkAccess = getDiagnosticNetworkAccess(networkAccess)
+	report := &NetworkDiagnosticsReport{}
+	report.add(checkFips())
+	report.add(checkCertificates(config))
+	if len(config.GetString(configuration.TLS_CLIENT_CERTIFICATE_FILE)) > 0 {
+		report.add(checkClientCertificate(config))
+	}
+	report.add(checkProxyAuthentication(config))
+
+	for _, target := range getDiagnosticTargets(config) {
+		report.add(checkProxy(networkAccess, target))
+		report.add(checkDns(target))
+		report.add(checkConnection(networkAccess, target))
+	}
+
+	report.add(checkAuthentication(config, networkAccess))
+	return report
+}
+
+// getDiagnosticNetworkAccess returns a clone of the network access that sends each request, i.e. without the response
+// cache, the circuit breaker, the offline mode and retries, so that the checks report the actual connections.
+func getDiagnosticNetworkAccess(networkAccess networking.NetworkAccess) networking.NetworkAccess {
+	clone := networkAccess.Clone()
+	config := clone.GetConfiguration()
+	config.Set(configuration.HTTP_CACHE_ENABLED, false)
+	config.Set(configuration.CIRCUIT_BREAKER_THRESHOLD, 0)
+	config.Set(configuration.OFFLINE, false)
+	config.Set(configuration.MAX_RETRY_ATTEMPTS, 1)
+	return clone
+}
+
+// getDiagnosticTargets returns the API URL, the app URL and the URLs of the authenticated subdomains.
+func getDiagnosticTargets(config configuration.Configuration) []string {
+	apiUrl := config.GetString(configuration.API_URL)
+	targets := []string{apiUrl}
+
+	if appUrl := config.GetString(configuration.WEB_APP_URL); len(appUrl) > 0 {
+		targets = append(targets, appUrl)
+	}
+
+	for _, subdomain := range config.GetStringSlice(configuration.AUTHENTICATION_SUBDOMAINS) {
+		if subdomainUrl, err := api.DeriveSubdomainUrl(apiUrl, subdomain); err == nil {
+			targets = append(targets, subdomainUrl)
+		}
+	}
+	return targets
+}
+
+func checkFips() DiagnosticCheck {
+	check := DiagnosticCheck{Name: "FIPS", Status: DiagnosticStatusOk, Message: "FIPS mode is disabled"}
+	if fips.IsAvailable() {
+		check.Message = "FIPS mode is enabled"
+	}
+	check.Details = map[string]any{"enabled": fips.IsAvailable()}
+	return check
+}
+
+func checkCertificates(config configuration.Configuration) DiagnosticCheck {
+	check := DiagnosticCheck{Name: "CA certificates", Status: DiagnosticStatusOk, Details: map[string]any{}}
+
+	extraCaLocations := []string{}
+	for _, location := range append([]string{config.GetString(configuration.ADD_TRUSTED_CA_FILE)}, config.GetStringSlice(configuration.ADD_TRUSTED_CA_LOCATIONS)...) {
+		if len(location) > 0 {
+			extraCaLocations = append(extraCaLocations, location)
+		}
+	}
+
+	// the configured certificates replace the system certificates only if there are any, see NetworkAccess.AddRootCAs
+	messages := []string{}
+	exclusive := config.GetBool(configuration.TRUSTED_CA_EXCLUSIVE) && len(extraCaLocations) > 0
+	check.Details["exclusive"] = exclusive
+	if exclusive {
+		messages = append(messages, "using only the configured certificates")
+	} else if _, err := x509.SystemCertPool(); err != nil {
+		check.Status = DiagnosticStatusWarning
+		messages = append(messages, fmt.Sprintf("system certificates are not available (%v)", err))
+	} else {
+		messages = append(messages, "using system certificates")
+	}
+
+	if config.GetBool(configuration.TRUSTED_CA_EXCLUSIVE) && !exclusive {
+		check.Status = DiagnosticStatusWarning
+		messages = append(messages, "exclusive certificates are requested but none are configured")
+	}
+
+	if len(extraCaLocations) > 0 {
+		check.Details["additional_ca_locations"] = extraCaLocations
+		extraCerts, err := certs.LoadCertificates(extraCaLocations...)
+		subjects := make([]string, 0, len(extraCerts))
+		for _, cert := range extraCerts {
+			subjects = append(subjects, cert.Subject.String())
+		}
+		check.Details["additional_ca_subjects"] = subjects
+		messages = append(messages, fmt.Sprintf("%d additional certificates from %s", len(extraCerts), strings.Join(extraCaLocations, ", ")))
+
+		if err != nil {
+			check.Status = DiagnosticStatusError
+			failures := []string{err.Error()}
+			var joinedErr interface{ Unwrap() []error }
+			if errors.As(err, &joinedErr) {
+				failures = []string{}
+				for _, failure := range joinedErr.Unwrap() {
+					failures = append(failures, failure.Error())
+				}
+			}
+			check.Details["additional_ca_errors"] = failures
+			messages = append(messages, fmt.Sprintf("failed to load %d certificates (%s)", len(failures), strings.Join(failures, "; ")))
+		}
+	}
+
+	if pins := config.GetStringSlice(configuration.API_SPKI_PINS); len(pins) > 0 {
+		check.Details["api_spki_pins"] = pins
+		messages = append(messages, fmt.Sprintf("%d public key pins for the API host", len(pins)))
+	}
+
+	insecure := config.GetBool(configuration.INSECURE_HTTPS)
+	check.Details["insecure"] = insecure
+	if insecure {
+		if check.Status == DiagnosticStatusOk {
+			check.Status = DiagnosticStatusWarning
+		}
+		messages = append(messages, "certificate verification is disabled")
+	}
+
+	check.Message = strings.Join(messages, ", ")
+	return check
+}
+
+func checkClientCertificate(config configuration.Configuration) DiagnosticCheck {
+	certificateFile := config.GetString(configuration.TLS_CLIENT_CERTIFICATE_FILE)
+	keyFile := config.GetString(configuration.TLS_CLIENT_KEY_FILE)
+	check := DiagnosticCheck{
+		Name:    "Client certificate",
+		Status:  DiagnosticStatusOk,
+		Details: map[string]any{"certificate_file": certificateFile, "key_file": keyFile},
+	}
+
+	certificate, err := certs.LoadClientCertificate(certificateFile, keyFile, config.GetString(configuration.TLS_CLIENT_KEY_PASSWORD))
+	if err != nil {
+		check.Status = DiagnosticStatusError
+		check.Message = fmt.Sprintf("failed to load client certificate (%v)", err)
+		return check
+	}
+
+	leaf := certificate.Leaf
+	check.Details["subject"] = leaf.Subject.String()
+	check.Details["issuer"] = leaf.Issuer.String()
+	check.Details["not_after"] = leaf.NotAfter.UTC().Format(time.RFC3339)
+
+	now := time.Now()
+	switch {
+	case now.After(leaf.NotAfter):
+		check.Status = DiagnosticStatusError
+		check.Message = fmt.Sprintf("client certificate %s expired on %s", leaf.Subject, leaf.NotAfter.UTC().Format(time.RFC3339))
+	case now.Before(leaf.NotBefore):
+		check.Status = DiagnosticStatusError
+		check.Message = fmt.Sprintf("client certificate %s is not valid before %s", leaf.Subject, leaf.NotBefore.UTC().Format(time.RFC3339))
+	default:
+		check.Message = fmt.Sprintf("using client certificate %s, valid until %s", leaf.Subject, leaf.NotAfter.UTC().Format(time.RFC3339))
+	}
+	return check
+}
+
+func checkProxyAuthentication(config configuration.Configuration) DiagnosticCheck {
+	mechanismName := config.GetString(configuration.PROXY_AUTHENTICATION_MECHANISM)
+	mechanism := httpauth.StringFromAuthenticationMechanism(httpauth.AuthenticationMechanismFromString(mechanismName))
+	check := DiagnosticCheck{
+		Name:    "Proxy authentication",
+		Status:  DiagnosticStatusOk,
+		Message: fmt.Sprintf("using mechanism %q", mechanism),
+		Details: map[string]any{"mechanism": mechanism},
+	}
+
+	switch {
+	case len(mechanismName) > 0 && !strings.EqualFold(mechanismName, mechanism):
+		check.Status = DiagnosticStatusWarning
+		check.Message = fmt.Sprintf("unknown mechanism %q, using %q", mechanismName, mechanism)
+	case len(mechanism) == 0:
+		check.Message = "no mechanism configured"
+	}
+	return check
+}
+
+// checkProxy reports the proxy the network stack uses for the target, e.g. as configured via PROXY_URL or PROXY_PAC.
+// The proxy of the environment, which is used by other tools, is reported as well if it differs.
+func checkProxy(networkAccess networking.NetworkAccess, target string) DiagnosticCheck {
+	check := DiagnosticCheck{Name: "Proxy", Target: target, Status: DiagnosticStatusOk, Details: map[string]any{}}
+
+	request, err := http.NewRequest(http.MethodGet, target, http.NoBody)
+	if err != nil {
+		check.Status = DiagnosticStatusError
+		check.Message = err.Error()
+		return check
+	}
+
+	proxyUrl, err := networking.ResolveProxy(networkAccess, request)
+	switch {
+	case err != nil:
+		check.Status = DiagnosticStatusError
+		check.Message = fmt.Sprintf("invalid proxy configuration (%v)", err)
+	case proxyUrl == nil:
+		check.Message = "direct connection"
+	default:
+		check.Message = "using proxy " + proxyUrl.Redacted()
+		check.Details["proxy"] = proxyUrl.Redacted()
+	}
+
+	environmentProxyUrl, environmentErr := http.ProxyFromEnvironment(request)
+	switch {
+	case environmentErr != nil:
+		check.Details["environment_proxy_error"] = environmentErr.Error()
+		check.Message += fmt.Sprintf(", the proxy of the environment is invalid (%v)", environmentErr)
+	case err == nil && isSameProxy(proxyUrl, environmentProxyUrl):
+		// the same proxy, e.g. if none is configured explicitly
+	case environmentProxyUrl == nil:
+		check.Details["environment_proxy"] = ""
+		check.Message += ", the environment uses a direct connection"
+	default:
+		check.Details["environment_proxy"] = environmentProxyUrl.Redacted()
+		check.Message += ", the environment uses proxy " + environmentProxyUrl.Redacted()
+	}
+	return check
+}
+
+// isSameProxy compares two proxies, nil means a direct connection.
+func isSameProxy(a *url.URL, b *url.URL) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.String() == b.String()
+}
+
+func checkDns(target string) DiagnosticCheck {
+	check := DiagnosticCheck{Name: "DNS", Target: target, Status: DiagnosticStatusOk}
+
+	targetUrl, err := url.Parse(target)
+	if err != nil {
+		check.Status = DiagnosticStatusError
+		check.Message = err.Error()
+		return check
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), networkDiagnosticsTimeout)
+	defer cancel()
+
+	addresses, err := net.DefaultResolver.LookupHost(ctx, targetUrl.Hostname())
+	if err != nil {
+		// behind a proxy, resolving the name locally is not required
+		check.Status = DiagnosticStatusWarning
+		check.Message = fmt.Sprintf("failed to resolve %s (%v)", targetUrl.Hostname(), err)
+		return check
+	}
+
+	check.Message = "resolved to " + strings.Join(addresses, ", ")
+	check.Details = map[string]any{"addresses": addresses}
+	return check
+}
+
+// checkConnection sends a request using the configured network stack and reports TLS details as well as the clock
+// skew derived from the Date header of the response.
+func checkConnection(networkAccess networking.NetworkAccess, target string) DiagnosticCheck {
+	check := DiagnosticCheck{Name: "Connection", Target: target, Status: DiagnosticStatusOk, Details: map[string]any{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), networkDiagnosticsTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target, http.NoBody)
+	if err != nil {
+		check.Status = DiagnosticStatusError
+		check.Message = err.Error()
+		return check
+	}
+
+	client := networkAccess.GetUnauthorizedHttpClient()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	start := time.Now()
+	response, err := client.Do(request)
+	if err != nil {
+		check.Status = DiagnosticStatusError
+		check.Message = fmt.Sprintf("request failed (%v)", err)
+		return check
+	}
+	_ = response.Body.Close() //nolint:errcheck // only the headers are relevant
+	localTime := start.Add(time.Since(start) / 2)
+
+	messages := []string{fmt.Sprintf("HTTP %d", response.StatusCode)}
+	check.Details["status_code"] = response.StatusCode
+	// the connection might be reused from a previous request, so the state is taken from the response
+	if tlsState := response.TLS; tlsState != nil {
+		version := tls.VersionName(tlsState.Version)
+		messages = append(messages, version)
+		check.Details["tls_version"] = version
+		check.Details["tls_cipher_suite"] = tls.CipherSuiteName(tlsState.CipherSuite)
+		if len(tlsState.PeerCertificates) > 0 {
+			leaf := tlsState.PeerCertificates[0]
+			check.Details["certificate_subject"] = leaf.Subject.String()
+			check.Details["certificate_issuer"] = leaf.Issuer.String()
+			check.Details["certificate_expiry"] = leaf.NotAfter.UTC().Format(time.RFC3339)
+			messages = append(messages, "certificate issued by "+leaf.Issuer.String())
+		}
+	}
+
+	if serverTime, dateErr := http.ParseTime(response.Header.Get("Date")); dateErr == nil {
+		skew := localTime.Sub(serverTime).Round(time.Second)
+		check.Details["clock_skew_seconds"] = skew.Seconds()
+		if skew.Abs() > maxClockSkew {
+			check.Status = DiagnosticStatusWarning
+			messages = append(messages, fmt.Sprintf("local clock differs by %s from server time", skew))
+		}
+	}
+
+	check.Message = strings.Join(messages, ", ")
+	return check
+}
+
+func checkAuthentication(config configuration.Configuration, networkAccess networking.NetworkAccess) DiagnosticCheck {
+	check := DiagnosticCheck{Name: "Authentication", Target: config.GetString(configuration.API_URL), Status: DiagnosticStatusOk}
+
+	client := api.NewApi(config.GetString(configuration.API_URL), networkAccess.GetHttpClient())
+	self, err := client.GetSelf()
+	if err != nil {
+		check.Status = DiagnosticStatusError
+		check.Message = fmt.Sprintf("not authenticated (%v)", err)
+		return check
+	}
+
+	name := self.Data.Attributes.Username
+	if len(name) == 0 {
+		name = self.Data.Attributes.Name
+	}
+	check.Message = "authenticated as " + name
+	return check
+}
@@ -11,6 +11,7 @@ import (
 // If the header is already present, it will not be overwritten.
 // If the header is not present, a new UUID will be generated and added.
 // This is usefud for tracking requests across services.
+// NetworkAccess adds the header to all requests itself, so this is only needed for requests created otherwise.
 func AddSnykRequestId(n networking.NetworkAccess) {
 	n.AddDynamicHeaderField("snyk-request-id", func(values []string) []string {
 		if len(values) > 0 {
@@ -211,10 +211,13 @@ func Test_Output_InitOutputWorkflow(t *testing.T) {
 	err := InitOutputWorkflow(engine)
 	assert.Nil(t, err)
 
-	json := config.Get("json")
+	// flags are bound globally and local to the workflow
+	scopedConfig := config.Scope(WORKFLOWID_OUTPUT_WORKFLOW.Host)
+	json := scopedConfig.Get("json")
 	assert.Equal(t, false, json)
+	assert.Equal(t, false, config.Get("json"))
 
-	jsonFileOutput := config.Get("json-file-output")
+	jsonFileOutput := scopedConfig.Get("json-file-output")
 	assert.Equal(t, "", jsonFileOutput)
 }
 
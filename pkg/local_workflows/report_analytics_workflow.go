@@ -54,6 +54,7 @@ func InitReportAnalyticsWorkflow(engine workflow.Engine) error {
 
 	// don't display in help
 	result.SetVisibility(false)
+	requireNetworkAccess(result)
 	return err
 }
 
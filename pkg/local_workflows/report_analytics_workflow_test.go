@@ -311,6 +311,17 @@ func testGetScanDonePayloadString() string {
 	}`
 }
 
+func Test_ReportAnalytics_RequiresNetworkAccess(t *testing.T) {
+	engine := workflow.NewWorkFlowEngine(configuration.NewWithOpts())
+	require.NoError(t, InitReportAnalyticsWorkflow(engine))
+
+	entry, ok := engine.GetWorkflow(WORKFLOWID_REPORT_ANALYTICS)
+	require.True(t, ok)
+	offlineEntry, ok := entry.(workflow.OfflineCapableEntry)
+	require.True(t, ok)
+	require.False(t, offlineEntry.IsOfflineCapable())
+}
+
 func testInitReportAnalyticsWorkflow(ctrl *gomock.Controller) error {
 	engine := mocks.NewMockEngine(ctrl)
 	engine.EXPECT().Register(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes().Return(&workflow.EntryImpl{}, nil)
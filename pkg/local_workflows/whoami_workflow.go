@@ -32,8 +32,13 @@ func InitWhoAmIWorkflow(engine workflow.Engine) error {
 	whoAmIConfig.Bool(jsonFlag, false, "output in json format")
 
 	// register workflow with engine
-	_, err := engine.Register(WORKFLOWID_WHOAMI, workflow.ConfigurationOptionsFromFlagset(whoAmIConfig), whoAmIWorkflowEntryPoint)
-	return err
+	entry, err := engine.Register(WORKFLOWID_WHOAMI, workflow.ConfigurationOptionsFromFlagset(whoAmIConfig), whoAmIWorkflowEntryPoint)
+	if err != nil {
+		return err
+	}
+
+	requireNetworkAccess(entry)
+	return nil
 }
 
 // whoAmIWorkflowEntryPoint is the entry point for the whoAmI workflow.
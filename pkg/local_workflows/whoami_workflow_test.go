@@ -14,6 +14,8 @@ import (
 
 	"github.com/snyk/go-application-framework/pkg/configuration"
 	"github.com/snyk/go-application-framework/pkg/mocks"
+	"github.com/snyk/go-application-framework/pkg/networking/middleware"
+	"github.com/snyk/go-application-framework/pkg/workflow"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -269,3 +271,14 @@ func Test_WhoAmI_whoAmIWorkflowEntryPoint_extractUserFailures(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, expectedError.Error(), err.Error())
 }
+
+func Test_WhoAmI_RequiresNetworkAccess(t *testing.T) {
+	config := configuration.NewWithOpts()
+	engine := workflow.NewWorkFlowEngine(config)
+	assert.NoError(t, InitWhoAmIWorkflow(engine))
+	assert.NoError(t, engine.Init())
+
+	config.Set(configuration.OFFLINE, true)
+	_, err := engine.Invoke(WORKFLOWID_WHOAMI)
+	assert.ErrorIs(t, err, middleware.ErrOffline)
+}
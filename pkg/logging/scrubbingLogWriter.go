@@ -17,8 +17,10 @@
 package logging
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
+	"reflect"
 	"regexp"
 	"strings"
 	"sync"
@@ -92,18 +94,25 @@ func (w *scrubbingIoWriter) RemoveTerm(term string) {
 	delete(w.scrubDict, term)
 }
 
+// addLiteralTermToDict adds a term that is matched literally, e.g. for user defined secrets that might contain regular
+// expression syntax.
+func addLiteralTermToDict(term string, dict ScrubbingDict) {
+	addTermToDict(regexp.QuoteMeta(term), 0, dict)
+}
+
 func GetScrubDictFromConfig(config configuration.Configuration) ScrubbingDict {
 	dict := getDefaultDict()
-	addTermToDict(config.GetString(configuration.AUTHENTICATION_TOKEN), 0, dict)
-	addTermToDict(config.GetString(configuration.AUTHENTICATION_BEARER_TOKEN), 0, dict)
-	addTermToDict(config.GetString(auth.PARAMETER_CLIENT_SECRET), 0, dict)
-	addTermToDict(config.GetString(auth.PARAMETER_CLIENT_ID), 0, dict)
+	addLiteralTermToDict(config.GetString(configuration.AUTHENTICATION_TOKEN), dict)
+	addLiteralTermToDict(config.GetString(configuration.AUTHENTICATION_BEARER_TOKEN), dict)
+	addLiteralTermToDict(config.GetString(auth.PARAMETER_CLIENT_SECRET), dict)
+	addLiteralTermToDict(config.GetString(auth.PARAMETER_CLIENT_ID), dict)
+	addLiteralTermToDict(config.GetString(configuration.TLS_CLIENT_KEY_PASSWORD), dict)
 	token, err := auth.GetOAuthToken(config)
 	if err != nil || token == nil {
 		return dict
 	}
-	addTermToDict(token.AccessToken, 0, dict)
-	addTermToDict(token.RefreshToken, 0, dict)
+	addLiteralTermToDict(token.AccessToken, dict)
+	addLiteralTermToDict(token.RefreshToken, dict)
 	return dict
 }
 
@@ -207,6 +216,101 @@ func scrub(p []byte, scrubDict ScrubbingDict) []byte {
 	return []byte(s)
 }
 
+// ScrubValue removes all terms of the given dictionary from the value. Strings contained in slices, maps and structs
+// are scrubbed as well. Slices, maps and structs of other types than the ones below are returned as []interface{} and
+// map[string]interface{}, numbers and booleans are returned unchanged.
+func ScrubValue(value interface{}, scrubDict ScrubbingDict) interface{} {
+	switch v := value.(type) {
+	case string:
+		return string(scrub([]byte(v), scrubDict))
+	case []string:
+		result := make([]string, len(v))
+		for i := range v {
+			result[i] = string(scrub([]byte(v[i]), scrubDict))
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i := range v {
+			result[i] = ScrubValue(v[i], scrubDict)
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, entry := range v {
+			result[key] = ScrubValue(entry, scrubDict)
+		}
+		return result
+	case map[string]string:
+		result := make(map[string]string, len(v))
+		for key, entry := range v {
+			result[key] = string(scrub([]byte(entry), scrubDict))
+		}
+		return result
+	default:
+		return scrubReflectedValue(value, scrubDict)
+	}
+}
+
+// scrubReflectedValue scrubs the values of ScrubValue that are not handled explicitly.
+func scrubReflectedValue(value interface{}, scrubDict ScrubbingDict) interface{} {
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.Slice, reflect.Array:
+		if reflected.Kind() == reflect.Slice && reflected.IsNil() {
+			return value
+		}
+		result := make([]interface{}, reflected.Len())
+		for i := range result {
+			result[i] = ScrubValue(reflected.Index(i).Interface(), scrubDict)
+		}
+		return result
+	case reflect.Map:
+		if reflected.IsNil() {
+			return value
+		}
+		result := make(map[string]interface{}, reflected.Len())
+		iter := reflected.MapRange()
+		for iter.Next() {
+			key := string(scrub([]byte(fmt.Sprint(iter.Key().Interface())), scrubDict))
+			result[key] = ScrubValue(iter.Value().Interface(), scrubDict)
+		}
+		return result
+	case reflect.Struct, reflect.Pointer, reflect.Interface:
+		if reflected.Kind() != reflect.Struct && reflected.IsNil() {
+			return value
+		}
+		// structs are scrubbed in their serialized form, values that can't be serialized are scrubbed as text
+		data, err := json.Marshal(value)
+		if err != nil {
+			return string(scrub([]byte(fmt.Sprintf("%v", value)), scrubDict))
+		}
+		var generic interface{}
+		if err = json.Unmarshal(data, &generic); err != nil {
+			return string(scrub(data, scrubDict))
+		}
+		return ScrubValue(generic, scrubDict)
+	default:
+		return value
+	}
+}
+
+// CreateSnapshot creates a snapshot of the given configuration, scrubbing all secrets of its scrub dictionary, see
+// GetScrubDictFromConfig.
+func CreateSnapshot(config configuration.Configuration) *configuration.Snapshot {
+	//nolint:errcheck // the redact function is never nil
+	snapshot, _ := configuration.CreateSnapshot(config, NewSnapshotRedactFunction(GetScrubDictFromConfig(config)))
+	return snapshot
+}
+
+// NewSnapshotRedactFunction returns a function to be used with configuration.CreateSnapshot that scrubs all values
+// using the given dictionary, see GetScrubDictFromConfig.
+func NewSnapshotRedactFunction(scrubDict ScrubbingDict) configuration.SnapshotRedactFunction {
+	return func(_ string, value interface{}) interface{} {
+		return ScrubValue(value, scrubDict)
+	}
+}
+
 func (w *scrubbingIoWriter) Write(p []byte) (n int, err error) {
 	// lock for dict changes, but allow unlimited readers
 	w.m.RLock()
@@ -80,6 +80,20 @@ func TestScrubbingWriter_WriteLevel(t *testing.T) {
 	require.Equal(t, expected, string(mockWriter.written), "password should be scrubbed")
 }
 
+func TestGetScrubDictFromConfig_regexMetacharacters(t *testing.T) {
+	config := configuration.NewInMemory()
+	config.Set(configuration.TLS_CLIENT_KEY_PASSWORD, "s3cr3t(pass")
+	config.Set(configuration.AUTHENTICATION_TOKEN, "a.b*c")
+
+	mockWriter := &mockWriter{}
+	writer := NewScrubbingWriter(mockWriter, GetScrubDictFromConfig(config))
+
+	_, err := writer.Write([]byte("password s3cr3t(pass, token a.b*c, other aXbbc"))
+	assert.Nil(t, err)
+
+	require.Equal(t, "password ***, token ***, other aXbbc", string(mockWriter.written))
+}
+
 func TestScrubbingIoWriter(t *testing.T) {
 	scrubDict := map[string]scrubStruct{
 		"token":    {0, regexp.MustCompile("token")},
@@ -243,3 +257,34 @@ func TestAddDefaults(t *testing.T) {
 		})
 	}
 }
+
+func TestScrubValue(t *testing.T) {
+	dict := getDefaultDict()
+	addTermToDict("secret", 0, dict)
+
+	assert.Equal(t, "my *** value", ScrubValue("my secret value", dict))
+	assert.Equal(t, []string{"***", "public"}, ScrubValue([]string{"secret", "public"}, dict))
+	assert.Equal(t, []interface{}{"***", 12}, ScrubValue([]interface{}{"secret", 12}, dict))
+	assert.Equal(t, map[string]interface{}{"token": "***"}, ScrubValue(map[string]interface{}{"token": "secret"}, dict))
+	assert.Equal(t, true, ScrubValue(true, dict))
+	assert.Equal(t, map[string]string{"token": "***"}, ScrubValue(map[string]string{"token": "secret"}, dict))
+	assert.Equal(t, map[string]interface{}{"nested": map[string]string{"token": "***"}}, ScrubValue(map[string]map[string]string{"nested": {"token": "secret"}}, dict))
+	assert.Equal(t, []interface{}{[]string{"***"}}, ScrubValue([][]string{{"secret"}}, dict))
+	assert.Equal(t, map[string]interface{}{"Token": "***"}, ScrubValue(struct{ Token string }{Token: "secret"}, dict))
+}
+
+func TestNewSnapshotRedactFunction(t *testing.T) {
+	config := configuration.NewWithOpts()
+	config.Set(configuration.AUTHENTICATION_TOKEN, "my-api-token")
+	config.Set(configuration.ORGANIZATION, "my-org")
+
+	config.Set("headers", map[string]string{"Authorization": "token my-api-token"})
+	config.Set("values", []interface{}{map[string]string{"token": "my-api-token"}})
+
+	snapshot := CreateSnapshot(config)
+
+	assert.Equal(t, redactMask, snapshot.Values[configuration.AUTHENTICATION_TOKEN])
+	assert.Equal(t, "my-org", snapshot.Values[configuration.ORGANIZATION])
+	assert.Equal(t, map[string]string{"Authorization": "token " + redactMask}, snapshot.Values["headers"])
+	assert.Equal(t, []interface{}{map[string]string{"token": redactMask}}, snapshot.Values["values"])
+}
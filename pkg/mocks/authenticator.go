@@ -75,3 +75,82 @@ func (mr *MockAuthenticatorMockRecorder) IsSupported() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsSupported", reflect.TypeOf((*MockAuthenticator)(nil).IsSupported))
 }
+
+// MockRenewableAuthenticator is a mock of RenewableAuthenticator interface.
+type MockRenewableAuthenticator struct {
+	ctrl     *gomock.Controller
+	recorder *MockRenewableAuthenticatorMockRecorder
+}
+
+// MockRenewableAuthenticatorMockRecorder is the mock recorder for MockRenewableAuthenticator.
+type MockRenewableAuthenticatorMockRecorder struct {
+	mock *MockRenewableAuthenticator
+}
+
+// NewMockRenewableAuthenticator creates a new mock instance.
+func NewMockRenewableAuthenticator(ctrl *gomock.Controller) *MockRenewableAuthenticator {
+	mock := &MockRenewableAuthenticator{ctrl: ctrl}
+	mock.recorder = &MockRenewableAuthenticatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRenewableAuthenticator) EXPECT() *MockRenewableAuthenticatorMockRecorder {
+	return m.recorder
+}
+
+// AddAuthenticationHeader mocks base method.
+func (m *MockRenewableAuthenticator) AddAuthenticationHeader(request *http.Request) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddAuthenticationHeader", request)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddAuthenticationHeader indicates an expected call of AddAuthenticationHeader.
+func (mr *MockRenewableAuthenticatorMockRecorder) AddAuthenticationHeader(request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddAuthenticationHeader", reflect.TypeOf((*MockRenewableAuthenticator)(nil).AddAuthenticationHeader), request)
+}
+
+// Authenticate mocks base method.
+func (m *MockRenewableAuthenticator) Authenticate() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockRenewableAuthenticatorMockRecorder) Authenticate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockRenewableAuthenticator)(nil).Authenticate))
+}
+
+// IsSupported mocks base method.
+func (m *MockRenewableAuthenticator) IsSupported() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsSupported")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsSupported indicates an expected call of IsSupported.
+func (mr *MockRenewableAuthenticatorMockRecorder) IsSupported() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsSupported", reflect.TypeOf((*MockRenewableAuthenticator)(nil).IsSupported))
+}
+
+// RenewAuthentication mocks base method.
+func (m *MockRenewableAuthenticator) RenewAuthentication(rejectedRequest *http.Request) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenewAuthentication", rejectedRequest)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RenewAuthentication indicates an expected call of RenewAuthentication.
+func (mr *MockRenewableAuthenticatorMockRecorder) RenewAuthentication(rejectedRequest interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenewAuthentication", reflect.TypeOf((*MockRenewableAuthenticator)(nil).RenewAuthentication), rejectedRequest)
+}
@@ -91,3 +91,96 @@ func (mr *MockStorageMockRecorder) Unlock() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlock", reflect.TypeOf((*MockStorage)(nil).Unlock))
 }
+
+// MockTransactionalStorage is a mock of TransactionalStorage interface.
+type MockTransactionalStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockTransactionalStorageMockRecorder
+}
+
+// MockTransactionalStorageMockRecorder is the mock recorder for MockTransactionalStorage.
+type MockTransactionalStorageMockRecorder struct {
+	mock *MockTransactionalStorage
+}
+
+// NewMockTransactionalStorage creates a new mock instance.
+func NewMockTransactionalStorage(ctrl *gomock.Controller) *MockTransactionalStorage {
+	mock := &MockTransactionalStorage{ctrl: ctrl}
+	mock.recorder = &MockTransactionalStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTransactionalStorage) EXPECT() *MockTransactionalStorageMockRecorder {
+	return m.recorder
+}
+
+// Lock mocks base method.
+func (m *MockTransactionalStorage) Lock(ctx context.Context, retryDelay time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Lock", ctx, retryDelay)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Lock indicates an expected call of Lock.
+func (mr *MockTransactionalStorageMockRecorder) Lock(ctx, retryDelay interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Lock", reflect.TypeOf((*MockTransactionalStorage)(nil).Lock), ctx, retryDelay)
+}
+
+// Refresh mocks base method.
+func (m *MockTransactionalStorage) Refresh(config configuration.Configuration, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Refresh", config, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Refresh indicates an expected call of Refresh.
+func (mr *MockTransactionalStorageMockRecorder) Refresh(config, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refresh", reflect.TypeOf((*MockTransactionalStorage)(nil).Refresh), config, key)
+}
+
+// Set mocks base method.
+func (m *MockTransactionalStorage) Set(key string, value any) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Set", key, value)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Set indicates an expected call of Set.
+func (mr *MockTransactionalStorageMockRecorder) Set(key, value interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockTransactionalStorage)(nil).Set), key, value)
+}
+
+// SetMany mocks base method.
+func (m *MockTransactionalStorage) SetMany(values map[string]any) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMany", values)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetMany indicates an expected call of SetMany.
+func (mr *MockTransactionalStorageMockRecorder) SetMany(values interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMany", reflect.TypeOf((*MockTransactionalStorage)(nil).SetMany), values)
+}
+
+// Unlock mocks base method.
+func (m *MockTransactionalStorage) Unlock() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unlock")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unlock indicates an expected call of Unlock.
+func (mr *MockTransactionalStorageMockRecorder) Unlock() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlock", reflect.TypeOf((*MockTransactionalStorage)(nil).Unlock))
+}
@@ -49,15 +49,51 @@ func (mr *MockConfigurationMockRecorder) AddAlternativeKeys(key, altKeys interfa
 }
 
 // AddDefaultValue mocks base method.
-func (m *MockConfiguration) AddDefaultValue(key string, defaultValue configuration.DefaultValueFunction) {
+func (m *MockConfiguration) AddDefaultValue(key string, defaultValue configuration.DefaultValueFunction, opts ...configuration.DefaultValueOption) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "AddDefaultValue", key, defaultValue)
+	varargs := []interface{}{key, defaultValue}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "AddDefaultValue", varargs...)
 }
 
 // AddDefaultValue indicates an expected call of AddDefaultValue.
-func (mr *MockConfigurationMockRecorder) AddDefaultValue(key, defaultValue interface{}) *gomock.Call {
+func (mr *MockConfigurationMockRecorder) AddDefaultValue(key, defaultValue interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{key, defaultValue}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDefaultValue", reflect.TypeOf((*MockConfiguration)(nil).AddDefaultValue), varargs...)
+}
+
+// AddDependencies mocks base method.
+func (m *MockConfiguration) AddDependencies(key string, dependencies ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{key}
+	for _, a := range dependencies {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "AddDependencies", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddDependencies indicates an expected call of AddDependencies.
+func (mr *MockConfigurationMockRecorder) AddDependencies(key interface{}, dependencies ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{key}, dependencies...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDependencies", reflect.TypeOf((*MockConfiguration)(nil).AddDependencies), varargs...)
+}
+
+// AddEnvVarMapping mocks base method.
+func (m *MockConfiguration) AddEnvVarMapping(envVar, key, description string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AddEnvVarMapping", envVar, key, description)
+}
+
+// AddEnvVarMapping indicates an expected call of AddEnvVarMapping.
+func (mr *MockConfigurationMockRecorder) AddEnvVarMapping(envVar, key, description interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDefaultValue", reflect.TypeOf((*MockConfiguration)(nil).AddDefaultValue), key, defaultValue)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddEnvVarMapping", reflect.TypeOf((*MockConfiguration)(nil).AddEnvVarMapping), envVar, key, description)
 }
 
 // AddFlagSet mocks base method.
@@ -100,6 +136,20 @@ func (mr *MockConfigurationMockRecorder) AutomaticEnv() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AutomaticEnv", reflect.TypeOf((*MockConfiguration)(nil).AutomaticEnv))
 }
 
+// BeginTransaction mocks base method.
+func (m *MockConfiguration) BeginTransaction() configuration.Transaction {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BeginTransaction")
+	ret0, _ := ret[0].(configuration.Transaction)
+	return ret0
+}
+
+// BeginTransaction indicates an expected call of BeginTransaction.
+func (mr *MockConfigurationMockRecorder) BeginTransaction() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginTransaction", reflect.TypeOf((*MockConfiguration)(nil).BeginTransaction))
+}
+
 // Clone mocks base method.
 func (m *MockConfiguration) Clone() configuration.Configuration {
 	m.ctrl.T.Helper()
@@ -114,6 +164,20 @@ func (mr *MockConfigurationMockRecorder) Clone() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Clone", reflect.TypeOf((*MockConfiguration)(nil).Clone))
 }
 
+// DependencyGraph mocks base method.
+func (m *MockConfiguration) DependencyGraph() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DependencyGraph")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// DependencyGraph indicates an expected call of DependencyGraph.
+func (mr *MockConfigurationMockRecorder) DependencyGraph() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DependencyGraph", reflect.TypeOf((*MockConfiguration)(nil).DependencyGraph))
+}
+
 // Get mocks base method.
 func (m *MockConfiguration) Get(key string) interface{} {
 	m.ctrl.T.Helper()
@@ -184,6 +248,34 @@ func (mr *MockConfigurationMockRecorder) GetBool(key interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBool", reflect.TypeOf((*MockConfiguration)(nil).GetBool), key)
 }
 
+// GetDependencies mocks base method.
+func (m *MockConfiguration) GetDependencies(key string) []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDependencies", key)
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// GetDependencies indicates an expected call of GetDependencies.
+func (mr *MockConfigurationMockRecorder) GetDependencies(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDependencies", reflect.TypeOf((*MockConfiguration)(nil).GetDependencies), key)
+}
+
+// GetEnvVarMappings mocks base method.
+func (m *MockConfiguration) GetEnvVarMappings() []configuration.EnvVarMapping {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEnvVarMappings")
+	ret0, _ := ret[0].([]configuration.EnvVarMapping)
+	return ret0
+}
+
+// GetEnvVarMappings indicates an expected call of GetEnvVarMappings.
+func (mr *MockConfigurationMockRecorder) GetEnvVarMappings() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEnvVarMappings", reflect.TypeOf((*MockConfiguration)(nil).GetEnvVarMappings))
+}
+
 // GetFiles mocks base method.
 func (m *MockConfiguration) GetFiles() []string {
 	m.ctrl.T.Helper()
@@ -226,6 +318,21 @@ func (mr *MockConfigurationMockRecorder) GetInt(key interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInt", reflect.TypeOf((*MockConfiguration)(nil).GetInt), key)
 }
 
+// GetKeyForEnvVar mocks base method.
+func (m *MockConfiguration) GetKeyForEnvVar(envVar string) (string, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetKeyForEnvVar", envVar)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// GetKeyForEnvVar indicates an expected call of GetKeyForEnvVar.
+func (mr *MockConfigurationMockRecorder) GetKeyForEnvVar(envVar interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetKeyForEnvVar", reflect.TypeOf((*MockConfiguration)(nil).GetKeyForEnvVar), envVar)
+}
+
 // GetKeyType mocks base method.
 func (m *MockConfiguration) GetKeyType(key string) configuration.KeyType {
 	m.ctrl.T.Helper()
@@ -240,6 +347,34 @@ func (mr *MockConfigurationMockRecorder) GetKeyType(key interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetKeyType", reflect.TypeOf((*MockConfiguration)(nil).GetKeyType), key)
 }
 
+// GetLockedKeys mocks base method.
+func (m *MockConfiguration) GetLockedKeys() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLockedKeys")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// GetLockedKeys indicates an expected call of GetLockedKeys.
+func (mr *MockConfigurationMockRecorder) GetLockedKeys() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLockedKeys", reflect.TypeOf((*MockConfiguration)(nil).GetLockedKeys))
+}
+
+// GetProjectConfigFile mocks base method.
+func (m *MockConfiguration) GetProjectConfigFile() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProjectConfigFile")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetProjectConfigFile indicates an expected call of GetProjectConfigFile.
+func (mr *MockConfigurationMockRecorder) GetProjectConfigFile() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProjectConfigFile", reflect.TypeOf((*MockConfiguration)(nil).GetProjectConfigFile))
+}
+
 // GetStorage mocks base method.
 func (m *MockConfiguration) GetStorage() configuration.Storage {
 	m.ctrl.T.Helper()
@@ -339,6 +474,20 @@ func (mr *MockConfigurationMockRecorder) GetWithError(key interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWithError", reflect.TypeOf((*MockConfiguration)(nil).GetWithError), key)
 }
 
+// IsLocked mocks base method.
+func (m *MockConfiguration) IsLocked(key string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsLocked", key)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsLocked indicates an expected call of IsLocked.
+func (mr *MockConfigurationMockRecorder) IsLocked(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsLocked", reflect.TypeOf((*MockConfiguration)(nil).IsLocked), key)
+}
+
 // IsSet mocks base method.
 func (m *MockConfiguration) IsSet(key string) bool {
 	m.ctrl.T.Helper()
@@ -353,6 +502,23 @@ func (mr *MockConfigurationMockRecorder) IsSet(key interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsSet", reflect.TypeOf((*MockConfiguration)(nil).IsSet), key)
 }
 
+// LockValue mocks base method.
+func (m *MockConfiguration) LockValue(key string, value interface{}, allowedValues ...interface{}) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{key, value}
+	for _, a := range allowedValues {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "LockValue", varargs...)
+}
+
+// LockValue indicates an expected call of LockValue.
+func (mr *MockConfigurationMockRecorder) LockValue(key, value interface{}, allowedValues ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{key, value}, allowedValues...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockValue", reflect.TypeOf((*MockConfiguration)(nil).LockValue), varargs...)
+}
+
 // PersistInStorage mocks base method.
 func (m *MockConfiguration) PersistInStorage(key string) {
 	m.ctrl.T.Helper()
@@ -365,6 +531,18 @@ func (mr *MockConfigurationMockRecorder) PersistInStorage(key interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PersistInStorage", reflect.TypeOf((*MockConfiguration)(nil).PersistInStorage), key)
 }
 
+// PrefetchDefaultValues mocks base method.
+func (m *MockConfiguration) PrefetchDefaultValues() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PrefetchDefaultValues")
+}
+
+// PrefetchDefaultValues indicates an expected call of PrefetchDefaultValues.
+func (mr *MockConfigurationMockRecorder) PrefetchDefaultValues() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PrefetchDefaultValues", reflect.TypeOf((*MockConfiguration)(nil).PrefetchDefaultValues))
+}
+
 // ReloadConfig mocks base method.
 func (m *MockConfiguration) ReloadConfig() error {
 	m.ctrl.T.Helper()
@@ -379,6 +557,20 @@ func (mr *MockConfigurationMockRecorder) ReloadConfig() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReloadConfig", reflect.TypeOf((*MockConfiguration)(nil).ReloadConfig))
 }
 
+// Scope mocks base method.
+func (m *MockConfiguration) Scope(namespace string) configuration.Configuration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Scope", namespace)
+	ret0, _ := ret[0].(configuration.Configuration)
+	return ret0
+}
+
+// Scope indicates an expected call of Scope.
+func (mr *MockConfigurationMockRecorder) Scope(namespace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Scope", reflect.TypeOf((*MockConfiguration)(nil).Scope), namespace)
+}
+
 // Set mocks base method.
 func (m *MockConfiguration) Set(key string, value interface{}) {
 	m.ctrl.T.Helper()
@@ -407,6 +599,20 @@ func (mr *MockConfigurationMockRecorder) SetFiles(files ...interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFiles", reflect.TypeOf((*MockConfiguration)(nil).SetFiles), files...)
 }
 
+// SetProjectConfigFile mocks base method.
+func (m *MockConfiguration) SetProjectConfigFile(file string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetProjectConfigFile", file)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetProjectConfigFile indicates an expected call of SetProjectConfigFile.
+func (mr *MockConfigurationMockRecorder) SetProjectConfigFile(file interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetProjectConfigFile", reflect.TypeOf((*MockConfiguration)(nil).SetProjectConfigFile), file)
+}
+
 // SetStorage mocks base method.
 func (m *MockConfiguration) SetStorage(storage configuration.Storage) {
 	m.ctrl.T.Helper()
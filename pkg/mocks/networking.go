@@ -13,6 +13,7 @@ import (
 	auth "github.com/snyk/go-application-framework/pkg/auth"
 	configuration "github.com/snyk/go-application-framework/pkg/configuration"
 	networking "github.com/snyk/go-application-framework/pkg/networking"
+	middleware "github.com/snyk/go-application-framework/pkg/networking/middleware"
 	networktypes "github.com/snyk/go-application-framework/pkg/networking/network_types"
 )
 
@@ -63,6 +64,18 @@ func (mr *MockNetworkAccessMockRecorder) AddErrorHandler(arg0 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddErrorHandler", reflect.TypeOf((*MockNetworkAccess)(nil).AddErrorHandler), arg0)
 }
 
+// AddErrorMapper mocks base method.
+func (m *MockNetworkAccess) AddErrorMapper(pathPrefix string, mapper middleware.ErrorMapperFunc) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AddErrorMapper", pathPrefix, mapper)
+}
+
+// AddErrorMapper indicates an expected call of AddErrorMapper.
+func (mr *MockNetworkAccessMockRecorder) AddErrorMapper(pathPrefix, mapper interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddErrorMapper", reflect.TypeOf((*MockNetworkAccess)(nil).AddErrorMapper), pathPrefix, mapper)
+}
+
 // AddHeaderField mocks base method.
 func (m *MockNetworkAccess) AddHeaderField(key, value string) {
 	m.ctrl.T.Helper()
@@ -89,6 +102,54 @@ func (mr *MockNetworkAccessMockRecorder) AddHeaders(request interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddHeaders", reflect.TypeOf((*MockNetworkAccess)(nil).AddHeaders), request)
 }
 
+// AddMiddleware mocks base method.
+func (m *MockNetworkAccess) AddMiddleware(middlewareFunc networking.MiddlewareFunc) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AddMiddleware", middlewareFunc)
+}
+
+// AddMiddleware indicates an expected call of AddMiddleware.
+func (mr *MockNetworkAccessMockRecorder) AddMiddleware(middlewareFunc interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddMiddleware", reflect.TypeOf((*MockNetworkAccess)(nil).AddMiddleware), middlewareFunc)
+}
+
+// AddRateLimit mocks base method.
+func (m *MockNetworkAccess) AddRateLimit(prefix string, requestsPerSecond float64, burst int) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AddRateLimit", prefix, requestsPerSecond, burst)
+}
+
+// AddRateLimit indicates an expected call of AddRateLimit.
+func (mr *MockNetworkAccessMockRecorder) AddRateLimit(prefix, requestsPerSecond, burst interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRateLimit", reflect.TypeOf((*MockNetworkAccess)(nil).AddRateLimit), prefix, requestsPerSecond, burst)
+}
+
+// AddRequestHook mocks base method.
+func (m *MockNetworkAccess) AddRequestHook(hook networking.RequestHookFunc) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AddRequestHook", hook)
+}
+
+// AddRequestHook indicates an expected call of AddRequestHook.
+func (mr *MockNetworkAccessMockRecorder) AddRequestHook(hook interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRequestHook", reflect.TypeOf((*MockNetworkAccess)(nil).AddRequestHook), hook)
+}
+
+// AddResponseHook mocks base method.
+func (m *MockNetworkAccess) AddResponseHook(hook networking.ResponseHookFunc) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AddResponseHook", hook)
+}
+
+// AddResponseHook indicates an expected call of AddResponseHook.
+func (mr *MockNetworkAccessMockRecorder) AddResponseHook(hook interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddResponseHook", reflect.TypeOf((*MockNetworkAccess)(nil).AddResponseHook), hook)
+}
+
 // AddRootCAs mocks base method.
 func (m *MockNetworkAccess) AddRootCAs(pemFileLocation string) error {
 	m.ctrl.T.Helper()
@@ -131,6 +192,20 @@ func (mr *MockNetworkAccessMockRecorder) GetAuthenticator() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuthenticator", reflect.TypeOf((*MockNetworkAccess)(nil).GetAuthenticator))
 }
 
+// GetCircuitBreakerStatus mocks base method.
+func (m *MockNetworkAccess) GetCircuitBreakerStatus() []middleware.CircuitStatus {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCircuitBreakerStatus")
+	ret0, _ := ret[0].([]middleware.CircuitStatus)
+	return ret0
+}
+
+// GetCircuitBreakerStatus indicates an expected call of GetCircuitBreakerStatus.
+func (mr *MockNetworkAccessMockRecorder) GetCircuitBreakerStatus() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCircuitBreakerStatus", reflect.TypeOf((*MockNetworkAccess)(nil).GetCircuitBreakerStatus))
+}
+
 // GetConfiguration mocks base method.
 func (m *MockNetworkAccess) GetConfiguration() configuration.Configuration {
 	m.ctrl.T.Helper()
@@ -145,6 +220,20 @@ func (mr *MockNetworkAccessMockRecorder) GetConfiguration() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConfiguration", reflect.TypeOf((*MockNetworkAccess)(nil).GetConfiguration))
 }
 
+// GetCorrelationId mocks base method.
+func (m *MockNetworkAccess) GetCorrelationId() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCorrelationId")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetCorrelationId indicates an expected call of GetCorrelationId.
+func (mr *MockNetworkAccessMockRecorder) GetCorrelationId() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCorrelationId", reflect.TypeOf((*MockNetworkAccess)(nil).GetCorrelationId))
+}
+
 // GetErrorHandler mocks base method.
 func (m *MockNetworkAccess) GetErrorHandler() networktypes.ErrorHandlerFunc {
 	m.ctrl.T.Helper()
@@ -227,6 +316,18 @@ func (mr *MockNetworkAccessMockRecorder) SetConfiguration(configuration interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetConfiguration", reflect.TypeOf((*MockNetworkAccess)(nil).SetConfiguration), configuration)
 }
 
+// SetCorrelationId mocks base method.
+func (m *MockNetworkAccess) SetCorrelationId(correlationId string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetCorrelationId", correlationId)
+}
+
+// SetCorrelationId indicates an expected call of SetCorrelationId.
+func (mr *MockNetworkAccessMockRecorder) SetCorrelationId(correlationId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCorrelationId", reflect.TypeOf((*MockNetworkAccess)(nil).SetCorrelationId), correlationId)
+}
+
 // SetLogger mocks base method.
 func (m *MockNetworkAccess) SetLogger(logger *zerolog.Logger) {
 	m.ctrl.T.Helper()
@@ -238,3 +339,15 @@ func (mr *MockNetworkAccessMockRecorder) SetLogger(logger interface{}) *gomock.C
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLogger", reflect.TypeOf((*MockNetworkAccess)(nil).SetLogger), logger)
 }
+
+// SetPacEvaluatorFactory mocks base method.
+func (m *MockNetworkAccess) SetPacEvaluatorFactory(factory middleware.PacEvaluatorFactory) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPacEvaluatorFactory", factory)
+}
+
+// SetPacEvaluatorFactory indicates an expected call of SetPacEvaluatorFactory.
+func (mr *MockNetworkAccessMockRecorder) SetPacEvaluatorFactory(factory interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPacEvaluatorFactory", reflect.TypeOf((*MockNetworkAccess)(nil).SetPacEvaluatorFactory), factory)
+}
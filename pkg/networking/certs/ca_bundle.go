@@ -0,0 +1,169 @@
+package certs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// caBundleExtensions are the file extensions considered when loading certificates from a directory.
+var caBundleExtensions = []string{".pem", ".crt", ".cer"}
+
+// CertificateError describes why a single certificate of a CA bundle couldn't be used. Index is the position of the
+// PEM block within the file, starting at 1, or 0 if the file itself couldn't be read.
+type CertificateError struct {
+	Location string
+	Index    int
+	Err      error
+}
+
+func (e *CertificateError) Error() string {
+	if e.Index == 0 {
+		return fmt.Sprintf("%s: %v", e.Location, e.Err)
+	}
+	return fmt.Sprintf("%s: certificate %d: %v", e.Location, e.Index, e.Err)
+}
+
+func (e *CertificateError) Unwrap() error {
+	return e.Err
+}
+
+// LoadCertificates loads the certificates of the given PEM files and directories. Directories are not searched
+// recursively, only files with the extension .pem, .crt or .cer are loaded from them. Certificates that can't be
+// parsed are skipped and reported as *CertificateError, joined into the returned error, while all valid certificates
+// are returned.
+func LoadCertificates(locations ...string) ([]*x509.Certificate, error) {
+	result := []*x509.Certificate{}
+	errs := []error{}
+
+	for _, location := range locations {
+		files, err := getCaBundleFiles(location)
+		if err != nil {
+			errs = append(errs, &CertificateError{Location: location, Err: err})
+			continue
+		}
+
+		for _, file := range files {
+			pemData, readErr := os.ReadFile(file)
+			if readErr != nil {
+				errs = append(errs, &CertificateError{Location: file, Err: readErr})
+				continue
+			}
+
+			certificates, parseErrs := parseCertificates(file, pemData)
+			result = append(result, certificates...)
+			errs = append(errs, parseErrs...)
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// ParseCertificates decodes all certificates of the PEM formatted data. In contrast to GetAllCerts, invalid blocks
+// are skipped and reported individually, location is only used in the errors.
+func ParseCertificates(location string, pemData []byte) ([]*x509.Certificate, error) {
+	certificates, errs := parseCertificates(location, pemData)
+	return certificates, errors.Join(errs...)
+}
+
+func parseCertificates(location string, pemData []byte) ([]*x509.Certificate, []error) {
+	result := []*x509.Certificate{}
+	errs := []error{}
+
+	for index := 1; ; index++ {
+		var block *pem.Block
+		block, pemData = pem.Decode(pemData)
+		if block == nil {
+			break
+		}
+
+		if !strings.EqualFold(block.Type, "certificate") {
+			errs = append(errs, &CertificateError{Location: location, Index: index, Err: fmt.Errorf("unexpected PEM block type %q", block.Type)})
+			continue
+		}
+
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			errs = append(errs, &CertificateError{Location: location, Index: index, Err: err})
+			continue
+		}
+		result = append(result, certificate)
+	}
+
+	if len(bytes.TrimSpace(pemData)) > 0 || (len(result) == 0 && len(errs) == 0) {
+		errs = append(errs, &CertificateError{Location: location, Err: fmt.Errorf("data contains non certificate")})
+	}
+
+	return result, errs
+}
+
+func getCaBundleFiles(location string) ([]string, error) {
+	info, err := os.Stat(location)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{location}, nil
+	}
+
+	entries, err := os.ReadDir(location)
+	if err != nil {
+		return nil, err
+	}
+
+	// ReadDir returns the entries sorted by name, which keeps the order of the certificates stable
+	files := []string{}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && slices.Contains(caBundleExtensions, strings.ToLower(filepath.Ext(entry.Name()))) {
+			files = append(files, filepath.Join(location, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// SpkiHash returns the base64 encoded SHA-256 hash of the certificate's subject public key info, the format used for
+// public key pinning, e.g. by `openssl x509 -pubkey | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64`.
+func SpkiHash(certificate *x509.Certificate) string {
+	hash := sha256.Sum256(certificate.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// NewSpkiPinVerifier returns a function for tls.Config.VerifyConnection, which requires connections to the given host
+// to present at least one certificate, leaf or CA, matching one of the pins. Pins are SpkiHash values, optionally
+// prefixed with "sha256/". Connections to other hosts are not affected. The host is identified by the TLS server name,
+// which isn't sent for IP addresses, so if host is an IP address, all connections without server name are verified.
+func NewSpkiPinVerifier(host string, pins []string) func(tls.ConnectionState) error {
+	normalizedPins := make([]string, 0, len(pins))
+	for _, pin := range pins {
+		normalizedPins = append(normalizedPins, strings.TrimPrefix(strings.TrimSpace(pin), "sha256/"))
+	}
+
+	serverName := host
+	if net.ParseIP(host) != nil {
+		serverName = ""
+	}
+
+	return func(state tls.ConnectionState) error {
+		if !strings.EqualFold(state.ServerName, serverName) {
+			return nil
+		}
+
+		for _, certificate := range state.PeerCertificates {
+			if slices.Contains(normalizedPins, SpkiHash(certificate)) {
+				return nil
+			}
+		}
+		return fmt.Errorf("no certificate presented by %s matches the configured public key pins", host)
+	}
+}
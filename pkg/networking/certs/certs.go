@@ -135,19 +135,12 @@ func GetAllCerts(pemData []byte) ([]*x509.Certificate, error) {
 	return result, nil
 }
 
-// Get global Certificate pool including x509.SystemCertPool() + extraCertificateLocation
+// Add the certificates specified via extraCertificateLocation, a PEM file or a directory of PEM files, to the given pool.
+// Valid certificates are added even if others can't be parsed, the returned error lists each of the failures.
 func AddCertificatesToPool(pool *x509.CertPool, extraCertificateLocation string) error {
-	_, extracCertList, err := GetExtraCaCert(extraCertificateLocation)
-	if err != nil {
-		return err
+	certificates, err := LoadCertificates(extraCertificateLocation)
+	for _, currentCert := range certificates {
+		pool.AddCert(currentCert)
 	}
-
-	// append extra ca certificates if specified
-	for _, currentCert := range extracCertList {
-		if currentCert != nil {
-			pool.AddCert(currentCert)
-		}
-	}
-
 	return err
 }
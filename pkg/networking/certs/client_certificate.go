@@ -0,0 +1,237 @@
+package certs
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1" //nolint:gosec // SHA1 is the default PRF of PBKDF2 and required to decrypt older keys
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"hash"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+var (
+	oidPbes2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPbkdf2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHmacWithSha1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHmacWithSha256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHmacWithSha384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHmacWithSha512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAes128Cbc      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAes192Cbc      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAes256Cbc      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	Prf            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// DecryptPKCS8PrivateKey decrypts the DER content of an "ENCRYPTED PRIVATE KEY" PEM block. Only PBES2 with PBKDF2
+// and AES-CBC is supported, which is the default of current OpenSSL versions.
+func DecryptPKCS8PrivateKey(der []byte, password []byte) (any, error) {
+	info := encryptedPrivateKeyInfo{}
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("invalid encrypted private key: %w", err)
+	}
+
+	if !info.Algorithm.Algorithm.Equal(oidPbes2) {
+		return nil, fmt.Errorf("unsupported private key encryption %s, only PBES2 is supported", info.Algorithm.Algorithm)
+	}
+
+	params := pbes2Params{}
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("invalid PBES2 parameters: %w", err)
+	}
+
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPbkdf2) {
+		return nil, fmt.Errorf("unsupported key derivation function %s", params.KeyDerivationFunc.Algorithm)
+	}
+
+	kdfParams := pbkdf2Params{}
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams); err != nil {
+		return nil, fmt.Errorf("invalid PBKDF2 parameters: %w", err)
+	}
+
+	var prf func() hash.Hash
+	switch {
+	case len(kdfParams.Prf.Algorithm) == 0 || kdfParams.Prf.Algorithm.Equal(oidHmacWithSha1):
+		prf = sha1.New
+	case kdfParams.Prf.Algorithm.Equal(oidHmacWithSha256):
+		prf = sha256.New
+	case kdfParams.Prf.Algorithm.Equal(oidHmacWithSha384):
+		prf = sha512.New384
+	case kdfParams.Prf.Algorithm.Equal(oidHmacWithSha512):
+		prf = sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported PBKDF2 function %s", kdfParams.Prf.Algorithm)
+	}
+
+	var keyLength int
+	switch {
+	case params.EncryptionScheme.Algorithm.Equal(oidAes128Cbc):
+		keyLength = 16
+	case params.EncryptionScheme.Algorithm.Equal(oidAes192Cbc):
+		keyLength = 24
+	case params.EncryptionScheme.Algorithm.Equal(oidAes256Cbc):
+		keyLength = 32
+	default:
+		return nil, fmt.Errorf("unsupported private key cipher %s", params.EncryptionScheme.Algorithm)
+	}
+
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, fmt.Errorf("invalid cipher parameters: %w", err)
+	}
+
+	key := pbkdf2.Key(password, kdfParams.Salt, kdfParams.IterationCount, keyLength, prf)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(iv) != block.BlockSize() || len(info.EncryptedData) == 0 || len(info.EncryptedData)%block.BlockSize() != 0 {
+		return nil, fmt.Errorf("invalid encrypted private key data")
+	}
+
+	decrypted := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, info.EncryptedData)
+
+	// remove the PKCS#7 padding, invalid padding usually indicates a wrong password
+	padding := int(decrypted[len(decrypted)-1])
+	if padding == 0 || padding > block.BlockSize() || !bytes.Equal(decrypted[len(decrypted)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, fmt.Errorf("failed to decrypt private key, the password might be wrong")
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(decrypted[:len(decrypted)-padding])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt private key, the password might be wrong")
+	}
+	return privateKey, nil
+}
+
+// LoadClientCertificate loads a PEM encoded certificate chain and private key. The key can be an unencrypted PKCS#1,
+// PKCS#8 or EC key or an encrypted PKCS#8 key, which requires the password.
+func LoadClientCertificate(certificateFile string, keyFile string, password string) (tls.Certificate, error) {
+	certificatePem, err := os.ReadFile(certificateFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	keyPem, err := os.ReadFile(keyFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	block, _ := pem.Decode(keyPem)
+	if block != nil && block.Type == "ENCRYPTED PRIVATE KEY" {
+		if len(password) == 0 {
+			return tls.Certificate{}, fmt.Errorf("the private key %s is encrypted, but no password is configured", keyFile)
+		}
+
+		privateKey, decryptErr := DecryptPKCS8PrivateKey(block.Bytes, []byte(password))
+		if decryptErr != nil {
+			return tls.Certificate{}, decryptErr
+		}
+
+		der, marshalErr := x509.MarshalPKCS8PrivateKey(privateKey)
+		if marshalErr != nil {
+			return tls.Certificate{}, marshalErr
+		}
+		keyPem = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	}
+
+	certificate, err := tls.X509KeyPair(certificatePem, keyPem)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	// parse the leaf, e.g. to report subject and expiry
+	if certificate.Leaf == nil && len(certificate.Certificate) > 0 {
+		certificate.Leaf, err = x509.ParseCertificate(certificate.Certificate[0])
+	}
+	return certificate, err
+}
+
+// ClientCertificateLoader provides a client certificate for TLS handshakes and reloads it whenever the certificate or
+// key file changed, e.g. after a rotation. It is safe for concurrent use.
+type ClientCertificateLoader struct {
+	mutex           sync.Mutex
+	certificateFile string
+	keyFile         string
+	password        string
+	certificate     *tls.Certificate
+	modTimes        [2]time.Time
+}
+
+func NewClientCertificateLoader(certificateFile string, keyFile string, password string) *ClientCertificateLoader {
+	return &ClientCertificateLoader{
+		certificateFile: certificateFile,
+		keyFile:         keyFile,
+		password:        password,
+	}
+}
+
+// GetCertificate returns the current certificate. If the files changed but can't be loaded, e.g. while they are
+// being replaced, the previous certificate is returned.
+func (l *ClientCertificateLoader) GetCertificate() (*tls.Certificate, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	modTimes, statErr := l.getModTimes()
+	if l.certificate != nil && (statErr != nil || modTimes == l.modTimes) {
+		return l.certificate, nil
+	}
+
+	certificate, err := LoadClientCertificate(l.certificateFile, l.keyFile, l.password)
+	if err != nil {
+		if l.certificate != nil {
+			return l.certificate, nil
+		}
+		return nil, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+
+	l.certificate = &certificate
+	l.modTimes = modTimes
+	return l.certificate, nil
+}
+
+// GetClientCertificate can be used as tls.Config.GetClientCertificate.
+func (l *ClientCertificateLoader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return l.GetCertificate()
+}
+
+func (l *ClientCertificateLoader) getModTimes() ([2]time.Time, error) {
+	result := [2]time.Time{}
+	for i, file := range []string{l.certificateFile, l.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return result, err
+		}
+		result[i] = info.ModTime()
+	}
+	return result, nil
+}
@@ -0,0 +1,152 @@
+package certs
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// encryptPKCS8PrivateKey encrypts a "PRIVATE KEY" pem block like `openssl pkcs8 -topk8 -v2 aes-256-cbc` does.
+func encryptPKCS8PrivateKey(t *testing.T, keyPem []byte, password string) []byte {
+	t.Helper()
+	block, _ := pem.Decode(keyPem)
+	require.NotNil(t, block)
+
+	der := block.Bytes
+	if block.Type == "RSA PRIVATE KEY" {
+		key, err := x509.ParsePKCS1PrivateKey(der)
+		require.NoError(t, err)
+		der, err = x509.MarshalPKCS8PrivateKey(key)
+		require.NoError(t, err)
+	}
+
+	salt := make([]byte, 16)
+	iv := make([]byte, aes.BlockSize)
+	_, err := rand.Read(salt)
+	require.NoError(t, err)
+	_, err = rand.Read(iv)
+	require.NoError(t, err)
+
+	padding := aes.BlockSize - len(der)%aes.BlockSize
+	for i := 0; i < padding; i++ {
+		der = append(der, byte(padding))
+	}
+
+	aesCipher, err := aes.NewCipher(pbkdf2.Key([]byte(password), salt, 2048, 32, sha256.New))
+	require.NoError(t, err)
+	encrypted := make([]byte, len(der))
+	cipher.NewCBCEncrypter(aesCipher, iv).CryptBlocks(encrypted, der)
+
+	marshal := func(value any) asn1.RawValue {
+		data, marshalErr := asn1.Marshal(value)
+		require.NoError(t, marshalErr)
+		return asn1.RawValue{FullBytes: data}
+	}
+
+	kdfParams := marshal(pbkdf2Params{Salt: salt, IterationCount: 2048, Prf: pkix.AlgorithmIdentifier{Algorithm: oidHmacWithSha256, Parameters: asn1.NullRawValue}})
+	params := marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPbkdf2, Parameters: kdfParams},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAes256Cbc, Parameters: marshal(iv)},
+	})
+	info := marshal(encryptedPrivateKeyInfo{Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidPbes2, Parameters: params}, EncryptedData: encrypted})
+	return pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: info.FullBytes})
+}
+
+func writeClientCertificate(t *testing.T, dir string, name string, password string) (string, string) {
+	t.Helper()
+	certPem, keyPem, err := MakeSelfSignedCert(name, []string{"localhost"}, log.Default())
+	require.NoError(t, err)
+
+	if len(password) > 0 {
+		keyPem = encryptPKCS8PrivateKey(t, keyPem, password)
+	}
+
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	require.NoError(t, os.WriteFile(certFile, certPem, 0o600))
+	require.NoError(t, os.WriteFile(keyFile, keyPem, 0o600))
+	return certFile, keyFile
+}
+
+func Test_LoadClientCertificate_Unencrypted(t *testing.T) {
+	certFile, keyFile := writeClientCertificate(t, t.TempDir(), "client", "")
+
+	certificate, err := LoadClientCertificate(certFile, keyFile, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "client", certificate.Leaf.Subject.CommonName)
+}
+
+func Test_LoadClientCertificate_Encrypted(t *testing.T) {
+	certFile, keyFile := writeClientCertificate(t, t.TempDir(), "client", "secret")
+
+	t.Run("correct password", func(t *testing.T) {
+		certificate, err := LoadClientCertificate(certFile, keyFile, "secret")
+		assert.NoError(t, err)
+		assert.NotNil(t, certificate.PrivateKey)
+		assert.Equal(t, "client", certificate.Leaf.Subject.CommonName)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		_, err := LoadClientCertificate(certFile, keyFile, "wrong")
+		assert.ErrorContains(t, err, "password might be wrong")
+	})
+
+	t.Run("missing password", func(t *testing.T) {
+		_, err := LoadClientCertificate(certFile, keyFile, "")
+		assert.ErrorContains(t, err, "no password is configured")
+	})
+}
+
+func Test_LoadClientCertificate_MissingFile(t *testing.T) {
+	_, err := LoadClientCertificate(filepath.Join(t.TempDir(), "missing.crt"), filepath.Join(t.TempDir(), "missing.key"), "")
+	assert.Error(t, err)
+}
+
+func Test_ClientCertificateLoader_ReloadsChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeClientCertificate(t, dir, "first", "")
+	loader := NewClientCertificateLoader(certFile, keyFile, "")
+
+	certificate, err := loader.GetCertificate()
+	require.NoError(t, err)
+	assert.Equal(t, "first", certificate.Leaf.Subject.CommonName)
+
+	// rotate the certificate, the modification time is set explicitly as file systems might have a coarse resolution
+	writeClientCertificate(t, dir, "second", "")
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	require.NoError(t, os.Chtimes(keyFile, later, later))
+
+	certificate, err = loader.GetClientCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "second", certificate.Leaf.Subject.CommonName)
+
+	// a broken file keeps the previous certificate
+	require.NoError(t, os.WriteFile(keyFile, []byte("invalid"), 0o600))
+	evenLater := later.Add(time.Minute)
+	require.NoError(t, os.Chtimes(keyFile, evenLater, evenLater))
+
+	certificate, err = loader.GetCertificate()
+	require.NoError(t, err)
+	assert.Equal(t, "second", certificate.Leaf.Subject.CommonName)
+}
+
+func Test_ClientCertificateLoader_FailsWithoutCertificate(t *testing.T) {
+	loader := NewClientCertificateLoader(filepath.Join(t.TempDir(), "missing.crt"), filepath.Join(t.TempDir(), "missing.key"), "")
+	_, err := loader.GetCertificate()
+	assert.ErrorContains(t, err, "failed to load client certificate")
+}
@@ -0,0 +1,45 @@
+package networking
+
+import (
+	"sync"
+
+	"github.com/snyk/go-application-framework/pkg/configuration"
+	"github.com/snyk/go-application-framework/pkg/networking/certs"
+)
+
+// clientCertificateProvider caches the loader for the configured client certificate, so that the certificate is only
+// reloaded if its files change. It is shared by all clones of a NetworkAccess.
+type clientCertificateProvider struct {
+	mutex     sync.Mutex
+	loaderKey string
+	loader    *certs.ClientCertificateLoader
+}
+
+// getLoader returns the loader for the given files, it is only recreated if the configuration changed.
+func (p *clientCertificateProvider) getLoader(certificateFile string, keyFile string, password string) *certs.ClientCertificateLoader {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	key := certificateFile + "\n" + keyFile + "\n" + password
+	if p.loader == nil || p.loaderKey != key {
+		p.loader = certs.NewClientCertificateLoader(certificateFile, keyFile, password)
+		p.loaderKey = key
+	}
+	return p.loader
+}
+
+// getClientCertificateLoader returns the loader of the certificate configured via TLS_CLIENT_CERTIFICATE_FILE and
+// TLS_CLIENT_KEY_FILE, or nil if no client certificate is configured.
+func (n *networkImpl) getClientCertificateLoader() *certs.ClientCertificateLoader {
+	certificateFile := n.config.GetString(configuration.TLS_CLIENT_CERTIFICATE_FILE)
+	if len(certificateFile) == 0 {
+		return nil
+	}
+
+	keyFile := n.config.GetString(configuration.TLS_CLIENT_KEY_FILE)
+	loader := n.clientCertificates.getLoader(certificateFile, keyFile, n.config.GetString(configuration.TLS_CLIENT_KEY_PASSWORD))
+	if _, err := loader.GetCertificate(); err != nil {
+		n.logger.Printf("Failed to load client certificate (%v)", err)
+	}
+	return loader
+}
@@ -0,0 +1,75 @@
+package networking
+
+import (
+	"net/http"
+)
+
+// RequestHookFunc is called before a request is sent, it can modify the request, e.g. to sign it. Returning an error
+// fails the request without sending it.
+type RequestHookFunc func(request *http.Request) error
+
+// ResponseHookFunc is called for each received response, it can inspect or modify the response. Returning an error
+// fails the request, the response body is closed in that case.
+type ResponseHookFunc func(response *http.Response) error
+
+// MiddlewareFunc wraps the given http.RoundTripper, e.g. to add telemetry or to rewrite requests of an endpoint.
+type MiddlewareFunc func(next http.RoundTripper) http.RoundTripper
+
+// hooks holds the request hooks, response hooks and middlewares registered by extensions.
+type hooks struct {
+	requestHooks  []RequestHookFunc
+	responseHooks []ResponseHookFunc
+	middlewares   []MiddlewareFunc
+}
+
+func (h hooks) clone() hooks {
+	return hooks{
+		requestHooks:  append([]RequestHookFunc{}, h.requestHooks...),
+		responseHooks: append([]ResponseHookFunc{}, h.responseHooks...),
+		middlewares:   append([]MiddlewareFunc{}, h.middlewares...),
+	}
+}
+
+// wrap applies the middlewares and hooks to the round tripper. The first registered middleware is the outermost one,
+// request and response hooks are called in the order of their registration, after all middlewares.
+func (h hooks) wrap(roundTripper http.RoundTripper) http.RoundTripper {
+	if len(h.requestHooks) > 0 || len(h.responseHooks) > 0 {
+		roundTripper = &hooksRoundTripper{next: roundTripper, hooks: h}
+	}
+
+	for i := len(h.middlewares) - 1; i >= 0; i-- {
+		roundTripper = h.middlewares[i](roundTripper)
+	}
+	return roundTripper
+}
+
+// hooksRoundTripper calls the request and response hooks.
+type hooksRoundTripper struct {
+	next  http.RoundTripper
+	hooks hooks
+}
+
+func (rt *hooksRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	if len(rt.hooks.requestHooks) > 0 {
+		// a round tripper must not modify the original request
+		request = request.Clone(request.Context())
+		for _, hook := range rt.hooks.requestHooks {
+			if err := hook(request); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	response, err := rt.next.RoundTrip(request)
+	if err != nil {
+		return response, err
+	}
+
+	for _, hook := range rt.hooks.responseHooks {
+		if hookErr := hook(response); hookErr != nil {
+			_ = response.Body.Close() //nolint:errcheck // the hook error is more relevant
+			return nil, hookErr
+		}
+	}
+	return response, nil
+}
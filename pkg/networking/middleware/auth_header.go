@@ -42,7 +42,39 @@ func (n *AuthHeaderMiddleware) RoundTrip(request *http.Request) (*http.Response,
 		return nil, err
 	}
 
-	return n.next.RoundTrip(newRequest)
+	response, err := n.next.RoundTrip(newRequest)
+	if err != nil || response.StatusCode != http.StatusUnauthorized || len(newRequest.Header.Get("Authorization")) == 0 {
+		return response, err
+	}
+
+	return n.retryWithRenewedAuthentication(request, newRequest, response)
+}
+
+// retryWithRenewedAuthentication sends a request that was rejected with 401 Unauthorized once more after renewing
+// its credentials, e.g. if the token was revoked before its expiry. The response is returned unchanged if the
+// credentials can't be renewed or the request body can't be rewound.
+func (n *AuthHeaderMiddleware) retryWithRenewedAuthentication(request *http.Request, rejectedRequest *http.Request, response *http.Response) (*http.Response, error) {
+	renewableAuthenticator, ok := n.authenticator.(auth.RenewableAuthenticator)
+	hasBody := request.Body != nil && request.Body != http.NoBody
+	if !ok || (hasBody && request.GetBody == nil) {
+		return response, nil
+	}
+
+	if err := renewableAuthenticator.RenewAuthentication(rejectedRequest); err != nil {
+		return response, nil //nolint:nilerr // the rejection is more relevant than the failed renewal
+	}
+
+	retryRequest, err := rewindRequest(request, 2)
+	if err != nil {
+		return response, nil //nolint:nilerr // the rejection is more relevant than the failed rewind
+	}
+	retryRequest = retryRequest.Clone(retryRequest.Context())
+	if err = AddAuthenticationHeader(n.authenticator, n.config, retryRequest); err != nil {
+		return response, nil //nolint:nilerr // the rejection is more relevant than the failed renewal
+	}
+
+	_ = response.Body.Close() //nolint:errcheck // the response is replaced by the retried one
+	return n.next.RoundTrip(retryRequest)
 }
 
 func ShouldRequireAuthentication(
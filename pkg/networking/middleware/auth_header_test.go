@@ -1,15 +1,23 @@
 package middleware_test
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
 
 	"github.com/snyk/go-application-framework/internal/api"
+	"github.com/snyk/go-application-framework/pkg/auth"
 	"github.com/snyk/go-application-framework/pkg/configuration"
 	"github.com/snyk/go-application-framework/pkg/mocks"
 	"github.com/snyk/go-application-framework/pkg/networking/middleware"
@@ -123,3 +131,139 @@ func TestAuthenticationError_Is(t *testing.T) {
 	assert.ErrorIs(t, err, middleware.ErrAuthenticationFailed)
 	assert.ErrorContains(t, err, "nope")
 }
+
+func Test_AuthHeaderMiddleware_RetriesOnceAfterRenewal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	token := "rejected"
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		bodies = append(bodies, string(body))
+		if r.Header.Get("Authorization") == "token rejected" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := configuration.NewInMemory()
+	config.Set(configuration.API_URL, server.URL)
+
+	authenticator := mocks.NewMockRenewableAuthenticator(ctrl)
+	authenticator.EXPECT().AddAuthenticationHeader(gomock.Any()).DoAndReturn(func(request *http.Request) error {
+		request.Header.Set("Authorization", "token "+token)
+		return nil
+	}).AnyTimes()
+	authenticator.EXPECT().RenewAuthentication(gomock.Any()).DoAndReturn(func(rejectedRequest *http.Request) error {
+		assert.Equal(t, "token rejected", rejectedRequest.Header.Get("Authorization"))
+		token = "renewed"
+		return nil
+	}).Times(1)
+
+	client := &http.Client{Transport: middleware.NewAuthHeaderMiddleware(config, authenticator, http.DefaultTransport)}
+
+	// case: the request is retried with the renewed token and its body
+	response, err := client.Post(server.URL, "text/plain", strings.NewReader("payload"))
+	assert.NoError(t, err)
+	_ = response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, []string{"payload", "payload"}, bodies)
+
+	// case: a request rejected after renewal is not retried again
+	bodies = nil
+	token = "rejected"
+	authenticator.EXPECT().RenewAuthentication(gomock.Any()).Return(nil).Times(1)
+	response, err = client.Get(server.URL)
+	assert.NoError(t, err)
+	_ = response.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	assert.Len(t, bodies, 2)
+
+	// case: failed renewals return the original response
+	bodies = nil
+	authenticator.EXPECT().RenewAuthentication(gomock.Any()).Return(fmt.Errorf("renewal failed")).Times(1)
+	response, err = client.Get(server.URL)
+	assert.NoError(t, err)
+	_ = response.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	assert.Len(t, bodies, 1)
+}
+
+func Test_AuthHeaderMiddleware_RenewsRejectedOAuthTokens(t *testing.T) {
+	renewals := 0
+	failRenewals := false
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/token" {
+			renewals++
+			if failRenewals {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprint(w, `{"access_token":"renewed","token_type":"Bearer","refresh_token":"refresh","expires_in":3600}`)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		requests = append(requests, strings.TrimSpace(r.Header.Get("Authorization")+" "+string(body)))
+		if r.URL.Path == "/rest/forbidden" || r.Header.Get("Authorization") != "Bearer renewed" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	newClient := func(t *testing.T) *http.Client {
+		t.Helper()
+		renewals = 0
+		requests = nil
+		revokedToken, err := json.Marshal(oauth2.Token{AccessToken: "revoked", TokenType: "Bearer", RefreshToken: "refresh", Expiry: time.Now().Add(time.Hour)})
+		require.NoError(t, err)
+		config := configuration.NewInMemory()
+		config.Set(configuration.API_URL, server.URL)
+		config.Set(auth.CONFIG_KEY_OAUTH_TOKEN, string(revokedToken))
+		return &http.Client{Transport: middleware.NewAuthHeaderMiddleware(config, auth.NewOAuth2AuthenticatorWithOpts(config), http.DefaultTransport)}
+	}
+
+	t.Run("retries with the renewed token and the rewound body", func(t *testing.T) {
+		failRenewals = false
+		client := newClient(t)
+
+		response, err := client.Post(server.URL+"/rest/resource", "text/plain", strings.NewReader("payload"))
+		require.NoError(t, err)
+		_ = response.Body.Close()
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, []string{"Bearer revoked payload", "Bearer renewed payload"}, requests)
+		assert.Equal(t, 1, renewals)
+
+		// requests rejected right after the renewal don't renew the token again
+		requests = nil
+		for range 3 {
+			response, err = client.Get(server.URL + "/rest/forbidden")
+			require.NoError(t, err)
+			_ = response.Body.Close()
+			assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+		}
+		assert.Equal(t, []string{"Bearer renewed", "Bearer renewed", "Bearer renewed"}, requests)
+		assert.Equal(t, 1, renewals)
+	})
+
+	t.Run("backs off after a failed renewal", func(t *testing.T) {
+		failRenewals = true
+		client := newClient(t)
+
+		for range 3 {
+			response, err := client.Get(server.URL + "/rest/resource")
+			require.NoError(t, err)
+			_ = response.Body.Close()
+			assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+		}
+		assert.Equal(t, []string{"Bearer revoked", "Bearer revoked", "Bearer revoked"}, requests)
+		assert.Equal(t, 1, renewals)
+	})
+}
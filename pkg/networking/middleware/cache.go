@@ -0,0 +1,373 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/snyk/go-application-framework/internal/utils"
+)
+
+// maxCachedBodySize limits the size of responses that are stored in the cache.
+const maxCachedBodySize = 10 * 1024 * 1024
+
+const (
+	// DefaultCacheMaxSize limits the total size of the cache directory, the least recently stored entries are evicted
+	// first.
+	DefaultCacheMaxSize int64 = 100 * 1024 * 1024
+	// DefaultCacheMaxAge is the duration after which entries are evicted, regardless of their freshness.
+	DefaultCacheMaxAge = 7 * 24 * time.Hour
+)
+
+// identityHeaders identify the account a request is sent for, they are part of the cache key.
+var identityHeaders = []string{"Authorization", "Session-Token"}
+
+// CachedResponse is a response stored by the CacheMiddleware.
+type CachedResponse struct {
+	Url        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	StoredAt   time.Time   `json:"stored_at"`
+	Expires    time.Time   `json:"expires"`
+	// VaryKey hashes the values of the request headers named by the Vary header of the response.
+	VaryKey string `json:"vary_key,omitempty"`
+}
+
+// isFresh checks if the response can be used without revalidation.
+func (c *CachedResponse) isFresh(now time.Time) bool {
+	return now.Before(c.Expires)
+}
+
+// matches checks if the response was stored for a request with the same values of the headers named by Vary.
+func (c *CachedResponse) matches(request *http.Request) bool {
+	return c.VaryKey == varyKey(request, c.Header)
+}
+
+func (c *CachedResponse) toResponse(request *http.Request) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(c.StatusCode) + " " + http.StatusText(c.StatusCode),
+		StatusCode:    c.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        c.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(c.Body)),
+		ContentLength: int64(len(c.Body)),
+		Request:       request,
+	}
+}
+
+// CacheMiddleware caches successful GET responses on disk. Fresh responses, according to Cache-Control max-age or
+// Expires, are served without a request, other responses are revalidated via If-None-Match and If-Modified-Since.
+// Responses with Cache-Control no-store and responses without freshness information or validators are not stored.
+// The cache key contains the URL and a hash of the authentication headers, so that responses are never shared
+// between accounts, responses are only used for requests matching their Vary header. Entries are evicted once they
+// are older than the max age or the directory exceeds the max size, see SetLimits.
+type CacheMiddleware struct {
+	next      http.RoundTripper
+	directory string
+	maxSize   int64
+	maxAge    time.Duration
+}
+
+func NewCacheMiddleware(roundTripper http.RoundTripper, directory string) *CacheMiddleware {
+	return &CacheMiddleware{
+		next:      roundTripper,
+		directory: directory,
+		maxSize:   DefaultCacheMaxSize,
+		maxAge:    DefaultCacheMaxAge,
+	}
+}
+
+// SetLimits replaces DefaultCacheMaxSize and DefaultCacheMaxAge.
+func (cm *CacheMiddleware) SetLimits(maxSize int64, maxAge time.Duration) {
+	cm.maxSize = maxSize
+	cm.maxAge = maxAge
+}
+
+func (cm *CacheMiddleware) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Method != http.MethodGet || hasCacheDirective(request.Header, "no-store") {
+		return cm.next.RoundTrip(request)
+	}
+
+	key := cacheKey(request)
+	cached := cm.load(key)
+	if cached != nil && !cached.matches(request) {
+		// the response was stored for a different variant, it is replaced by the response to this request
+		cached = nil
+	}
+	if cached != nil && cached.isFresh(time.Now()) && !hasCacheDirective(request.Header, "no-cache") {
+		return cached.toResponse(request), nil
+	}
+
+	outgoing := request
+	if cached != nil {
+		outgoing = request.Clone(request.Context())
+		if etag := cached.Header.Get("ETag"); len(etag) > 0 {
+			outgoing.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := cached.Header.Get("Last-Modified"); len(lastModified) > 0 {
+			outgoing.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	response, err := cm.next.RoundTrip(outgoing)
+	if err != nil {
+		return response, err
+	}
+
+	if cached != nil && response.StatusCode == http.StatusNotModified {
+		_ = response.Body.Close() //nolint:errcheck // the body of a 304 is empty
+		for name, values := range response.Header {
+			cached.Header[name] = values
+		}
+		cached.StoredAt = time.Now()
+		cached.Expires = expiresAt(cached.Header, cached.StoredAt)
+		cm.store(key, cached)
+		return cached.toResponse(request), nil
+	}
+
+	if response.StatusCode != http.StatusOK || !isCacheable(response.Header) {
+		return response, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxCachedBodySize+1))
+	if err != nil {
+		_ = response.Body.Close() //nolint:errcheck // the read error is more relevant
+		return nil, err
+	}
+
+	if len(body) > maxCachedBodySize {
+		// too large to be cached, continue streaming the remaining body
+		response.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), response.Body), response.Body}
+		return response, nil
+	}
+
+	_ = response.Body.Close() //nolint:errcheck // the body has been read completely
+	response.Body = io.NopCloser(bytes.NewReader(body))
+
+	header := response.Header.Clone()
+	header.Del("Set-Cookie")
+	now := time.Now()
+	cm.store(key, &CachedResponse{
+		Url:        request.URL.Redacted(),
+		StatusCode: response.StatusCode,
+		Header:     header,
+		Body:       body,
+		StoredAt:   now,
+		Expires:    expiresAt(header, now),
+		VaryKey:    varyKey(request, header),
+	})
+
+	return response, nil
+}
+
+// LoadCachedResponse returns the cached response for the request regardless of its freshness, e.g. to fall back to it
+// if the network is not available.
+func (cm *CacheMiddleware) LoadCachedResponse(request *http.Request) (*http.Response, bool) {
+	cached := cm.load(cacheKey(request))
+	if cached == nil || !cached.matches(request) {
+		return nil, false
+	}
+	return cached.toResponse(request), true
+}
+
+func (cm *CacheMiddleware) path(key string) string {
+	return filepath.Join(cm.directory, key+".json")
+}
+
+func (cm *CacheMiddleware) load(key string) *CachedResponse {
+	data, err := os.ReadFile(cm.path(key))
+	if err != nil {
+		return nil
+	}
+
+	cached := &CachedResponse{}
+	if json.Unmarshal(data, cached) != nil {
+		return nil
+	}
+
+	if cached.Header == nil {
+		cached.Header = http.Header{}
+	}
+	return cached
+}
+
+// store writes the entry via a temporary file, so that concurrent readers never see partial entries. Failing to
+// store an entry only affects performance and is therefore ignored.
+func (cm *CacheMiddleware) store(key string, cached *CachedResponse) {
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return
+	}
+
+	if err = os.MkdirAll(cm.directory, utils.FILEPERM_755); err != nil {
+		return
+	}
+
+	tmpFile, err := os.CreateTemp(cm.directory, key+".*.tmp")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.Write(data)
+	closeErr := tmpFile.Close()
+	if err != nil || closeErr != nil {
+		return
+	}
+
+	if os.Rename(tmpFile.Name(), cm.path(key)) == nil {
+		cm.evict(time.Now())
+	}
+}
+
+// evict removes entries that are older than the max age and, if the directory still exceeds the max size, the least
+// recently stored entries. Failing to remove an entry is ignored, it is removed by a later eviction.
+func (cm *CacheMiddleware) evict(now time.Time) {
+	dirEntries, err := os.ReadDir(cm.directory)
+	if err != nil {
+		return
+	}
+
+	entries := []fs.FileInfo{}
+	var size int64
+	for _, dirEntry := range dirEntries {
+		info, infoErr := dirEntry.Info()
+		if infoErr != nil || !info.Mode().IsRegular() || filepath.Ext(info.Name()) != ".json" {
+			continue
+		}
+
+		if now.Sub(info.ModTime()) > cm.maxAge {
+			_ = os.Remove(filepath.Join(cm.directory, info.Name())) //nolint:errcheck // see function comment
+			continue
+		}
+		entries = append(entries, info)
+		size += info.Size()
+	}
+
+	slices.SortFunc(entries, func(a, b fs.FileInfo) int {
+		return a.ModTime().Compare(b.ModTime())
+	})
+	for _, info := range entries {
+		if size <= cm.maxSize {
+			break
+		}
+		_ = os.Remove(filepath.Join(cm.directory, info.Name())) //nolint:errcheck // see function comment
+		size -= info.Size()
+	}
+}
+
+// cacheKey hashes URL and authentication headers, the latter must never be stored in clear text.
+func cacheKey(request *http.Request) string {
+	hash := sha256.New()
+	hash.Write([]byte(request.URL.String()))
+	for _, name := range identityHeaders {
+		hash.Write([]byte{0})
+		hash.Write([]byte(request.Header.Get(name)))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// varyKey hashes the values of the request headers named by the Vary response header. Like the cache key, it must
+// not contain header values in clear text, since they might be credentials.
+func varyKey(request *http.Request, responseHeader http.Header) string {
+	names := varyHeaders(responseHeader)
+	if len(names) == 0 {
+		return ""
+	}
+
+	hash := sha256.New()
+	for _, name := range names {
+		hash.Write([]byte(name))
+		hash.Write([]byte{0})
+		hash.Write([]byte(strings.Join(request.Header.Values(name), ",")))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// varyHeaders returns the sorted, canonical header names of the Vary header.
+func varyHeaders(header http.Header) []string {
+	names := []string{}
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); len(name) > 0 {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+func isCacheable(header http.Header) bool {
+	// a response varying on everything can't be matched with later requests
+	if hasCacheDirective(header, "no-store") || slices.Contains(varyHeaders(header), "*") {
+		return false
+	}
+
+	hasValidator := len(header.Get("ETag")) > 0 || len(header.Get("Last-Modified")) > 0
+	_, hasMaxAge := cacheMaxAge(header)
+	return hasValidator || hasMaxAge || len(header.Get("Expires")) > 0
+}
+
+// expiresAt determines until when a response is fresh, responses with no-cache always require revalidation.
+func expiresAt(header http.Header, now time.Time) time.Time {
+	if hasCacheDirective(header, "no-cache") {
+		return now
+	}
+
+	if maxAge, ok := cacheMaxAge(header); ok {
+		return now.Add(maxAge)
+	}
+
+	if expires, err := http.ParseTime(header.Get("Expires")); err == nil {
+		return expires
+	}
+
+	return now
+}
+
+func cacheMaxAge(header http.Header) (time.Duration, bool) {
+	for _, directive := range cacheDirectives(header) {
+		name, value, found := strings.Cut(directive, "=")
+		if !found || name != "max-age" {
+			continue
+		}
+
+		seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+		if err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+	return 0, false
+}
+
+func hasCacheDirective(header http.Header, directive string) bool {
+	return slices.Contains(cacheDirectives(header), directive)
+}
+
+func cacheDirectives(header http.Header) []string {
+	directives := []string{}
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			directives = append(directives, strings.ToLower(strings.TrimSpace(directive)))
+		}
+	}
+	return directives
+}
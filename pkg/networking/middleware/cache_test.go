@@ -0,0 +1,199 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/go-application-framework/pkg/networking/middleware"
+)
+
+func Test_CacheMiddleware(t *testing.T) {
+	var requests atomic.Int32
+	var revalidations atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/max-age":
+			w.Header().Set("Cache-Control", "max-age=60")
+		case "/etag":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				revalidations.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+		case "/last-modified":
+			if len(r.Header.Get("If-Modified-Since")) > 0 {
+				revalidations.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store, max-age=60")
+		case "/vary":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "accept-language")
+		case "/vary-all":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "*")
+		case "/large":
+			w.Header().Set("Cache-Control", "max-age=60")
+			_, err := w.Write(make([]byte, 1024))
+			assert.NoError(t, err)
+		}
+		_, err := w.Write([]byte(r.URL.Path + " " + r.Header.Get("Authorization") + r.Header.Get("Accept-Language")))
+		assert.NoError(t, err)
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	send := func(rt http.RoundTripper, path string, auth string, header ...string) string {
+		t.Helper()
+		request, err := http.NewRequest(http.MethodGet, server.URL+path, http.NoBody)
+		require.NoError(t, err)
+		request.Header.Set("Authorization", auth)
+		for i := 0; i+1 < len(header); i += 2 {
+			request.Header.Set(header[i], header[i+1])
+		}
+		res, err := rt.RoundTrip(request)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	t.Run("serves fresh responses from cache", func(t *testing.T) {
+		requests.Store(0)
+		rt := middleware.NewCacheMiddleware(http.DefaultTransport, t.TempDir())
+
+		assert.Equal(t, "/max-age token a", send(rt, "/max-age", "token a"))
+		assert.Equal(t, "/max-age token a", send(rt, "/max-age", "token a"))
+		assert.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("separates accounts", func(t *testing.T) {
+		requests.Store(0)
+		rt := middleware.NewCacheMiddleware(http.DefaultTransport, t.TempDir())
+
+		assert.Equal(t, "/max-age token a", send(rt, "/max-age", "token a"))
+		assert.Equal(t, "/max-age token b", send(rt, "/max-age", "token b"))
+		assert.Equal(t, int32(2), requests.Load())
+	})
+
+	t.Run("revalidates with validators", func(t *testing.T) {
+		rt := middleware.NewCacheMiddleware(http.DefaultTransport, t.TempDir())
+		for _, path := range []string{"/etag", "/last-modified"} {
+			requests.Store(0)
+			revalidations.Store(0)
+
+			assert.Equal(t, path+" token a", send(rt, path, "token a"))
+			assert.Equal(t, path+" token a", send(rt, path, "token a"))
+			assert.Equal(t, int32(2), requests.Load())
+			assert.Equal(t, int32(1), revalidations.Load())
+		}
+	})
+
+	t.Run("honours no-store", func(t *testing.T) {
+		requests.Store(0)
+		rt := middleware.NewCacheMiddleware(http.DefaultTransport, t.TempDir())
+
+		send(rt, "/no-store", "token a")
+		send(rt, "/no-store", "token a")
+		assert.Equal(t, int32(2), requests.Load())
+	})
+
+	t.Run("does not store responses without caching information", func(t *testing.T) {
+		requests.Store(0)
+		directory := t.TempDir()
+		rt := middleware.NewCacheMiddleware(http.DefaultTransport, directory)
+
+		send(rt, "/plain", "token a")
+		send(rt, "/plain", "token a")
+		assert.Equal(t, int32(2), requests.Load())
+		entries, err := os.ReadDir(directory)
+		assert.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("loads stale responses", func(t *testing.T) {
+		rt := middleware.NewCacheMiddleware(http.DefaultTransport, t.TempDir())
+		send(rt, "/etag", "token a")
+
+		request, err := http.NewRequest(http.MethodGet, server.URL+"/etag", http.NoBody)
+		require.NoError(t, err)
+		request.Header.Set("Authorization", "token a")
+		res, found := rt.LoadCachedResponse(request)
+		require.True(t, found)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		request.Header.Set("Authorization", "token b")
+		_, found = rt.LoadCachedResponse(request)
+		assert.False(t, found)
+	})
+
+	t.Run("honours vary", func(t *testing.T) {
+		requests.Store(0)
+		rt := middleware.NewCacheMiddleware(http.DefaultTransport, t.TempDir())
+
+		assert.Equal(t, "/vary token aen", send(rt, "/vary", "token a", "Accept-Language", "en"))
+		assert.Equal(t, "/vary token aen", send(rt, "/vary", "token a", "Accept-Language", "en"))
+		assert.Equal(t, int32(1), requests.Load())
+
+		assert.Equal(t, "/vary token ade", send(rt, "/vary", "token a", "Accept-Language", "de"))
+		assert.Equal(t, int32(2), requests.Load())
+
+		request, err := http.NewRequest(http.MethodGet, server.URL+"/vary", http.NoBody)
+		require.NoError(t, err)
+		request.Header.Set("Authorization", "token a")
+		request.Header.Set("Accept-Language", "en")
+		_, found := rt.LoadCachedResponse(request)
+		assert.False(t, found)
+
+		requests.Store(0)
+		send(rt, "/vary-all", "token a")
+		send(rt, "/vary-all", "token a")
+		assert.Equal(t, int32(2), requests.Load())
+	})
+
+	t.Run("evicts entries", func(t *testing.T) {
+		directory := t.TempDir()
+		rt := middleware.NewCacheMiddleware(http.DefaultTransport, directory)
+		// enough for one entry with a large body only
+		rt.SetLimits(2048, time.Hour)
+
+		send(rt, "/large", "token a")
+		entries, err := os.ReadDir(directory)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		oldest := entries[0].Name()
+		past := time.Now().Add(-time.Minute)
+		require.NoError(t, os.Chtimes(filepath.Join(directory, oldest), past, past))
+
+		// the least recently stored entry is evicted once the size is exceeded
+		send(rt, "/large", "token b")
+		entries, err = os.ReadDir(directory)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.NotEqual(t, oldest, entries[0].Name())
+
+		// expired entries are evicted regardless of the size
+		past = time.Now().Add(-2 * time.Hour)
+		require.NoError(t, os.Chtimes(filepath.Join(directory, entries[0].Name()), past, past))
+		send(rt, "/max-age", "token a")
+		entries, err = os.ReadDir(directory)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "/max-age token a", send(rt, "/max-age", "token a"))
+	})
+}
@@ -0,0 +1,234 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/snyk/error-catalog-golang-public/snyk"
+	"github.com/snyk/error-catalog-golang-public/snyk_errors"
+)
+
+// CircuitState is the state of the circuit for a single host.
+type CircuitState int
+
+const (
+	// CircuitClosed lets all requests pass.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects all requests until the cooldown expired.
+	CircuitOpen
+	// CircuitHalfOpen lets a single probe request pass, its result closes or re-opens the circuit.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitStatus describes the circuit of a single host, e.g. for diagnostics.
+type CircuitStatus struct {
+	Host                string
+	State               CircuitState
+	ConsecutiveFailures int
+	OpenedAt            time.Time
+}
+
+type circuit struct {
+	state               CircuitState
+	consecutiveFailures int
+	openedAt            time.Time
+	probeInFlight       bool
+}
+
+// CircuitBreaker tracks consecutive failures per host. It is safe for concurrent use and intended to be shared by
+// all clones of a NetworkAccess.
+type CircuitBreaker struct {
+	mutex    sync.Mutex
+	circuits map[string]*circuit
+}
+
+// DefaultCircuitBreakerThreshold and DefaultCircuitBreakerCooldown are the defaults used by the application. The
+// circuit breaker is disabled by default, applications opt in via CIRCUIT_BREAKER_THRESHOLD.
+const (
+	DefaultCircuitBreakerThreshold = 0
+	DefaultCircuitBreakerCooldown  = 30 * time.Second
+)
+
+func NewCircuitBreaker() *CircuitBreaker {
+	return &CircuitBreaker{
+		circuits: map[string]*circuit{},
+	}
+}
+
+// allow checks if a request to the host may be sent. In the half-open state only a single probe request is allowed.
+func (cb *CircuitBreaker) allow(host string, cooldown time.Duration) (bool, time.Duration) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	c := cb.circuits[host]
+	if c == nil {
+		return true, 0
+	}
+
+	switch c.state {
+	case CircuitOpen:
+		remaining := time.Until(c.openedAt.Add(cooldown))
+		if remaining > 0 {
+			return false, remaining
+		}
+		c.state = CircuitHalfOpen
+		c.probeInFlight = true
+		return true, 0
+	case CircuitHalfOpen:
+		if c.probeInFlight {
+			return false, 0
+		}
+		c.probeInFlight = true
+		return true, 0
+	default:
+		return true, 0
+	}
+}
+
+// record updates the circuit of the host with the result of a request.
+func (cb *CircuitBreaker) record(host string, failed bool, threshold int) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	c := cb.circuits[host]
+	if c == nil {
+		if !failed {
+			return
+		}
+		c = &circuit{}
+		cb.circuits[host] = c
+	}
+
+	c.probeInFlight = false
+	if !failed {
+		delete(cb.circuits, host)
+		return
+	}
+
+	c.consecutiveFailures++
+	if c.state == CircuitHalfOpen || c.consecutiveFailures >= threshold {
+		c.state = CircuitOpen
+		c.openedAt = time.Now()
+	}
+}
+
+// release frees a probe slot without changing the state of the circuit.
+func (cb *CircuitBreaker) release(host string) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if c := cb.circuits[host]; c != nil {
+		c.probeInFlight = false
+	}
+}
+
+// GetState returns the state of the circuit for the given host.
+func (cb *CircuitBreaker) GetState(host string) CircuitState {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if c := cb.circuits[host]; c != nil {
+		return c.state
+	}
+	return CircuitClosed
+}
+
+// GetStatus returns the status of all hosts that had failed requests recently.
+func (cb *CircuitBreaker) GetStatus() []CircuitStatus {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	result := make([]CircuitStatus, 0, len(cb.circuits))
+	for host, c := range cb.circuits {
+		result = append(result, CircuitStatus{
+			Host:                host,
+			State:               c.state,
+			ConsecutiveFailures: c.consecutiveFailures,
+			OpenedAt:            c.openedAt,
+		})
+	}
+	return result
+}
+
+// CircuitBreakerMiddleware fails fast for hosts that failed FailureThreshold times in a row. Transport errors, e.g.
+// refused connections, and 5xx responses count as failures, errors of other middlewares, e.g. the client side rate
+// limit, don't. After the cooldown a single probe request is sent, if it succeeds the circuit is
+// closed again.
+type CircuitBreakerMiddleware struct {
+	next             http.RoundTripper
+	breaker          *CircuitBreaker
+	failureThreshold int
+	cooldown         time.Duration
+}
+
+func NewCircuitBreakerMiddleware(roundTripper http.RoundTripper, breaker *CircuitBreaker, failureThreshold int, cooldown time.Duration) *CircuitBreakerMiddleware {
+	return &CircuitBreakerMiddleware{
+		next:             roundTripper,
+		breaker:          breaker,
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+	}
+}
+
+func (cbm *CircuitBreakerMiddleware) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.URL == nil {
+		return cbm.next.RoundTrip(request)
+	}
+
+	host := request.URL.Host
+	if allowed, remaining := cbm.breaker.allow(host, cbm.cooldown); !allowed {
+		return nil, snyk.NewServerError(
+			fmt.Sprintf("Requests to %s are suspended after %d consecutive failures, please try again later.", host, cbm.failureThreshold),
+			snyk_errors.WithMeta("circuit-host", host),
+			snyk_errors.WithMeta("circuit-retry-after", remaining.Round(time.Second).String()),
+			snyk_errors.WithMeta("circuit-state", cbm.breaker.GetState(host).String()),
+		)
+	}
+
+	response, err := cbm.next.RoundTrip(request)
+
+	// canceled requests and errors of other middlewares don't tell anything about the health of the host
+	switch {
+	case request.Context().Err() != nil:
+		cbm.breaker.release(host)
+	case isTransportFailure(err) || (err == nil && response != nil && response.StatusCode >= http.StatusInternalServerError):
+		cbm.breaker.record(host, true, cbm.failureThreshold)
+	case err != nil:
+		cbm.breaker.release(host)
+	default:
+		cbm.breaker.record(host, false, cbm.failureThreshold)
+	}
+
+	return response, err
+}
+
+// isTransportFailure checks if the error was caused by the connection to the host, e.g. a refused connection, a
+// timeout or a connection closed while reading the response.
+func isTransportFailure(err error) bool {
+	// context errors implement net.Error, but they are caused by the caller
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,129 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/snyk/error-catalog-golang-public/snyk"
+	"github.com/snyk/error-catalog-golang-public/snyk_errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/go-application-framework/pkg/networking/middleware"
+)
+
+func Test_CircuitBreakerMiddleware(t *testing.T) {
+	var failing atomic.Bool
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	serverUrl, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	host := serverUrl.Host
+
+	breaker := middleware.NewCircuitBreaker()
+	cooldown := 50 * time.Millisecond
+	rt := middleware.NewCircuitBreakerMiddleware(http.DefaultTransport, breaker, 2, cooldown)
+
+	send := func() (*http.Response, error) {
+		request, requestErr := http.NewRequest(http.MethodGet, server.URL, http.NoBody)
+		require.NoError(t, requestErr)
+		res, roundTripErr := rt.RoundTrip(request)
+		if res != nil {
+			_ = res.Body.Close()
+		}
+		return res, roundTripErr
+	}
+
+	failing.Store(true)
+	for range 2 {
+		res, roundTripErr := send()
+		assert.NoError(t, roundTripErr)
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	}
+	assert.Equal(t, middleware.CircuitOpen, breaker.GetState(host))
+
+	// fails fast while open
+	requests.Store(0)
+	_, err = send()
+	var snykError snyk_errors.Error
+	require.ErrorAs(t, err, &snykError)
+	assert.Equal(t, host, snykError.Meta["circuit-host"])
+	assert.Equal(t, int32(0), requests.Load())
+
+	status := breaker.GetStatus()
+	require.Len(t, status, 1)
+	assert.Equal(t, host, status[0].Host)
+	assert.Equal(t, 2, status[0].ConsecutiveFailures)
+
+	// a failing probe re-opens the circuit
+	time.Sleep(cooldown)
+	_, err = send()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), requests.Load())
+	assert.Equal(t, middleware.CircuitOpen, breaker.GetState(host))
+
+	// a successful probe closes the circuit
+	failing.Store(false)
+	time.Sleep(cooldown)
+	res, err := send()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, middleware.CircuitClosed, breaker.GetState(host))
+	assert.Empty(t, breaker.GetStatus())
+}
+
+type errorRoundTripper struct {
+	err error
+}
+
+func (e errorRoundTripper) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, e.err
+}
+
+func Test_CircuitBreakerMiddleware_CountsTransportFailuresOnly(t *testing.T) {
+	send := func(rt http.RoundTripper, target string) error {
+		request, err := http.NewRequest(http.MethodGet, target, http.NoBody)
+		require.NoError(t, err)
+		_, err = rt.RoundTrip(request) //nolint:bodyclose // all requests fail
+		return err
+	}
+
+	breaker := middleware.NewCircuitBreaker()
+	rateLimited := errorRoundTripper{err: snyk.NewTooManyRequestsError("rate limited")}
+	rt := middleware.NewCircuitBreakerMiddleware(rateLimited, breaker, 1, time.Minute)
+	assert.Error(t, send(rt, "https://rate-limited.example.com"))
+	assert.Error(t, send(rt, "https://rate-limited.example.com"))
+	assert.Equal(t, middleware.CircuitClosed, breaker.GetState("rate-limited.example.com"))
+
+	canceled := errorRoundTripper{err: context.Canceled}
+	rt = middleware.NewCircuitBreakerMiddleware(canceled, breaker, 1, time.Minute)
+	assert.Error(t, send(rt, "https://canceled.example.com"))
+	assert.Equal(t, middleware.CircuitClosed, breaker.GetState("canceled.example.com"))
+
+	// nothing listens on the port of a closed server
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	rt = middleware.NewCircuitBreakerMiddleware(http.DefaultTransport, breaker, 1, time.Minute)
+	assert.Error(t, send(rt, server.URL))
+	assert.Equal(t, middleware.CircuitOpen, breaker.GetState(server.Listener.Addr().String()))
+}
+
+func Test_CircuitState_String(t *testing.T) {
+	assert.Equal(t, "closed", middleware.CircuitClosed.String())
+	assert.Equal(t, "open", middleware.CircuitOpen.String())
+	assert.Equal(t, "half-open", middleware.CircuitHalfOpen.String())
+}
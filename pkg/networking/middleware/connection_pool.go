@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"slices"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// DefaultMaxIdleConnectionsPerHost keeps enough connections to a host for concurrent workflows, the default of
+// net/http only keeps two.
+const DefaultMaxIdleConnectionsPerHost = 16
+
+// ConnectionPoolSettings tune how connections of a transport are kept for reuse, zero values keep the default of the
+// transport.
+type ConnectionPoolSettings struct {
+	MaxIdleConnections        int
+	MaxIdleConnectionsPerHost int
+	IdleConnectionTimeout     time.Duration
+	// DisableHttp2 restricts connections to HTTP/1.1, e.g. for proxies that don't handle HTTP/2 well
+	DisableHttp2 bool
+	// Http2ReadIdleTimeout is the duration after which a health check ping is sent on an HTTP/2 connection without
+	// received frames, zero disables health checks
+	Http2ReadIdleTimeout time.Duration
+	// Http2PingTimeout is the duration after which a connection is closed if the health check ping isn't answered
+	Http2PingTimeout time.Duration
+}
+
+// ApplyConnectionPoolSettings configures the connection reuse and HTTP/2 behavior on a copy of the transport. It needs
+// to be applied last, since HTTP/2 is configured based on the final TLS configuration of the transport.
+func ApplyConnectionPoolSettings(transport *http.Transport, settings ConnectionPoolSettings) *http.Transport {
+	transport = transport.Clone()
+
+	if settings.MaxIdleConnections > 0 {
+		transport.MaxIdleConns = settings.MaxIdleConnections
+	}
+
+	if settings.MaxIdleConnectionsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = settings.MaxIdleConnectionsPerHost
+	}
+
+	if settings.IdleConnectionTimeout > 0 {
+		transport.IdleConnTimeout = settings.IdleConnectionTimeout
+	}
+
+	if settings.DisableHttp2 {
+		// a non nil, empty map disables HTTP/2 as documented by net/http
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		// the base transport might have offered HTTP/2 already, which servers would accept without it being supported
+		if transport.TLSClientConfig != nil {
+			transport.TLSClientConfig.NextProtos = slices.DeleteFunc(slices.Clone(transport.TLSClientConfig.NextProtos), func(protocol string) bool {
+				return protocol == http2.NextProtoTLS
+			})
+		}
+		return transport
+	}
+
+	if settings.Http2ReadIdleTimeout > 0 {
+		// only fails if HTTP/2 was configured already, in which case the existing configuration remains
+		if http2Transport, err := http2.ConfigureTransports(transport); err == nil {
+			http2Transport.ReadIdleTimeout = settings.Http2ReadIdleTimeout
+			if settings.Http2PingTimeout > 0 {
+				http2Transport.PingTimeout = settings.Http2PingTimeout
+			}
+		}
+	}
+	return transport
+}
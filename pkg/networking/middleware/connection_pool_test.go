@@ -0,0 +1,33 @@
+package middleware_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/snyk/go-application-framework/pkg/networking/middleware"
+)
+
+func TestApplyConnectionPoolSettings(t *testing.T) {
+	base := &http.Transport{ForceAttemptHTTP2: true, MaxIdleConns: 100, TLSClientConfig: &tls.Config{NextProtos: []string{"h2", "http/1.1"}}}
+
+	transport := middleware.ApplyConnectionPoolSettings(base, middleware.ConnectionPoolSettings{
+		MaxIdleConnectionsPerHost: 8,
+		IdleConnectionTimeout:     time.Minute,
+	})
+	assert.Equal(t, 100, transport.MaxIdleConns)
+	assert.Equal(t, 8, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, time.Minute, transport.IdleConnTimeout)
+	assert.True(t, transport.ForceAttemptHTTP2)
+
+	transport = middleware.ApplyConnectionPoolSettings(base, middleware.ConnectionPoolSettings{DisableHttp2: true})
+	assert.False(t, transport.ForceAttemptHTTP2)
+	assert.NotNil(t, transport.TLSNextProto)
+	assert.Empty(t, transport.TLSNextProto)
+	assert.Equal(t, []string{"http/1.1"}, transport.TLSClientConfig.NextProtos)
+	// the base transport remains unchanged
+	assert.Equal(t, []string{"h2", "http/1.1"}, base.TLSClientConfig.NextProtos)
+}
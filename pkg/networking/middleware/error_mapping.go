@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/snyk/error-catalog-golang-public/snyk_errors"
+)
+
+// ErrorMapperFunc maps an error response of a Snyk host to an error. Returning nil leaves the response to the default
+// mapping by status code, returning ErrExpectedResponse passes the response to the caller.
+type ErrorMapperFunc func(res *http.Response) error
+
+// ErrExpectedResponse is returned by an ErrorMapperFunc for error status codes that are part of the contract of an
+// endpoint, e.g. 403 for disabled feature flags. Such responses are returned to the caller instead of an error.
+var ErrExpectedResponse = errors.New("the error status code is an expected response")
+
+type errorMapper struct {
+	pathPrefix string
+	mapper     ErrorMapperFunc
+}
+
+// ErrorMappers holds the mappers for error responses (status code >= 400) of requests to Snyk hosts. It is shared by a
+// NetworkAccess and its clones, mappers are matched by the longest URL path prefix.
+type ErrorMappers struct {
+	mutex   sync.RWMutex
+	mappers []*errorMapper
+}
+
+// NewErrorMappers returns the built-in mappers of endpoints whose error responses are read by their callers.
+func NewErrorMappers() *ErrorMappers {
+	return &ErrorMappers{
+		mappers: []*errorMapper{
+			{pathPrefix: "/v1/cli-config/feature-flags/", mapper: mapExpectedCliConfigResponse},
+			{pathPrefix: "/v1/cli-config/settings/", mapper: mapExpectedCliConfigResponse},
+		},
+	}
+}
+
+// builtinErrorMappers is used by HandleResponse and by middlewares without their own mappers.
+var builtinErrorMappers = NewErrorMappers()
+
+// mapExpectedCliConfigResponse passes forbidden and not found responses of the CLI configuration endpoints to the
+// caller, which reads e.g. the state of disabled and unknown feature flags from their body.
+func mapExpectedCliConfigResponse(res *http.Response) error {
+	if res.StatusCode == http.StatusForbidden || res.StatusCode == http.StatusNotFound {
+		return ErrExpectedResponse
+	}
+	return nil
+}
+
+// maxJsonApiBodySize limits how much of an error response body is read to find JSON:API errors.
+const maxJsonApiBodySize = 1024 * 1024
+
+// Register adds a mapper for error responses of requests whose URL path starts with the given prefix, e.g.
+// "/rest/orgs". Extensions use it to map the errors of their own endpoints. If several mappers match, the one with the
+// longest prefix, or for the same prefix the last registered one, is invoked. The returned function removes the mapper again.
+func (m *ErrorMappers) Register(pathPrefix string, mapper ErrorMapperFunc) func() {
+	entry := &errorMapper{pathPrefix: pathPrefix, mapper: mapper}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.mappers = append(m.mappers, entry)
+
+	return func() {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		for i, candidate := range m.mappers {
+			if candidate == entry {
+				m.mappers = append(m.mappers[:i:i], m.mappers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// mapResponse invokes the best matching mapper.
+func (m *ErrorMappers) mapResponse(res *http.Response) error {
+	if res.Request == nil || res.Request.URL == nil {
+		return nil
+	}
+
+	m.mutex.RLock()
+	var match *errorMapper
+	for _, candidate := range m.mappers {
+		if strings.HasPrefix(res.Request.URL.Path, candidate.pathPrefix) && (match == nil || len(candidate.pathPrefix) >= len(match.pathPrefix)) {
+			match = candidate
+		}
+	}
+	m.mutex.RUnlock()
+
+	if match == nil {
+		return nil
+	}
+	return match.mapper(res)
+}
+
+type jsonApiErrorDocument struct {
+	Errors []jsonApiError `json:"errors"`
+}
+
+type jsonApiError struct {
+	Title  string         `json:"title"`
+	Detail string         `json:"detail"`
+	Code   string         `json:"code"`
+	Meta   map[string]any `json:"meta"`
+	Links  struct {
+		About string `json:"about"`
+	} `json:"links"`
+}
+
+// addJsonApiDetailsToErr enriches a snyk_errors.Error with the errors of a JSON:API error document in the response
+// body. The first error defines the error code and title, the details of all errors are combined. The body is restored,
+// so that it can still be read by the caller.
+func addJsonApiDetailsToErr(err error, res *http.Response) error {
+	snykErr := snyk_errors.Error{}
+	if !errors.As(err, &snykErr) || res.Body == nil || !strings.Contains(res.Header.Get("Content-Type"), "json") {
+		return err
+	}
+
+	body, readErr := io.ReadAll(io.LimitReader(res.Body, maxJsonApiBodySize))
+	_ = res.Body.Close() //nolint:errcheck // the body has been read completely
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	if readErr != nil {
+		return err
+	}
+
+	document := jsonApiErrorDocument{}
+	if json.Unmarshal(body, &document) != nil || len(document.Errors) == 0 {
+		return err
+	}
+
+	first := document.Errors[0]
+	if len(first.Code) > 0 {
+		snykErr.ErrorCode = first.Code
+	}
+	if len(first.Title) > 0 {
+		snykErr.Title = first.Title
+	}
+
+	details := []string{}
+	for _, jsonApiErr := range document.Errors {
+		if len(jsonApiErr.Detail) > 0 {
+			details = append(details, jsonApiErr.Detail)
+		}
+		if len(jsonApiErr.Links.About) > 0 {
+			snykErr.Links = append(snykErr.Links, jsonApiErr.Links.About)
+		}
+		for key, value := range jsonApiErr.Meta {
+			if snykErr.Meta == nil {
+				snykErr.Meta = map[string]any{}
+			}
+			snykErr.Meta[key] = value
+		}
+	}
+	if len(details) > 0 {
+		snykErr.Detail = strings.Join(details, "\n")
+	}
+
+	return snykErr
+}
@@ -0,0 +1,446 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snyk/go-application-framework/internal/utils"
+	"github.com/snyk/go-application-framework/pkg/configuration"
+	"github.com/snyk/go-application-framework/pkg/logging"
+)
+
+// maxHarBodySize limits the size of request and response bodies that are stored in HAR files, larger bodies are
+// truncated, but their size is still reported.
+const maxHarBodySize = 1024 * 1024
+
+// The following types implement the subset of the HAR 1.2 format, see http://www.softwareishard.com/blog/har-12-spec/,
+// that is used by HarMiddleware. Custom fields are prefixed with an underscore as required by the format.
+
+type HarFile struct {
+	Log HarLog `json:"log"`
+}
+
+type HarLog struct {
+	Version string     `json:"version"`
+	Creator HarCreator `json:"creator"`
+	Entries []HarEntry `json:"entries"`
+}
+
+type HarCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type HarEntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HarRequest  `json:"request"`
+	Response        HarResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HarTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+type HarRequest struct {
+	Method      string         `json:"method"`
+	Url         string         `json:"url"`
+	HttpVersion string         `json:"httpVersion"`
+	Cookies     []HarNameValue `json:"cookies"`
+	Headers     []HarNameValue `json:"headers"`
+	QueryString []HarNameValue `json:"queryString"`
+	PostData    *HarPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type HarResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HttpVersion string         `json:"httpVersion"`
+	Cookies     []HarNameValue `json:"cookies"`
+	Headers     []HarNameValue `json:"headers"`
+	Content     HarContent     `json:"content"`
+	RedirectUrl string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type HarNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HarPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type HarContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// HarTimings are the durations of the request phases in milliseconds, -1 means that the phase didn't apply, e.g. no
+// DNS lookup for a reused connection.
+type HarTimings struct {
+	Blocked float64 `json:"blocked"`
+	Dns     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Ssl     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// harFileTrailer closes the entries, the log and the file. New entries are inserted before it, so that the file is
+// complete at any time, e.g. if the application is interrupted.
+const harFileTrailer = "\n]}}\n"
+
+// HarWriter appends the entries of all round trippers writing to the same file.
+type HarWriter struct {
+	mutex   sync.Mutex
+	path    string
+	entries int
+}
+
+// HarWriterPool shares a HarWriter between all round trippers writing to the same file. A NetworkAccess and its
+// clones use the same pool.
+type HarWriterPool struct {
+	mutex   sync.Mutex
+	writers map[string]*HarWriter
+}
+
+func NewHarWriterPool() *HarWriterPool {
+	return &HarWriterPool{writers: map[string]*HarWriter{}}
+}
+
+// Get returns the HarWriter for the given file, an existing file is replaced by the first entry.
+func (p *HarWriterPool) Get(path string) *HarWriter {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if writer, ok := p.writers[path]; ok {
+		return writer
+	}
+
+	writer := &HarWriter{path: path}
+	p.writers[path] = writer
+	return writer
+}
+
+func (w *HarWriter) write(entry HarEntry) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if w.entries == 0 {
+		header, headerErr := json.Marshal(HarFile{Log: HarLog{
+			Version: "1.2",
+			Creator: HarCreator{Name: "go-application-framework", Version: "1.0"},
+			Entries: []HarEntry{},
+		}})
+		if headerErr != nil {
+			return headerErr
+		}
+
+		if err = os.MkdirAll(filepath.Dir(w.path), utils.FILEPERM_755); err != nil {
+			return err
+		}
+		content := strings.TrimSuffix(string(header), "]}}") + "\n" + string(data) + harFileTrailer
+		if err = os.WriteFile(w.path, []byte(content), utils.FILEPERM_600); err != nil {
+			return err
+		}
+		w.entries++
+		return nil
+	}
+
+	file, err := os.OpenFile(w.path, os.O_WRONLY, utils.FILEPERM_600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err = file.Seek(-int64(len(harFileTrailer)), io.SeekEnd); err != nil {
+		return err
+	}
+	if _, err = file.WriteString(",\n" + string(data) + harFileTrailer); err != nil {
+		return err
+	}
+	w.entries++
+	return nil
+}
+
+// HarMiddleware writes every request and response, including timings, sizes and redirects, to a HAR file, see
+// HAR_FILE. Authentication headers and cookies are masked and all values are scrubbed using the ScrubbingDict of the
+// configuration at the time of the request, so that the file can be shared, e.g. with support.
+type HarMiddleware struct {
+	next   http.RoundTripper
+	config configuration.Configuration
+	writer *HarWriter
+}
+
+// NewHarMiddleware returns the given round tripper if HAR_FILE is not set.
+func NewHarMiddleware(roundTripper http.RoundTripper, config configuration.Configuration, writers *HarWriterPool) http.RoundTripper {
+	path := config.GetString(configuration.HAR_FILE)
+	if len(path) == 0 {
+		return roundTripper
+	}
+
+	return &HarMiddleware{
+		next:   roundTripper,
+		config: config,
+		writer: writers.Get(path),
+	}
+}
+
+func (hm *HarMiddleware) RoundTrip(request *http.Request) (*http.Response, error) {
+	request, requestBody, err := readRequestBody(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// credentials might change between requests, e.g. when an OAuth token is refreshed
+	scrubber := harScrubber{dict: logging.GetScrubDictFromConfig(hm.config)}
+	trace := &harTrace{start: time.Now()}
+	entry := HarEntry{
+		StartedDateTime: trace.start,
+		Request:         scrubber.newHarRequest(request, requestBody),
+	}
+
+	response, err := hm.next.RoundTrip(request.WithContext(httptrace.WithClientTrace(request.Context(), trace.clientTrace())))
+	if err != nil {
+		entry.Error = scrubber.scrub(err.Error())
+		entry.Response = HarResponse{Cookies: []HarNameValue{}, Headers: []HarNameValue{}, HeadersSize: -1, BodySize: -1}
+		hm.finish(entry, trace)
+		return response, err
+	}
+
+	entry.Response = scrubber.newHarResponse(response)
+	response.Body = &harBody{
+		ReadCloser: response.Body,
+		onDone: func(body []byte, size int64) {
+			entry.Response.BodySize = size
+			entry.Response.Content.Size = size
+			entry.Response.Content.Text, entry.Response.Content.Encoding = encodeBody(body)
+			entry.Response.Content.Text = scrubber.scrub(entry.Response.Content.Text)
+			hm.finish(entry, trace)
+		},
+	}
+	return response, nil
+}
+
+// finish calculates the timings and writes the entry. Failing to write the HAR file must not affect the request and
+// is therefore ignored.
+func (hm *HarMiddleware) finish(entry HarEntry, trace *harTrace) {
+	entry.Time, entry.Timings, entry.ServerIPAddress = trace.result(time.Now())
+	//nolint:errcheck // see function comment
+	_ = hm.writer.write(entry)
+}
+
+// harScrubber scrubs the values of a single entry.
+type harScrubber struct {
+	dict logging.ScrubbingDict
+}
+
+func (hs harScrubber) scrub(value string) string {
+	return scrubString(value, hs.dict)
+}
+
+func (hs harScrubber) newHarRequest(request *http.Request, body []byte) HarRequest {
+	harRequest := HarRequest{
+		Method:      request.Method,
+		Url:         hs.scrub(request.URL.Redacted()),
+		HttpVersion: request.Proto,
+		Cookies:     []HarNameValue{},
+		Headers:     hs.toHarHeaders(request.Header),
+		QueryString: []HarNameValue{},
+		HeadersSize: -1,
+		BodySize:    int64(len(body)),
+	}
+
+	if len(harRequest.HttpVersion) == 0 {
+		harRequest.HttpVersion = "HTTP/1.1"
+	}
+
+	for _, cookie := range request.Cookies() {
+		harRequest.Cookies = append(harRequest.Cookies, HarNameValue{Name: cookie.Name, Value: scrubbedHeaderValue})
+	}
+
+	for name, values := range request.URL.Query() {
+		for _, value := range values {
+			harRequest.QueryString = append(harRequest.QueryString, HarNameValue{Name: name, Value: hs.scrub(value)})
+		}
+	}
+
+	if len(body) > 0 {
+		text, _ := encodeBody(truncate(body))
+		harRequest.PostData = &HarPostData{MimeType: request.Header.Get("Content-Type"), Text: hs.scrub(text)}
+	}
+	return harRequest
+}
+
+func (hs harScrubber) newHarResponse(response *http.Response) HarResponse {
+	harResponse := HarResponse{
+		Status:      response.StatusCode,
+		StatusText:  http.StatusText(response.StatusCode),
+		HttpVersion: response.Proto,
+		Cookies:     []HarNameValue{},
+		Headers:     hs.toHarHeaders(response.Header),
+		Content:     HarContent{MimeType: response.Header.Get("Content-Type")},
+		RedirectUrl: hs.scrub(response.Header.Get("Location")),
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+
+	for _, cookie := range response.Cookies() {
+		harResponse.Cookies = append(harResponse.Cookies, HarNameValue{Name: cookie.Name, Value: scrubbedHeaderValue})
+	}
+	return harResponse
+}
+
+func (hs harScrubber) toHarHeaders(header http.Header) []HarNameValue {
+	result := []HarNameValue{}
+	for name, values := range scrubHeader(header) {
+		for _, value := range values {
+			result = append(result, HarNameValue{Name: name, Value: hs.scrub(value)})
+		}
+	}
+	return result
+}
+
+func truncate(body []byte) []byte {
+	if len(body) > maxHarBodySize {
+		return body[:maxHarBodySize]
+	}
+	return body
+}
+
+// harBody captures the response body while it is read by the caller. The entry is completed once the body was read
+// completely or closed.
+type harBody struct {
+	io.ReadCloser
+	captured []byte
+	size     int64
+	once     sync.Once
+	onDone   func(body []byte, size int64)
+}
+
+func (b *harBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	if remaining := maxHarBodySize - len(b.captured); remaining > 0 {
+		b.captured = append(b.captured, p[:min(n, remaining)]...)
+	}
+
+	if err != nil {
+		b.done()
+	}
+	return n, err
+}
+
+func (b *harBody) Close() error {
+	b.done()
+	return b.ReadCloser.Close()
+}
+
+func (b *harBody) done() {
+	b.once.Do(func() {
+		b.onDone(b.captured, b.size)
+	})
+}
+
+// harTrace records the points in time of a request needed for the HAR timings.
+type harTrace struct {
+	mutex                                   sync.Mutex
+	start, dnsStart, dnsDone, connectStart  time.Time
+	tlsStart, tlsDone, connectDone, gotConn time.Time
+	wroteRequest, gotFirstByte              time.Time
+	remoteAddress                           string
+}
+
+func (t *harTrace) clientTrace() *httptrace.ClientTrace {
+	set := func(target *time.Time) {
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+		*target = time.Now()
+	}
+
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { set(&t.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { set(&t.dnsDone) },
+		ConnectStart:         func(string, string) { set(&t.connectStart) },
+		ConnectDone:          func(string, string, error) { set(&t.connectDone) },
+		TLSHandshakeStart:    func() { set(&t.tlsStart) },
+		TLSHandshakeDone:     func(tlsState tls.ConnectionState, err error) { set(&t.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { set(&t.wroteRequest) },
+		GotFirstResponseByte: func() { set(&t.gotFirstByte) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			set(&t.gotConn)
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			if info.Conn != nil {
+				t.remoteAddress = info.Conn.RemoteAddr().String()
+			}
+		},
+	}
+}
+
+// result returns the total time and the timings in milliseconds. Blocked is the remaining time not covered by the
+// other phases, so that the timings add up to the total time as required by the HAR format.
+func (t *harTrace) result(end time.Time) (float64, HarTimings, string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	between := func(from time.Time, to time.Time) float64 {
+		if from.IsZero() || to.IsZero() || to.Before(from) {
+			return -1
+		}
+		return float64(to.Sub(from)) / float64(time.Millisecond)
+	}
+
+	connectDone := t.connectDone
+	if t.tlsDone.After(connectDone) {
+		// the HAR connect time includes the TLS handshake
+		connectDone = t.tlsDone
+	}
+
+	timings := HarTimings{
+		Dns:     between(t.dnsStart, t.dnsDone),
+		Connect: between(t.connectStart, connectDone),
+		Ssl:     between(t.tlsStart, t.tlsDone),
+		Send:    between(t.gotConn, t.wroteRequest),
+		Wait:    between(t.wroteRequest, t.gotFirstByte),
+		Receive: between(t.gotFirstByte, end),
+	}
+
+	total := between(t.start, end)
+	blocked := total
+	for _, phase := range []float64{timings.Dns, timings.Connect, timings.Send, timings.Wait, timings.Receive} {
+		if phase > 0 {
+			blocked -= phase
+		}
+	}
+	timings.Blocked = max(blocked, 0)
+
+	host, _, err := net.SplitHostPort(t.remoteAddress)
+	if err != nil {
+		host = ""
+	}
+	return total, timings, host
+}
@@ -0,0 +1,137 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/go-application-framework/pkg/configuration"
+	"github.com/snyk/go-application-framework/pkg/networking/middleware"
+)
+
+func readHarFile(t *testing.T, path string) middleware.HarFile {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	har := middleware.HarFile{}
+	require.NoError(t, json.Unmarshal(data, &har))
+	return har
+}
+
+func Test_HarMiddleware(t *testing.T) {
+	const token = "my-secret-token-value"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/target?token="+token, http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		_, err := w.Write([]byte(`{"token":"` + token + `","name":"result"}`))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	harFile := filepath.Join(t.TempDir(), "traffic.har")
+	config := configuration.NewWithOpts()
+	config.Set(configuration.HAR_FILE, harFile)
+	config.Set(configuration.AUTHENTICATION_TOKEN, token)
+
+	client := &http.Client{Transport: middleware.NewHarMiddleware(http.DefaultTransport, config, middleware.NewHarWriterPool())}
+	request, err := http.NewRequest(http.MethodPost, server.URL+"/redirect", strings.NewReader(`{"query":"`+token+`"}`))
+	require.NoError(t, err)
+	request.Header.Set("Authorization", "token "+token)
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := client.Do(request)
+	require.NoError(t, err)
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.NoError(t, response.Body.Close())
+	assert.Contains(t, string(body), token)
+
+	data, err := os.ReadFile(harFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), token)
+
+	har := readHarFile(t, harFile)
+	assert.Equal(t, "1.2", har.Log.Version)
+	require.Len(t, har.Log.Entries, 2)
+
+	redirect := har.Log.Entries[0]
+	assert.Equal(t, http.MethodPost, redirect.Request.Method)
+	assert.Equal(t, http.StatusFound, redirect.Response.Status)
+	assert.Contains(t, redirect.Response.RedirectUrl, "/target?token=")
+	assert.Contains(t, redirect.Request.Headers, middleware.HarNameValue{Name: "Authorization", Value: "***"})
+	require.NotNil(t, redirect.Request.PostData)
+	assert.Equal(t, "application/json", redirect.Request.PostData.MimeType)
+	assert.Equal(t, int64(len(`{"query":"`+token+`"}`)), redirect.Request.BodySize)
+
+	target := har.Log.Entries[1]
+	assert.Equal(t, http.MethodGet, target.Request.Method)
+	assert.Equal(t, http.StatusOK, target.Response.Status)
+	assert.Equal(t, int64(len(body)), target.Response.BodySize)
+	assert.Equal(t, int64(len(body)), target.Response.Content.Size)
+	assert.Equal(t, "application/json", target.Response.Content.MimeType)
+	assert.Contains(t, target.Response.Content.Text, `"name":"result"`)
+	assert.Equal(t, []middleware.HarNameValue{{Name: "session", Value: "***"}}, target.Response.Cookies)
+	assert.Equal(t, "127.0.0.1", target.ServerIPAddress)
+
+	timings := target.Timings
+	sum := timings.Blocked
+	for _, phase := range []float64{timings.Dns, timings.Connect, timings.Send, timings.Wait, timings.Receive} {
+		sum += max(phase, 0)
+	}
+	assert.InDelta(t, target.Time, sum, 0.001)
+	assert.GreaterOrEqual(t, timings.Wait, 0.0)
+
+	t.Run("failed requests are recorded", func(t *testing.T) {
+		failingRequest, requestErr := http.NewRequest(http.MethodGet, "http://127.0.0.1:1/unreachable", nil)
+		require.NoError(t, requestErr)
+		_, err = client.Do(failingRequest)
+		assert.Error(t, err)
+
+		entries := readHarFile(t, harFile).Log.Entries
+		require.Len(t, entries, 3)
+		assert.NotEmpty(t, entries[2].Error)
+		assert.Equal(t, 0, entries[2].Response.Status)
+	})
+
+	t.Run("changed credentials are scrubbed", func(t *testing.T) {
+		const newToken = "my-new-secret-token-value"
+		config.Set(configuration.AUTHENTICATION_TOKEN, newToken)
+
+		response, err = client.Get(server.URL + "/path?token=" + newToken)
+		require.NoError(t, err)
+		_, err = io.ReadAll(response.Body)
+		require.NoError(t, err)
+		require.NoError(t, response.Body.Close())
+
+		data, err = os.ReadFile(harFile)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), newToken)
+		assert.Len(t, readHarFile(t, harFile).Log.Entries, 4)
+	})
+
+	t.Run("other pools replace the file", func(t *testing.T) {
+		otherClient := &http.Client{Transport: middleware.NewHarMiddleware(http.DefaultTransport, config, middleware.NewHarWriterPool())}
+		response, err = otherClient.Get(server.URL)
+		require.NoError(t, err)
+		require.NoError(t, response.Body.Close())
+
+		assert.Len(t, readHarFile(t, harFile).Log.Entries, 1)
+	})
+}
+
+func Test_HarMiddleware_Disabled(t *testing.T) {
+	rt := middleware.NewHarMiddleware(http.DefaultTransport, configuration.NewWithOpts(), middleware.NewHarWriterPool())
+	assert.Equal(t, http.DefaultTransport, rt)
+}
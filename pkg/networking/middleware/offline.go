@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/snyk/error-catalog-golang-public/snyk"
+	"github.com/snyk/error-catalog-golang-public/snyk_errors"
+
+	"github.com/snyk/go-application-framework/pkg/configuration"
+)
+
+// ErrOffline is the cause of the errors for requests and workflows that require network access while the offline mode
+// is enabled via OFFLINE.
+var ErrOffline = errors.New("offline mode is enabled")
+
+// NewOfflineError returns the catalog error for an action that requires network access while the offline mode is
+// enabled, its cause is ErrOffline.
+func NewOfflineError(detail string) snyk_errors.Error {
+	err := snyk.NewBadRequestError(detail, snyk_errors.WithCause(ErrOffline))
+	// no request was sent, so there is no status code
+	err.StatusCode = 0
+	return err
+}
+
+// OfflineMiddleware fails requests fast while the offline mode is enabled, instead of waiting for connections that
+// can't succeed. GET requests are answered from the cache instead if a cached response exists, regardless of its
+// freshness.
+type OfflineMiddleware struct {
+	next   http.RoundTripper
+	config configuration.Configuration
+	cache  *CacheMiddleware
+}
+
+// NewOfflineMiddleware creates an OfflineMiddleware, cache is optional and only used to load cached responses.
+func NewOfflineMiddleware(roundTripper http.RoundTripper, config configuration.Configuration, cache *CacheMiddleware) *OfflineMiddleware {
+	return &OfflineMiddleware{
+		next:   roundTripper,
+		config: config,
+		cache:  cache,
+	}
+}
+
+func (om *OfflineMiddleware) RoundTrip(request *http.Request) (*http.Response, error) {
+	if !om.config.GetBool(configuration.OFFLINE) {
+		return om.next.RoundTrip(request)
+	}
+
+	if om.cache != nil && request.Method == http.MethodGet {
+		if response, found := om.cache.LoadCachedResponse(request); found {
+			return response, nil
+		}
+	}
+
+	return nil, NewOfflineError(fmt.Sprintf("The offline mode is enabled, the request to %s was not sent.", request.URL.Redacted()))
+}
@@ -0,0 +1,54 @@
+package middleware_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/snyk/error-catalog-golang-public/snyk_errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/go-application-framework/pkg/configuration"
+	"github.com/snyk/go-application-framework/pkg/networking/middleware"
+)
+
+func Test_OfflineMiddleware(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		_, err := w.Write([]byte("cached"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	config := getBaseConfig()
+	cache := middleware.NewCacheMiddleware(http.DefaultTransport, t.TempDir())
+	rt := middleware.NewOfflineMiddleware(cache, config, cache)
+
+	res, err := rt.RoundTrip(buildRequest(server.URL + "/cached"))
+	require.NoError(t, err)
+	_ = res.Body.Close()
+	assert.Equal(t, int32(1), requests.Load())
+
+	config.Set(configuration.OFFLINE, true)
+
+	// stale cached responses are used while offline
+	res, err = rt.RoundTrip(buildRequest(server.URL + "/cached"))
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "cached", string(body))
+
+	res, err = rt.RoundTrip(buildRequest(server.URL + "/uncached"))
+	assert.Nil(t, res)
+	assert.True(t, errors.Is(err, middleware.ErrOffline))
+	snykErr := snyk_errors.Error{}
+	require.ErrorAs(t, err, &snykErr)
+	assert.Contains(t, snykErr.Detail, server.URL+"/uncached")
+	assert.Equal(t, int32(1), requests.Load())
+}
@@ -0,0 +1,347 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// DefaultPacLoadTimeout limits loading a PAC script via http or https if ProxyOptions.PacClient isn't set.
+const DefaultPacLoadTimeout = 10 * time.Second
+
+// ProxyFunc returns the proxy to use for a request, nil means a direct connection, see http.Transport.Proxy.
+type ProxyFunc func(req *http.Request) (*url.URL, error)
+
+// PacEvaluator evaluates a proxy auto-config (PAC) script for a URL. The result uses the PAC format, e.g.
+// "PROXY proxy.example.com:8080; DIRECT".
+type PacEvaluator interface {
+	FindProxyForURL(u *url.URL) (string, error)
+}
+
+// PacEvaluatorFactory creates a PacEvaluator from the content of a PAC script. It allows to plug in a full
+// JavaScript based evaluator, the default is NewSimplePacEvaluator.
+type PacEvaluatorFactory func(script string) (PacEvaluator, error)
+
+// ProxyOptions configures NewProxyFunc.
+type ProxyOptions struct {
+	// ProxyUrl is used for all requests, e.g. "http://proxy.example.com:8080".
+	ProxyUrl string
+	// NoProxy lists hosts, domains, IPs and CIDR ranges that are connected to directly, e.g. "localhost", ".corp.com".
+	NoProxy []string
+	// PacLocation is a file path or a file, http or https URL of a PAC script. It is ignored if ProxyUrl is set.
+	PacLocation string
+	// PacEvaluatorFactory is used to evaluate the PAC script, it defaults to NewSimplePacEvaluator.
+	PacEvaluatorFactory PacEvaluatorFactory
+	// PacClient loads a PAC script from an http or https URL. It must connect directly, as the proxy isn't known yet,
+	// and should have a timeout. It defaults to a client without TLS customizations and DefaultPacLoadTimeout.
+	PacClient *http.Client
+}
+
+// NewProxyFunc creates the proxy function for the given options. Without explicit proxy URL and PAC script, the
+// environment (HTTPS_PROXY, HTTP_PROXY, NO_PROXY) is used, extended by the NoProxy option.
+func NewProxyFunc(options ProxyOptions) (ProxyFunc, error) {
+	noProxy := strings.Join(options.NoProxy, ",")
+
+	if len(options.ProxyUrl) > 0 {
+		if err := validateProxyUrl(options.ProxyUrl); err != nil {
+			return nil, err
+		}
+
+		config := &httpproxy.Config{HTTPProxy: options.ProxyUrl, HTTPSProxy: options.ProxyUrl, NoProxy: noProxy}
+		return fromHttpProxyConfig(config), nil
+	}
+
+	if len(options.PacLocation) > 0 {
+		factory := options.PacEvaluatorFactory
+		if factory == nil {
+			factory = NewSimplePacEvaluator
+		}
+
+		script, err := loadPacScript(options.PacLocation, options.PacClient)
+		if err != nil {
+			return nil, err
+		}
+
+		evaluator, err := factory(script)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate PAC script %s: %w", options.PacLocation, err)
+		}
+		return newPacProxyFunc(evaluator, noProxy), nil
+	}
+
+	if len(noProxy) == 0 {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	config := httpproxy.FromEnvironment()
+	config.NoProxy = strings.Join([]string{config.NoProxy, noProxy}, ",")
+	return fromHttpProxyConfig(config), nil
+}
+
+// validateProxyUrl requires an absolute http, https or socks5 URL with a host, as the transport otherwise ignores the
+// proxy or connects to an unexpected one, e.g. to "http://proxy.example.com:8080" for "proxy.example.com:8080".
+func validateProxyUrl(proxyUrl string) error {
+	parsedUrl, err := url.Parse(proxyUrl)
+	if err != nil {
+		return fmt.Errorf("invalid proxy url: %w", err)
+	}
+
+	switch parsedUrl.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return fmt.Errorf("invalid proxy url %q: the scheme must be http, https or socks5", parsedUrl.Redacted())
+	}
+
+	if len(parsedUrl.Hostname()) == 0 {
+		return fmt.Errorf("invalid proxy url %q: the host is missing", parsedUrl.Redacted())
+	}
+	return nil
+}
+
+func fromHttpProxyConfig(config *httpproxy.Config) ProxyFunc {
+	proxyFunc := config.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+}
+
+// newPacProxyFunc uses the first usable entry of the PAC result. Hosts matching noProxy are always connected to
+// directly.
+func newPacProxyFunc(evaluator PacEvaluator, noProxy string) ProxyFunc {
+	// a placeholder proxy is used to reuse the no-proxy matching of httpproxy, it is never connected to
+	bypass := (&httpproxy.Config{HTTPProxy: "placeholder", HTTPSProxy: "placeholder", NoProxy: noProxy}).ProxyFunc()
+
+	return func(req *http.Request) (*url.URL, error) {
+		if placeholder, err := bypass(req.URL); err == nil && placeholder == nil {
+			return nil, nil
+		}
+
+		result, err := evaluator.FindProxyForURL(req.URL)
+		if err != nil {
+			return nil, err
+		}
+		return parsePacResult(result)
+	}
+}
+
+// parsePacResult converts a PAC result like "PROXY a:8080; SOCKS5 b:1080; DIRECT" into a proxy URL. SOCKS entries
+// mean SOCKS4 in PAC scripts, which isn't supported by the transport, so they are skipped like unknown entries.
+func parsePacResult(result string) (*url.URL, error) {
+	for _, entry := range strings.Split(result, ";") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var scheme string
+		switch strings.ToUpper(fields[0]) {
+		case "DIRECT":
+			return nil, nil
+		case "PROXY", "HTTP":
+			scheme = "http"
+		case "HTTPS":
+			scheme = "https"
+		case "SOCKS5":
+			scheme = "socks5"
+		default:
+			continue
+		}
+
+		if len(fields) < 2 {
+			continue
+		}
+		return url.Parse(scheme + "://" + fields[1])
+	}
+
+	return nil, fmt.Errorf("no usable proxy in PAC result %q", result)
+}
+
+func loadPacScript(location string, client *http.Client) (string, error) {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		// the PAC script is loaded directly, as the proxy isn't known yet
+		if client == nil {
+			client = &http.Client{Transport: &http.Transport{Proxy: nil}, Timeout: DefaultPacLoadTimeout}
+		}
+		response, err := client.Get(location)
+		if err != nil {
+			return "", fmt.Errorf("failed to load PAC script: %w", err)
+		}
+		defer response.Body.Close()
+
+		if response.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("failed to load PAC script %s: %s", location, response.Status)
+		}
+
+		script, err := io.ReadAll(response.Body)
+		return string(script), err
+	}
+
+	path := location
+	if fileUrl, err := url.Parse(location); err == nil && fileUrl.Scheme == "file" {
+		path = fileUrl.Path
+	}
+
+	script, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to load PAC script: %w", err)
+	}
+	return string(script), nil
+}
+
+// simplePacRule is a single `if (conditions) return "result";` statement.
+type simplePacRule struct {
+	conditions []simplePacCondition
+	result     string
+}
+
+type simplePacCondition struct {
+	function string
+	argument string
+}
+
+// simplePacEvaluator supports a JavaScript free subset of PAC scripts, see NewSimplePacEvaluator.
+type simplePacEvaluator struct {
+	rules         []simplePacRule
+	defaultResult string
+}
+
+var (
+	pacFunctionPattern  = regexp.MustCompile(`(?s)function\s+FindProxyForURL\s*\(\s*url\s*,\s*host\s*\)\s*\{(.*)\}`)
+	pacRulePattern      = regexp.MustCompile(`^if\s*\((.*)\)\s*(?:\{\s*)?return\s+"([^"]*)"\s*;?\s*\}?$`)
+	pacReturnPattern    = regexp.MustCompile(`^return\s+"([^"]*)"\s*;?$`)
+	pacConditionPattern = regexp.MustCompile(`^(\w+)\s*\(\s*host\s*(?:,\s*"([^"]*)"\s*)?\)$`)
+	pacCommentPattern   = regexp.MustCompile(`(?m)//.*$|(?s)/\*.*?\*/`)
+)
+
+// NewSimplePacEvaluator parses PAC scripts that consist of `if` statements returning a constant, combined with `||`,
+// and a final `return`, e.g.
+//
+//	function FindProxyForURL(url, host) {
+//		if (isPlainHostName(host) || dnsDomainIs(host, ".corp.example.com")) return "DIRECT";
+//		if (shExpMatch(host, "*.snyk.io")) return "PROXY proxy.example.com:8080";
+//		return "DIRECT";
+//	}
+//
+// Supported conditions are isPlainHostName(host), dnsDomainIs(host, "domain"), shExpMatch(host, "pattern") and
+// localHostOrDomainIs(host, "hostname"). Other scripts require a JavaScript based PacEvaluatorFactory.
+func NewSimplePacEvaluator(script string) (PacEvaluator, error) {
+	script = pacCommentPattern.ReplaceAllString(script, "")
+	match := pacFunctionPattern.FindStringSubmatch(script)
+	if match == nil {
+		return nil, fmt.Errorf("FindProxyForURL(url, host) not found")
+	}
+
+	evaluator := &simplePacEvaluator{}
+	for _, statement := range splitPacStatements(match[1]) {
+		if evaluator.defaultResult != "" {
+			return nil, fmt.Errorf("unreachable statement %q", statement)
+		}
+
+		if returnMatch := pacReturnPattern.FindStringSubmatch(statement); returnMatch != nil {
+			evaluator.defaultResult = returnMatch[1]
+			continue
+		}
+
+		ruleMatch := pacRulePattern.FindStringSubmatch(statement)
+		if ruleMatch == nil {
+			return nil, fmt.Errorf("unsupported statement %q", statement)
+		}
+
+		rule := simplePacRule{result: ruleMatch[2]}
+		for _, condition := range strings.Split(ruleMatch[1], "||") {
+			conditionMatch := pacConditionPattern.FindStringSubmatch(strings.TrimSpace(condition))
+			if conditionMatch == nil || !isSupportedPacFunction(conditionMatch[1]) {
+				return nil, fmt.Errorf("unsupported condition %q", strings.TrimSpace(condition))
+			}
+			rule.conditions = append(rule.conditions, simplePacCondition{function: conditionMatch[1], argument: conditionMatch[2]})
+		}
+		evaluator.rules = append(evaluator.rules, rule)
+	}
+
+	if evaluator.defaultResult == "" {
+		return nil, fmt.Errorf("missing final return statement")
+	}
+	return evaluator, nil
+}
+
+// splitPacStatements splits the function body at semicolons outside of string literals, an `if` with its return
+// counts as one statement.
+func splitPacStatements(body string) []string {
+	statements := []string{}
+	appendStatement := func(statement string) {
+		statement = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(statement), "}"))
+		if len(statement) > 0 {
+			statements = append(statements, statement)
+		}
+	}
+
+	inString := false
+	start := 0
+	for i, r := range body {
+		switch {
+		case r == '"':
+			inString = !inString
+		case r == ';' && !inString:
+			appendStatement(body[start:i])
+			start = i + 1
+		}
+	}
+	appendStatement(body[start:])
+	return statements
+}
+
+func isSupportedPacFunction(function string) bool {
+	switch function {
+	case "isPlainHostName", "dnsDomainIs", "shExpMatch", "localHostOrDomainIs":
+		return true
+	default:
+		return false
+	}
+}
+
+func (e *simplePacEvaluator) FindProxyForURL(u *url.URL) (string, error) {
+	host := u.Hostname()
+	for _, rule := range e.rules {
+		for _, condition := range rule.conditions {
+			if condition.matches(host) {
+				return rule.result, nil
+			}
+		}
+	}
+	return e.defaultResult, nil
+}
+
+func (c simplePacCondition) matches(host string) bool {
+	switch c.function {
+	case "isPlainHostName":
+		return !strings.Contains(host, ".")
+	case "dnsDomainIs":
+		return strings.HasSuffix(strings.ToLower(host), strings.ToLower(c.argument))
+	case "localHostOrDomainIs":
+		return strings.EqualFold(host, c.argument) || (!strings.Contains(host, ".") && strings.HasPrefix(strings.ToLower(c.argument), strings.ToLower(host)+"."))
+	case "shExpMatch":
+		return shExpMatch(host, c.argument)
+	default:
+		return false
+	}
+}
+
+var shExpCache sync.Map
+
+// shExpMatch matches shell expressions with * and ? wildcards.
+func shExpMatch(value string, pattern string) bool {
+	compiled, ok := shExpCache.Load(pattern)
+	if !ok {
+		expression := "^" + strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(pattern)) + "$"
+		compiled, _ = shExpCache.LoadOrStore(pattern, regexp.MustCompile("(?i)"+expression))
+	}
+	return compiled.(*regexp.Regexp).MatchString(value) //nolint:forcetypeassert // only regular expressions are stored
+}
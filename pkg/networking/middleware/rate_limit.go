@@ -0,0 +1,238 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snyk/error-catalog-golang-public/snyk"
+	"github.com/snyk/error-catalog-golang-public/snyk_errors"
+
+	"github.com/snyk/go-application-framework/pkg/configuration"
+)
+
+// RateLimitRule limits the requests to a host, e.g. "api.snyk.io", or to a URL prefix, e.g.
+// "https://api.snyk.io/rest/orgs".
+type RateLimitRule struct {
+	Prefix            string
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// tokenBucket allows Burst requests at once and refills at the given rate.
+type tokenBucket struct {
+	rate       float64
+	burst      float64
+	tokens     float64
+	lastRefill time.Time
+}
+
+// reserve takes a token and returns how long the caller has to wait until the token is available. The number of
+// tokens can become negative, so that concurrent callers queue up.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.lastRefill).Seconds()*b.rate)
+	b.lastRefill = now
+	b.tokens--
+
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+func (b *tokenBucket) cancel() {
+	b.tokens = min(b.burst, b.tokens+1)
+}
+
+// RateLimiter holds token buckets per host or URL prefix. It is safe for concurrent use and intended to be shared by
+// all clones of a NetworkAccess.
+type RateLimiter struct {
+	mutex   sync.Mutex
+	rules   []RateLimitRule
+	buckets map[string]*tokenBucket
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		buckets: map[string]*tokenBucket{},
+	}
+}
+
+// AddRule adds or replaces the rule for the given prefix.
+func (r *RateLimiter) AddRule(rule RateLimitRule) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i := range r.rules {
+		if r.rules[i].Prefix == rule.Prefix {
+			r.rules[i] = rule
+			delete(r.buckets, rule.Prefix)
+			return
+		}
+	}
+	r.rules = append(r.rules, rule)
+}
+
+// GetRules returns all rules added via AddRule.
+func (r *RateLimiter) GetRules() []RateLimitRule {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]RateLimitRule{}, r.rules...)
+}
+
+// matchRule returns the rule with the longest matching prefix, it must be called while holding the lock. The
+// additional rules take precedence over the rules of the limiter with the same prefix.
+func (r *RateLimiter) matchRule(u *url.URL, additionalRules []RateLimitRule) (RateLimitRule, bool) {
+	var result RateLimitRule
+	found := false
+	for _, rules := range [][]RateLimitRule{r.rules, additionalRules} {
+		for _, rule := range rules {
+			if ruleMatches(rule.Prefix, u) && len(rule.Prefix) >= len(result.Prefix) {
+				result = rule
+				found = true
+			}
+		}
+	}
+	return result, found
+}
+
+// ruleMatches checks if a rule applies to the URL. A host matches exactly, a URL prefix matches scheme and host
+// exactly and the path on segment boundaries, e.g. "https://api.snyk.io/rest" matches "https://api.snyk.io/rest/orgs"
+// but neither "https://api.snyk.io/restricted" nor "https://api.snyk.io.example.com/rest".
+func ruleMatches(prefix string, u *url.URL) bool {
+	if !strings.Contains(prefix, "://") {
+		return strings.EqualFold(prefix, u.Host)
+	}
+
+	prefixUrl, err := url.Parse(prefix)
+	if err != nil || !strings.EqualFold(prefixUrl.Scheme, u.Scheme) || !strings.EqualFold(prefixUrl.Host, u.Host) {
+		return false
+	}
+
+	prefixPath := strings.TrimSuffix(prefixUrl.Path, "/")
+	return u.Path == prefixPath || strings.HasPrefix(u.Path, prefixPath+"/")
+}
+
+// Wait blocks until the request to the given URL is allowed. The additional rules, e.g. from the configuration, are
+// matched together with the rules of the limiter. The fallback rule is used for the URL's host if no rule matches, a
+// fallback without positive rate disables limiting. If the wait would exceed the deadline of the context, a
+// snyk_errors.Error is returned immediately.
+func (r *RateLimiter) Wait(ctx context.Context, u *url.URL, fallback RateLimitRule, additionalRules ...RateLimitRule) error {
+	r.mutex.Lock()
+	rule, found := r.matchRule(u, additionalRules)
+	if !found {
+		rule = fallback
+		rule.Prefix = u.Host
+	}
+
+	if rule.RequestsPerSecond <= 0 {
+		r.mutex.Unlock()
+		return nil
+	}
+
+	bucket := r.buckets[rule.Prefix]
+	burst := float64(max(rule.Burst, 1))
+	if bucket == nil || bucket.rate != rule.RequestsPerSecond || bucket.burst != burst {
+		bucket = &tokenBucket{rate: rule.RequestsPerSecond, burst: burst, tokens: burst, lastRefill: time.Now()}
+		r.buckets[rule.Prefix] = bucket
+	}
+
+	delay := bucket.reserve(time.Now())
+	if deadline, ok := ctx.Deadline(); ok && delay > 0 && time.Now().Add(delay).After(deadline) {
+		bucket.cancel()
+		r.mutex.Unlock()
+		return snyk.NewTooManyRequestsError(
+			fmt.Sprintf("The client side rate limit of %v requests per second for %s would delay the request beyond its deadline.", rule.RequestsPerSecond, rule.Prefix),
+			snyk_errors.WithMeta("rate-limit-prefix", rule.Prefix),
+			snyk_errors.WithMeta("rate-limit-delay", delay.String()),
+		)
+	}
+	r.mutex.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		r.mutex.Lock()
+		bucket.cancel()
+		r.mutex.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RateLimitMiddleware delays requests according to the rules of a RateLimiter and the configured rules, see
+// ParseRateLimitRules. Requests to Snyk hosts, i.e. the API, its subdomains and AUTHENTICATION_ADDITIONAL_URLS, are
+// limited by RATE_LIMIT_REQUESTS_PER_SECOND unless a more specific rule exists.
+type RateLimitMiddleware struct {
+	next    http.RoundTripper
+	config  configuration.Configuration
+	limiter *RateLimiter
+	rules   []RateLimitRule
+}
+
+func NewRateLimitMiddleware(roundTripper http.RoundTripper, config configuration.Configuration, limiter *RateLimiter, rules ...RateLimitRule) *RateLimitMiddleware {
+	return &RateLimitMiddleware{
+		next:    roundTripper,
+		config:  config,
+		limiter: limiter,
+		rules:   rules,
+	}
+}
+
+// ParseRateLimitRule parses a "prefix=rps" entry of RATE_LIMITS, e.g. "deeproxy.snyk.io=5" or
+// "https://api.snyk.io/rest/orgs=2.5". The prefix is a host or a URL prefix, see RateLimitRule.
+func ParseRateLimitRule(entry string, burst int) (RateLimitRule, error) {
+	separator := strings.LastIndex(entry, "=")
+	if separator < 0 {
+		return RateLimitRule{}, fmt.Errorf("invalid rate limit '%s', expected prefix=rps", entry)
+	}
+
+	prefix := strings.TrimSpace(entry[:separator])
+	requestsPerSecond, err := strconv.ParseFloat(strings.TrimSpace(entry[separator+1:]), 64)
+	if err != nil || len(prefix) == 0 {
+		return RateLimitRule{}, fmt.Errorf("invalid rate limit '%s', expected prefix=rps", entry)
+	}
+
+	return RateLimitRule{Prefix: prefix, RequestsPerSecond: requestsPerSecond, Burst: burst}, nil
+}
+
+func (rl *RateLimitMiddleware) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.URL == nil {
+		return rl.next.RoundTrip(request)
+	}
+
+	fallback := RateLimitRule{}
+	if isSnykUrl(rl.config, request.URL) {
+		fallback.RequestsPerSecond = rl.config.GetFloat64(configuration.RATE_LIMIT_REQUESTS_PER_SECOND)
+		fallback.Burst = rl.config.GetInt(configuration.RATE_LIMIT_BURST)
+	}
+
+	err := rl.limiter.Wait(request.Context(), request.URL, fallback, rl.rules...)
+	if err != nil {
+		return nil, err
+	}
+
+	return rl.next.RoundTrip(request)
+}
+
+// isSnykUrl checks if the URL belongs to the configured API, one of its authenticated subdomains or additional URLs.
+func isSnykUrl(config configuration.Configuration, u *url.URL) bool {
+	apiUrl := config.GetString(configuration.API_URL)
+	additionalSubdomains := config.GetStringSlice(configuration.AUTHENTICATION_SUBDOMAINS)
+	additionalUrls := config.GetStringSlice(configuration.AUTHENTICATION_ADDITIONAL_URLS)
+
+	isKnownHost, err := ShouldRequireAuthentication(apiUrl, u, additionalSubdomains, additionalUrls)
+	return isKnownHost && err == nil
+}
@@ -0,0 +1,162 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/snyk/error-catalog-golang-public/snyk_errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/go-application-framework/pkg/configuration"
+	"github.com/snyk/go-application-framework/pkg/networking/middleware"
+)
+
+func Test_RateLimiter_Wait(t *testing.T) {
+	apiUrl, err := url.Parse("https://api.snyk.io/rest/orgs")
+	require.NoError(t, err)
+	deeproxyUrl, err := url.Parse("https://deeproxy.snyk.io/filters")
+	require.NoError(t, err)
+
+	t.Run("no limit without rule", func(t *testing.T) {
+		limiter := middleware.NewRateLimiter()
+		for range 100 {
+			assert.NoError(t, limiter.Wait(context.Background(), apiUrl, middleware.RateLimitRule{}))
+		}
+	})
+
+	t.Run("delays requests exceeding the burst", func(t *testing.T) {
+		limiter := middleware.NewRateLimiter()
+		limiter.AddRule(middleware.RateLimitRule{Prefix: "api.snyk.io", RequestsPerSecond: 20, Burst: 2})
+
+		start := time.Now()
+		for range 3 {
+			assert.NoError(t, limiter.Wait(context.Background(), apiUrl, middleware.RateLimitRule{}))
+		}
+		assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+		// other hosts are not affected
+		start = time.Now()
+		assert.NoError(t, limiter.Wait(context.Background(), deeproxyUrl, middleware.RateLimitRule{}))
+		assert.Less(t, time.Since(start), 40*time.Millisecond)
+	})
+
+	t.Run("longest prefix wins", func(t *testing.T) {
+		limiter := middleware.NewRateLimiter()
+		limiter.AddRule(middleware.RateLimitRule{Prefix: "api.snyk.io", RequestsPerSecond: 1000, Burst: 10})
+		limiter.AddRule(middleware.RateLimitRule{Prefix: "https://api.snyk.io/rest/", RequestsPerSecond: 0.001, Burst: 1})
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		assert.NoError(t, limiter.Wait(ctx, apiUrl, middleware.RateLimitRule{}))
+		err := limiter.Wait(ctx, apiUrl, middleware.RateLimitRule{})
+
+		var snykError snyk_errors.Error
+		require.ErrorAs(t, err, &snykError)
+		assert.Equal(t, http.StatusTooManyRequests, snykError.StatusCode)
+		assert.Equal(t, "https://api.snyk.io/rest/", snykError.Meta["rate-limit-prefix"])
+	})
+
+	t.Run("prefixes match scheme and host exactly and paths by segment", func(t *testing.T) {
+		limiter := middleware.NewRateLimiter()
+		limiter.AddRule(middleware.RateLimitRule{Prefix: "https://api.snyk.io/rest", RequestsPerSecond: 0.001, Burst: 1})
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		for _, target := range []string{
+			"https://api.snyk.io/restricted",
+			"https://api.snyk.io.example.com/rest/orgs",
+			"http://api.snyk.io/rest/orgs",
+			"https://api.snyk.io:8443/rest/orgs",
+		} {
+			u, parseErr := url.Parse(target)
+			require.NoError(t, parseErr)
+			for range 2 {
+				assert.NoError(t, limiter.Wait(ctx, u, middleware.RateLimitRule{}), target)
+			}
+		}
+
+		assert.NoError(t, limiter.Wait(ctx, apiUrl, middleware.RateLimitRule{}))
+		assert.Error(t, limiter.Wait(ctx, apiUrl, middleware.RateLimitRule{}))
+	})
+
+	t.Run("additional rules take precedence for the same prefix", func(t *testing.T) {
+		limiter := middleware.NewRateLimiter()
+		limiter.AddRule(middleware.RateLimitRule{Prefix: apiUrl.Host, RequestsPerSecond: 0.001, Burst: 1})
+		configured := middleware.RateLimitRule{Prefix: apiUrl.Host, RequestsPerSecond: 1000, Burst: 10}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		for range 5 {
+			assert.NoError(t, limiter.Wait(ctx, apiUrl, middleware.RateLimitRule{}, configured))
+		}
+	})
+
+	t.Run("fallback applies per host", func(t *testing.T) {
+		limiter := middleware.NewRateLimiter()
+		fallback := middleware.RateLimitRule{RequestsPerSecond: 0.001, Burst: 1}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		assert.NoError(t, limiter.Wait(ctx, apiUrl, fallback))
+		assert.NoError(t, limiter.Wait(ctx, deeproxyUrl, fallback))
+		assert.Error(t, limiter.Wait(ctx, apiUrl, fallback))
+	})
+}
+
+func Test_RateLimitMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := configuration.NewWithOpts()
+	config.Set(configuration.API_URL, server.URL)
+	config.Set(configuration.RATE_LIMIT_REQUESTS_PER_SECOND, 0.001)
+	config.Set(configuration.RATE_LIMIT_BURST, 1)
+
+	limiter := middleware.NewRateLimiter()
+	rt := middleware.NewRateLimitMiddleware(http.DefaultTransport, config, limiter)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, http.NoBody)
+	require.NoError(t, err)
+
+	res, err := rt.RoundTrip(request)
+	require.NoError(t, err)
+	_ = res.Body.Close()
+
+	_, err = rt.RoundTrip(request)
+	var snykError snyk_errors.Error
+	assert.ErrorAs(t, err, &snykError)
+
+	// requests to unknown hosts are not limited by default
+	otherRequest, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost.invalid", http.NoBody)
+	require.NoError(t, err)
+	assert.NoError(t, limiter.Wait(ctx, otherRequest.URL, middleware.RateLimitRule{}))
+}
+
+func Test_ParseRateLimitRule(t *testing.T) {
+	rule, err := middleware.ParseRateLimitRule("deeproxy.snyk.io=5", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, middleware.RateLimitRule{Prefix: "deeproxy.snyk.io", RequestsPerSecond: 5, Burst: 2}, rule)
+
+	rule, err = middleware.ParseRateLimitRule(" https://api.snyk.io/rest?version=1 = 2.5 ", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, middleware.RateLimitRule{Prefix: "https://api.snyk.io/rest?version=1", RequestsPerSecond: 2.5, Burst: 1}, rule)
+
+	for _, entry := range []string{"", "api.snyk.io", "=5", "api.snyk.io=fast"} {
+		_, err = middleware.ParseRateLimitRule(entry, 1)
+		assert.Error(t, err, entry)
+	}
+}
@@ -0,0 +1,369 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/snyk/go-application-framework/internal/utils"
+	"github.com/snyk/go-application-framework/pkg/configuration"
+	"github.com/snyk/go-application-framework/pkg/logging"
+)
+
+const (
+	// RecordReplayModeRecord sends requests and stores them with their responses in the fixture file.
+	RecordReplayModeRecord = "record"
+	// RecordReplayModeReplay answers requests from the fixture file without sending them.
+	RecordReplayModeReplay = "replay"
+)
+
+// scrubbedHeaders are the headers whose values are never written to fixture files.
+var scrubbedHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Session-Token",
+	"Cookie",
+	"Set-Cookie",
+}
+
+const scrubbedHeaderValue = "***"
+
+// RecordedRequest is the part of a request that is stored in a fixture file.
+type RecordedRequest struct {
+	Method       string      `json:"method"`
+	Url          string      `json:"url"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+// RecordedResponse is the part of a response that is stored in a fixture file.
+type RecordedResponse struct {
+	StatusCode   int         `json:"status_code"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+// RecordedInteraction is a request/response pair of a fixture file.
+type RecordedInteraction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type recordedFixture struct {
+	Interactions []RecordedInteraction `json:"interactions"`
+}
+
+// Recorder records interactions into or replays interactions from a fixture file.
+type Recorder struct {
+	mutex        sync.Mutex
+	mode         string
+	path         string
+	interactions []RecordedInteraction
+	replayed     map[int]bool
+}
+
+// RecorderPool shares a Recorder between all round trippers using the same mode and file, so that concurrent
+// requests end up in the same fixture. A NetworkAccess and its clones use the same pool.
+type RecorderPool struct {
+	mutex     sync.Mutex
+	recorders map[string]*Recorder
+}
+
+func NewRecorderPool() *RecorderPool {
+	return &RecorderPool{recorders: map[string]*Recorder{}}
+}
+
+// Get returns the Recorder for the given mode and fixture file. In replay mode the fixture file is loaded once, in
+// record mode an existing fixture file is replaced.
+func (p *RecorderPool) Get(mode string, path string) (*Recorder, error) {
+	if mode != RecordReplayModeRecord && mode != RecordReplayModeReplay {
+		return nil, fmt.Errorf("unknown record/replay mode %q", mode)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	id := mode + ":" + path
+	if recorder, ok := p.recorders[id]; ok {
+		return recorder, nil
+	}
+
+	recorder := &Recorder{mode: mode, path: path, replayed: map[int]bool{}}
+	if mode == RecordReplayModeReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fixture file: %w", err)
+		}
+
+		fixture := recordedFixture{}
+		if err = json.Unmarshal(data, &fixture); err != nil {
+			return nil, fmt.Errorf("failed to parse fixture file %s: %w", path, err)
+		}
+		recorder.interactions = fixture.Interactions
+	}
+
+	p.recorders[id] = recorder
+	return recorder, nil
+}
+
+// GetInteractions returns the recorded or loaded interactions.
+func (r *Recorder) GetInteractions() []RecordedInteraction {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]RecordedInteraction{}, r.interactions...)
+}
+
+// record adds the interaction and rewrites the fixture file.
+func (r *Recorder) record(interaction RecordedInteraction) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.interactions = append(r.interactions, interaction)
+	data, err := json.MarshalIndent(recordedFixture{Interactions: r.interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(r.path), utils.FILEPERM_755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, data, utils.FILEPERM_600)
+}
+
+// replay returns the first matching interaction that wasn't replayed yet. Once all matching interactions were
+// replayed, the last one is returned for further requests.
+func (r *Recorder) replay(request RecordedRequest) (RecordedInteraction, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	last := -1
+	for i, interaction := range r.interactions {
+		if !interaction.Request.matches(request) {
+			continue
+		}
+		if !r.replayed[i] {
+			r.replayed[i] = true
+			return interaction, true
+		}
+		last = i
+	}
+
+	if last >= 0 {
+		return r.interactions[last], true
+	}
+	return RecordedInteraction{}, false
+}
+
+// matches compares method, path, query and body of two requests.
+func (rr RecordedRequest) matches(other RecordedRequest) bool {
+	if rr.Method != other.Method || rr.Body != other.Body {
+		return false
+	}
+
+	a, errA := url.Parse(rr.Url)
+	b, errB := url.Parse(other.Url)
+	if errA != nil || errB != nil {
+		return rr.Url == other.Url
+	}
+
+	// Encode sorts the query parameters, so that their order doesn't matter
+	return a.Path == b.Path && a.Query().Encode() == b.Query().Encode()
+}
+
+// RecordReplayMiddleware records requests and responses into a fixture file or replays them from it, see
+// RECORD_REPLAY_MODE and RECORD_REPLAY_FILE. Sensitive headers are masked and URLs, headers and bodies are scrubbed
+// using the ScrubbingDict of the configuration before they are written.
+type RecordReplayMiddleware struct {
+	next     http.RoundTripper
+	config   configuration.Configuration
+	recorder *Recorder
+}
+
+// NewRecordReplayMiddleware returns the given round tripper if RECORD_REPLAY_MODE is not set. If the recorder can't be
+// created, e.g. because the fixture file doesn't exist, all requests fail with the error, so that tests never fall
+// back to the network silently.
+func NewRecordReplayMiddleware(roundTripper http.RoundTripper, config configuration.Configuration, recorders *RecorderPool) http.RoundTripper {
+	mode := config.GetString(configuration.RECORD_REPLAY_MODE)
+	if len(mode) == 0 {
+		return roundTripper
+	}
+
+	recorder, err := recorders.Get(mode, config.GetString(configuration.RECORD_REPLAY_FILE))
+	if err != nil {
+		return failingRoundTripper{err: err}
+	}
+
+	return &RecordReplayMiddleware{
+		next:     roundTripper,
+		config:   config,
+		recorder: recorder,
+	}
+}
+
+func (rrm *RecordReplayMiddleware) RoundTrip(request *http.Request) (*http.Response, error) {
+	request, body, err := readRequestBody(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// the live request is scrubbed like the recorded ones, so that they still match during replay
+	scrubDict := logging.GetScrubDictFromConfig(rrm.config)
+	recordedRequest := newRecordedRequest(request, body, scrubDict)
+
+	if rrm.recorder.mode == RecordReplayModeReplay {
+		interaction, found := rrm.recorder.replay(recordedRequest)
+		if !found {
+			return nil, fmt.Errorf("no recorded interaction for %s %s", request.Method, request.URL.Redacted())
+		}
+		return interaction.Response.toResponse(request)
+	}
+
+	response, err := rrm.next.RoundTrip(request)
+	if err != nil {
+		return response, err
+	}
+
+	responseBody, err := io.ReadAll(response.Body)
+	_ = response.Body.Close() //nolint:errcheck // the body has been read completely
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	recordedResponse := RecordedResponse{StatusCode: response.StatusCode, Header: scrubHeaderValues(response.Header, scrubDict)}
+	recordedResponse.Body, recordedResponse.BodyEncoding = encodeBody(responseBody)
+	recordedResponse.Body = scrubString(recordedResponse.Body, scrubDict)
+
+	err = rrm.recorder.record(RecordedInteraction{Request: recordedRequest, Response: recordedResponse})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write fixture file: %w", err)
+	}
+
+	return response, nil
+}
+
+func newRecordedRequest(request *http.Request, body []byte, scrubDict logging.ScrubbingDict) RecordedRequest {
+	recordedRequest := RecordedRequest{
+		Method: request.Method,
+		Url:    scrubString(request.URL.String(), scrubDict),
+		Header: scrubHeaderValues(request.Header, scrubDict),
+	}
+	recordedRequest.Body, recordedRequest.BodyEncoding = encodeBody(body)
+	recordedRequest.Body = scrubString(recordedRequest.Body, scrubDict)
+	return recordedRequest
+}
+
+// readRequestBody returns the body of the request without consuming it. If the body can't be read again via GetBody,
+// a clone of the request with a copy of the body is returned, which must be sent instead of the original request.
+func readRequestBody(request *http.Request) (*http.Request, []byte, error) {
+	if request.GetBody != nil {
+		reader, err := request.GetBody()
+		if err != nil {
+			return request, nil, err
+		}
+		defer reader.Close()
+		body, err := io.ReadAll(reader)
+		return request, body, err
+	}
+
+	if request.Body == nil || request.Body == http.NoBody {
+		return request, nil, nil
+	}
+
+	body, err := io.ReadAll(request.Body)
+	_ = request.Body.Close() //nolint:errcheck // the body has been read completely
+	if err != nil {
+		return request, nil, err
+	}
+
+	clone := request.Clone(request.Context())
+	clone.Body = io.NopCloser(bytes.NewReader(body))
+	clone.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return clone, body, nil
+}
+
+func (rr RecordedResponse) toResponse(request *http.Request) (*http.Response, error) {
+	body, err := decodeBody(rr.Body, rr.BodyEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	header := rr.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rr.StatusCode, http.StatusText(rr.StatusCode)),
+		StatusCode:    rr.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}, nil
+}
+
+// scrubHeaderValues masks sensitive headers and scrubs the values of all other headers.
+func scrubHeaderValues(header http.Header, scrubDict logging.ScrubbingDict) http.Header {
+	result := scrubHeader(header)
+	for _, values := range result {
+		for i := range values {
+			values[i] = scrubString(values[i], scrubDict)
+		}
+	}
+	return result
+}
+
+func scrubString(value string, scrubDict logging.ScrubbingDict) string {
+	//nolint:forcetypeassert // ScrubValue returns strings for strings
+	return logging.ScrubValue(value, scrubDict).(string)
+}
+
+func scrubHeader(header http.Header) http.Header {
+	result := header.Clone()
+	for _, name := range scrubbedHeaders {
+		if len(result.Values(name)) > 0 {
+			result.Set(name, scrubbedHeaderValue)
+		}
+	}
+	return result
+}
+
+// encodeBody keeps text bodies readable in fixture files and stores binary bodies base64 encoded.
+func encodeBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+func decodeBody(body string, encoding string) ([]byte, error) {
+	if encoding == "base64" {
+		return base64.StdEncoding.DecodeString(body)
+	}
+	return []byte(body), nil
+}
+
+type failingRoundTripper struct {
+	err error
+}
+
+func (f failingRoundTripper) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, f.err
+}
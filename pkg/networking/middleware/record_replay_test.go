@@ -0,0 +1,147 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/go-application-framework/pkg/configuration"
+	"github.com/snyk/go-application-framework/pkg/networking/middleware"
+)
+
+func Test_RecordReplayMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(http.StatusCreated)
+		_, err = w.Write([]byte(r.URL.Query().Get("a") + r.URL.Query().Get("b") + string(body)))
+		assert.NoError(t, err)
+	}))
+
+	fixture := filepath.Join(t.TempDir(), "fixtures", "interactions.json")
+	send := func(rt http.RoundTripper, url string, body string) (*http.Response, error) {
+		request, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		require.NoError(t, err)
+		request.Header.Set("Authorization", "token my-secret-token")
+		return rt.RoundTrip(request)
+	}
+
+	t.Run("record", func(t *testing.T) {
+		config := configuration.NewWithOpts()
+		config.Set(configuration.RECORD_REPLAY_MODE, middleware.RecordReplayModeRecord)
+		config.Set(configuration.RECORD_REPLAY_FILE, fixture)
+		rt := middleware.NewRecordReplayMiddleware(http.DefaultTransport, config, middleware.NewRecorderPool())
+
+		res, err := send(rt, server.URL+"/path?a=1&b=2", "body")
+		require.NoError(t, err)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "12body", string(body))
+
+		data, err := os.ReadFile(fixture)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "my-secret-token")
+		assert.NotContains(t, string(data), "session=secret")
+		assert.Contains(t, string(data), "12body")
+	})
+
+	server.Close()
+
+	t.Run("replay", func(t *testing.T) {
+		config := configuration.NewWithOpts()
+		config.Set(configuration.RECORD_REPLAY_MODE, middleware.RecordReplayModeReplay)
+		config.Set(configuration.RECORD_REPLAY_FILE, fixture)
+		rt := middleware.NewRecordReplayMiddleware(http.DefaultTransport, config, middleware.NewRecorderPool())
+
+		// the order of query parameters doesn't matter
+		res, err := send(rt, server.URL+"/path?b=2&a=1", "body")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "12body", string(body))
+
+		// repeated requests replay the last match
+		res, err = send(rt, server.URL+"/path?a=1&b=2", "body")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+		_, err = send(rt, server.URL+"/path?a=1&b=2", "other body")
+		assert.ErrorContains(t, err, "no recorded interaction")
+
+		_, err = send(rt, server.URL+"/other?a=1&b=2", "body")
+		assert.ErrorContains(t, err, "no recorded interaction")
+	})
+
+	t.Run("replay fails without fixture", func(t *testing.T) {
+		config := configuration.NewWithOpts()
+		config.Set(configuration.RECORD_REPLAY_MODE, middleware.RecordReplayModeReplay)
+		config.Set(configuration.RECORD_REPLAY_FILE, filepath.Join(t.TempDir(), "missing.json"))
+		rt := middleware.NewRecordReplayMiddleware(http.DefaultTransport, config, middleware.NewRecorderPool())
+
+		_, err := send(rt, "https://api.snyk.io/rest", "")
+		assert.ErrorContains(t, err, "failed to read fixture file")
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		rt := middleware.NewRecordReplayMiddleware(http.DefaultTransport, configuration.NewWithOpts(), middleware.NewRecorderPool())
+		assert.Equal(t, http.DefaultTransport, rt)
+	})
+
+	t.Run("scrubs secrets", func(t *testing.T) {
+		secretServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := w.Write([]byte(`{"token":"my-api-token"}`))
+			assert.NoError(t, err)
+		}))
+		defer secretServer.Close()
+
+		secretFixture := filepath.Join(t.TempDir(), "secrets.json")
+		config := configuration.NewWithOpts()
+		config.Set(configuration.AUTHENTICATION_TOKEN, "my-api-token")
+		config.Set(configuration.RECORD_REPLAY_MODE, middleware.RecordReplayModeRecord)
+		config.Set(configuration.RECORD_REPLAY_FILE, secretFixture)
+		rt := middleware.NewRecordReplayMiddleware(http.DefaultTransport, config, middleware.NewRecorderPool())
+
+		res, err := send(rt, secretServer.URL+"/path?access_token=my-access-token&a=1", "token=my-api-token")
+		require.NoError(t, err)
+		_ = res.Body.Close()
+
+		data, err := os.ReadFile(secretFixture)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "my-api-token")
+		assert.NotContains(t, string(data), "my-access-token")
+
+		// the recorded requests still match the scrubbed live requests
+		config.Set(configuration.RECORD_REPLAY_MODE, middleware.RecordReplayModeReplay)
+		rt = middleware.NewRecordReplayMiddleware(http.DefaultTransport, config, middleware.NewRecorderPool())
+		res, err = send(rt, "http://replayed.invalid/path?access_token=my-access-token&a=1", "token=my-api-token")
+		require.NoError(t, err)
+		_ = res.Body.Close()
+	})
+
+	t.Run("doesn't modify the request", func(t *testing.T) {
+		config := configuration.NewWithOpts()
+		config.Set(configuration.RECORD_REPLAY_MODE, middleware.RecordReplayModeReplay)
+		config.Set(configuration.RECORD_REPLAY_FILE, fixture)
+		rt := middleware.NewRecordReplayMiddleware(http.DefaultTransport, config, middleware.NewRecorderPool())
+
+		// the body can't be read again via GetBody
+		request, err := http.NewRequest(http.MethodPost, "http://replayed.invalid/path?a=1&b=2", io.MultiReader(strings.NewReader("body")))
+		require.NoError(t, err)
+		body := request.Body
+
+		res, err := rt.RoundTrip(request)
+		require.NoError(t, err)
+		_ = res.Body.Close()
+		assert.Equal(t, body, request.Body)
+		assert.Nil(t, request.GetBody)
+	})
+}
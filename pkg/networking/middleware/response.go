@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/snyk/error-catalog-golang-public/openapi"
 	"github.com/snyk/error-catalog-golang-public/snyk"
 	"github.com/snyk/error-catalog-golang-public/snyk_errors"
 	"github.com/snyk/go-application-framework/pkg/configuration"
@@ -11,19 +12,27 @@ import (
 )
 
 type ResponseMiddleware struct {
-	next       http.RoundTripper
-	config     configuration.Configuration
-	errHandler networktypes.ErrorHandlerFunc
+	next         http.RoundTripper
+	config       configuration.Configuration
+	errHandler   networktypes.ErrorHandlerFunc
+	errorMappers *ErrorMappers
 }
 
 func NewReponseMiddleware(roundTriper http.RoundTripper, config configuration.Configuration, errHandler networktypes.ErrorHandlerFunc) *ResponseMiddleware {
 	return &ResponseMiddleware{
-		next:       roundTriper,
-		config:     config,
-		errHandler: errHandler,
+		next:         roundTriper,
+		config:       config,
+		errHandler:   errHandler,
+		errorMappers: builtinErrorMappers,
 	}
 }
 
+// WithErrorMappers replaces the built-in error mappers, e.g. with the ones registered for a NetworkAccess.
+func (rm *ResponseMiddleware) WithErrorMappers(errorMappers *ErrorMappers) *ResponseMiddleware {
+	rm.errorMappers = errorMappers
+	return rm
+}
+
 func (rm ResponseMiddleware) RoundTrip(req *http.Request) (*http.Response, error) {
 	res, err := rm.next.RoundTrip(req)
 
@@ -31,7 +40,7 @@ func (rm ResponseMiddleware) RoundTrip(req *http.Request) (*http.Response, error
 		return res, err
 	}
 
-	err = HandleResponse(res, rm.config)
+	err = handleResponse(res, rm.config, rm.errorMappers)
 
 	err = rm.errHandler(err, res.Request.Context())
 
@@ -45,6 +54,10 @@ func (rm ResponseMiddleware) RoundTrip(req *http.Request) (*http.Response, error
 
 // HandleResponse maps the response param to the eror catalog error.
 func HandleResponse(res *http.Response, config configuration.Configuration) error {
+	return handleResponse(res, config, builtinErrorMappers)
+}
+
+func handleResponse(res *http.Response, config configuration.Configuration, errorMappers *ErrorMappers) error {
 	if res == nil {
 		return nil
 	}
@@ -61,7 +74,19 @@ func HandleResponse(res *http.Response, config configuration.Configuration) erro
 		return nil
 	}
 
-	err = errFromStatusCode(res.StatusCode)
+	if res.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+
+	err = errorMappers.mapResponse(res)
+	if errors.Is(err, ErrExpectedResponse) {
+		return nil
+	}
+	if err == nil {
+		err = errFromStatusCode(res.StatusCode)
+		err = addJsonApiDetailsToErr(err, res)
+	}
+
 	if err != nil {
 		return addMetadataToErr(err, res)
 	}
@@ -70,20 +95,42 @@ func HandleResponse(res *http.Response, config configuration.Configuration) erro
 }
 
 // errFromStatusCode matches the providede status code to an Error Catalog error. If no match is found, nil is returned.
+// The status code of the returned error is the one of the response, even if the catalog error is defined for a
+// different one, e.g. a 503 is reported as server error with status code 503.
 func errFromStatusCode(code int) error {
+	var snykErr snyk_errors.Error
 	switch code {
 	case http.StatusUnauthorized:
-		return snyk.NewUnauthorisedError("Use `snyk auth` to authenticate.")
+		snykErr = snyk.NewUnauthorisedError("Use `snyk auth` to authenticate.")
+	case http.StatusForbidden:
+		snykErr = openapi.NewForbiddenError("You are not allowed to access this resource, check the permissions of your account and the configured organization.")
 	case http.StatusBadRequest:
-		return snyk.NewBadRequestError("The request cannot be processed.")
+		snykErr = snyk.NewBadRequestError("The request cannot be processed.")
+	case http.StatusNotFound:
+		snykErr = openapi.NewNotFoundError("The requested resource was not found, check the configured organization and URLs.")
+	case http.StatusConflict:
+		snykErr = snyk.NewBadRequestError("The request conflicts with the current state of the resource.")
+	case http.StatusUnprocessableEntity:
+		snykErr = snyk.NewBadRequestError("The request is well-formed but contains invalid values.")
+	case http.StatusRequestTimeout:
+		snykErr = snyk.NewTimeoutError("The server timed out waiting for the request.")
+	case http.StatusTooManyRequests:
+		snykErr = snyk.NewTooManyRequestsError("Too many requests, please try again later.")
 	case http.StatusInternalServerError:
-		return snyk.NewServerError("Internal server error.")
+		snykErr = snyk.NewServerError("Internal server error.")
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		snykErr = snyk.NewServerError("The service is temporarily unavailable, please try again later.")
+	case http.StatusGatewayTimeout:
+		snykErr = snyk.NewTimeoutError("The server did not respond in time, please try again later.")
 	default:
 		return nil
 	}
+
+	snykErr.StatusCode = code
+	return snykErr
 }
 
-// addMetadataToErr adds the request-id and request-url fields in the metadata map for the error.
+// addMetadataToErr adds the request-id, correlation-id and request-path fields in the metadata map for the error.
 func addMetadataToErr(err error, res *http.Response) error {
 	snykErr := snyk_errors.Error{}
 	if !errors.As(err, &snykErr) {
@@ -95,6 +142,7 @@ func addMetadataToErr(err error, res *http.Response) error {
 	}
 
 	snykErr.Meta["request-id"] = res.Request.Header.Get("snyk-request-id")
+	snykErr.Meta["correlation-id"] = res.Request.Header.Get("snyk-correlation-id")
 	snykErr.Meta["request-path"] = res.Request.URL.Path
 
 	return snykErr
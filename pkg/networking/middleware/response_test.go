@@ -5,8 +5,12 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"path"
+	"strconv"
 	"testing"
 
+	"github.com/snyk/error-catalog-golang-public/openapi"
+	"github.com/snyk/error-catalog-golang-public/snyk"
 	"github.com/snyk/error-catalog-golang-public/snyk_errors"
 	"github.com/snyk/go-application-framework/pkg/configuration"
 	"github.com/snyk/go-application-framework/pkg/networking/middleware"
@@ -15,6 +19,11 @@ import (
 
 func Test_ResponseMiddleware(t *testing.T) {
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if code, err := strconv.Atoi(path.Base(r.URL.Path)); err == nil {
+			w.WriteHeader(code)
+			return
+		}
+
 		switch r.URL.Path {
 		case "/400":
 			w.WriteHeader(http.StatusBadRequest)
@@ -28,6 +37,13 @@ func Test_ResponseMiddleware(t *testing.T) {
 			w.WriteHeader(http.StatusProxyAuthRequired)
 			_, err := w.Write([]byte(`{"jsonapi":{"version":"1.0"},"errors":[{"status":"407","detail":"Proxy auth required"}]}`))
 			assert.Nil(t, err)
+		case "/rest/orgs/jsonapi":
+			w.Header().Set("Content-Type", "application/vnd.api+json")
+			w.WriteHeader(http.StatusForbidden)
+			_, err := w.Write([]byte(`{"jsonapi":{"version":"1.0"},"errors":[` +
+				`{"status":"403","code":"SNYK-OS-0001","title":"Forbidden","detail":"Org is not allowed","links":{"about":"https://docs.snyk.io/forbidden"},"meta":{"org":"abc"}},` +
+				`{"status":"403","detail":"Feature is disabled"}]}`))
+			assert.Nil(t, err)
 		default:
 			w.WriteHeader(http.StatusOK)
 		}
@@ -54,7 +70,7 @@ func Test_ResponseMiddleware(t *testing.T) {
 		config.Set(configuration.AUTHENTICATION_ADDITIONAL_URLS, []string{server.URL})
 		rt := middleware.NewReponseMiddleware(http.DefaultTransport, config, errHandler)
 
-		codes := []int{400, 401, 500}
+		codes := []int{400, 401, 403, 404, 408, 409, 422, 429, 500, 502, 503, 504}
 		for _, code := range codes {
 			snykErr := snyk_errors.Error{}
 			url := fmt.Sprintf("%s/%d", server.URL, code)
@@ -76,13 +92,97 @@ func Test_ResponseMiddleware(t *testing.T) {
 		config.Set(configuration.AUTHENTICATION_ADDITIONAL_URLS, []string{server.URL})
 
 		rt := middleware.NewReponseMiddleware(http.DefaultTransport, config, errHandler)
-		req := buildRequest(server.URL + "/404")
+		req := buildRequest(server.URL + "/418")
 		res, err := rt.RoundTrip(req)
 
 		assert.NotNil(t, res)
 		assert.Nil(t, err)
 	})
 
+	t.Run("adds details from JSON:API error documents", func(t *testing.T) {
+		config := getBaseConfig()
+		config.Set(configuration.AUTHENTICATION_ADDITIONAL_URLS, []string{server.URL})
+		rt := middleware.NewReponseMiddleware(http.DefaultTransport, config, errHandler)
+
+		res, err := rt.RoundTrip(buildRequest(server.URL + "/rest/orgs/jsonapi"))
+		assert.Nil(t, res)
+
+		snykErr := snyk_errors.Error{}
+		assert.ErrorAs(t, err, &snykErr)
+		assert.Equal(t, http.StatusForbidden, snykErr.StatusCode)
+		assert.Equal(t, "SNYK-OS-0001", snykErr.ErrorCode)
+		assert.Equal(t, "Forbidden", snykErr.Title)
+		assert.Equal(t, "Org is not allowed\nFeature is disabled", snykErr.Detail)
+		assert.Contains(t, snykErr.Links, "https://docs.snyk.io/forbidden")
+		assert.Equal(t, "abc", snykErr.Meta["org"])
+		assert.Equal(t, "1234", snykErr.Meta["request-id"])
+		assert.Equal(t, "5678", snykErr.Meta["correlation-id"])
+	})
+
+	t.Run("uses registered error mappers", func(t *testing.T) {
+		config := getBaseConfig()
+		config.Set(configuration.AUTHENTICATION_ADDITIONAL_URLS, []string{server.URL})
+		errorMappers := middleware.NewErrorMappers()
+		rt := middleware.NewReponseMiddleware(http.DefaultTransport, config, errHandler).WithErrorMappers(errorMappers)
+
+		expectedErr := snyk.NewNotImplementedError("custom mapping")
+		unregister := errorMappers.Register("/rest/orgs", func(res *http.Response) error {
+			return expectedErr
+		})
+		// mappers returning nil fall back to the default mapping
+		errorMappers.Register("/rest/orgs/jsonapi", func(res *http.Response) error {
+			return nil
+		})
+
+		_, err := rt.RoundTrip(buildRequest(server.URL + "/rest/orgs/404"))
+		snykErr := snyk_errors.Error{}
+		assert.ErrorAs(t, err, &snykErr)
+		assert.Equal(t, expectedErr.ErrorCode, snykErr.ErrorCode)
+		assert.Equal(t, "/rest/orgs/404", snykErr.Meta["request-path"])
+
+		_, err = rt.RoundTrip(buildRequest(server.URL + "/rest/orgs/jsonapi"))
+		assert.ErrorAs(t, err, &snykErr)
+		assert.Equal(t, "SNYK-OS-0001", snykErr.ErrorCode)
+
+		unregister()
+		_, err = rt.RoundTrip(buildRequest(server.URL + "/rest/orgs/404"))
+		assert.ErrorAs(t, err, &snykErr)
+		assert.Equal(t, openapi.NewNotFoundError("").ErrorCode, snykErr.ErrorCode)
+
+		// other middlewares are not affected
+		_, err = middleware.NewReponseMiddleware(http.DefaultTransport, config, errHandler).RoundTrip(buildRequest(server.URL + "/rest/orgs/409"))
+		assert.ErrorAs(t, err, &snykErr)
+		assert.Equal(t, http.StatusConflict, snykErr.StatusCode)
+		assert.NotEqual(t, expectedErr.ErrorCode, snykErr.ErrorCode)
+	})
+
+	t.Run("maps forbidden and not found to the matching catalog errors", func(t *testing.T) {
+		config := getBaseConfig()
+		config.Set(configuration.AUTHENTICATION_ADDITIONAL_URLS, []string{server.URL})
+		rt := middleware.NewReponseMiddleware(http.DefaultTransport, config, errHandler)
+
+		snykErr := snyk_errors.Error{}
+		_, err := rt.RoundTrip(buildRequest(server.URL + "/403"))
+		assert.ErrorAs(t, err, &snykErr)
+		assert.Equal(t, openapi.NewForbiddenError("").ErrorCode, snykErr.ErrorCode)
+
+		_, err = rt.RoundTrip(buildRequest(server.URL + "/404"))
+		assert.ErrorAs(t, err, &snykErr)
+		assert.Equal(t, openapi.NewNotFoundError("").ErrorCode, snykErr.ErrorCode)
+	})
+
+	t.Run("passes expected responses of the CLI configuration to the caller", func(t *testing.T) {
+		config := getBaseConfig()
+		config.Set(configuration.AUTHENTICATION_ADDITIONAL_URLS, []string{server.URL})
+		rt := middleware.NewReponseMiddleware(http.DefaultTransport, config, errHandler)
+
+		for _, endpoint := range []string{"/v1/cli-config/feature-flags/404", "/v1/cli-config/settings/403"} {
+			res, err := rt.RoundTrip(buildRequest(server.URL + endpoint))
+			assert.NoError(t, err)
+			assert.NotNil(t, res)
+		}
+	})
+
 	t.Run("shoud not intercept external urls", func(t *testing.T) {
 		config := getBaseConfig()
 
@@ -100,6 +200,7 @@ func Test_ResponseMiddleware(t *testing.T) {
 func buildRequest(url string) *http.Request {
 	req, err := http.NewRequest(http.MethodGet, url, http.NoBody)
 	req.Header.Set("snyk-request-id", "1234")
+	req.Header.Set("snyk-correlation-id", "5678")
 	if err != nil {
 		panic(err)
 	}
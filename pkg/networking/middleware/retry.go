@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RetryAttemptFunc is invoked for every attempt that is going to be retried after the given delay.
+type RetryAttemptFunc func(attempt int, request *http.Request, response *http.Response, err error, delay time.Duration)
+
+// RetryOptions configures the RetryMiddleware.
+type RetryOptions struct {
+	// MaxAttempts is the maximum number of attempts including the first one, values below 2 disable retries.
+	MaxAttempts int
+	// InitialDelay is the delay before the first retry, it is doubled for every further retry.
+	InitialDelay time.Duration
+	// MaxDelay limits the delay between attempts, including delays requested via Retry-After.
+	MaxDelay time.Duration
+	// RetryableStatusCodes are the response status codes that cause a retry.
+	RetryableStatusCodes []int
+	// OnRetry is optional and invoked before waiting for the next attempt, e.g. to log it.
+	OnRetry RetryAttemptFunc
+}
+
+// idempotencyKeyHeader marks requests of non-idempotent methods as safe to retry.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+var idempotentMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodTrace,
+	http.MethodPut,
+	http.MethodDelete,
+}
+
+// DefaultRetryOptions returns the options used by the networking package.
+func DefaultRetryOptions() RetryOptions {
+	return RetryOptions{
+		MaxAttempts:  3,
+		InitialDelay: 500 * time.Millisecond,
+		MaxDelay:     30 * time.Second,
+		RetryableStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// RetryMiddleware retries requests that failed with a retryable status code or a transient network error, using exponential
+// backoff with jitter and honoring Retry-After headers. Only idempotent requests and requests with an
+// Idempotency-Key header are retried. Request bodies are rewound via http.Request.GetBody, requests with a body that
+// can't be rewound are not retried.
+type RetryMiddleware struct {
+	next    http.RoundTripper
+	options RetryOptions
+}
+
+func NewRetryMiddleware(roundTripper http.RoundTripper, options RetryOptions) *RetryMiddleware {
+	return &RetryMiddleware{
+		next:    roundTripper,
+		options: options,
+	}
+}
+
+func (rm *RetryMiddleware) RoundTrip(request *http.Request) (*http.Response, error) {
+	if !rm.isRetryable(request) {
+		return rm.next.RoundTrip(request)
+	}
+
+	for attempt := 1; ; attempt++ {
+		attemptRequest, err := rewindRequest(request, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := rm.next.RoundTrip(attemptRequest)
+		if attempt >= rm.options.MaxAttempts || !rm.shouldRetry(request, response, err) {
+			return response, err
+		}
+
+		delay := rm.delay(attempt, response)
+		if deadline, ok := request.Context().Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			// waiting would exceed the deadline, so the current result is returned
+			return response, err
+		}
+
+		if rm.options.OnRetry != nil {
+			rm.options.OnRetry(attempt, attemptRequest, response, err, delay)
+		}
+
+		if response != nil {
+			// drain the body to allow reusing the connection
+			_, _ = io.Copy(io.Discard, response.Body) //nolint:errcheck // the response is discarded anyway
+			_ = response.Body.Close()                 //nolint:errcheck // the response is discarded anyway
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-request.Context().Done():
+			timer.Stop()
+			return nil, request.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// isRetryable checks if the request can be sent several times.
+func (rm *RetryMiddleware) isRetryable(request *http.Request) bool {
+	if rm.options.MaxAttempts < 2 {
+		return false
+	}
+
+	hasBody := request.Body != nil && request.Body != http.NoBody
+	if hasBody && request.GetBody == nil {
+		return false
+	}
+
+	return slices.Contains(idempotentMethods, request.Method) || len(request.Header.Get(idempotencyKeyHeader)) > 0
+}
+
+func (rm *RetryMiddleware) shouldRetry(request *http.Request, response *http.Response, err error) bool {
+	if request.Context().Err() != nil {
+		return false
+	}
+
+	if err != nil {
+		return isTransientNetworkError(err)
+	}
+
+	return response != nil && slices.Contains(rm.options.RetryableStatusCodes, response.StatusCode)
+}
+
+// isTransientNetworkError checks if another attempt might succeed. Errors that fail the same way on every attempt,
+// e.g. an untrusted certificate or an unknown host, and errors of other middlewares aren't retried.
+func isTransientNetworkError(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+
+	var verificationErr *tls.CertificateVerificationError
+	var recordHeaderErr tls.RecordHeaderError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certificateInvalidErr x509.CertificateInvalidError
+	if errors.As(err, &verificationErr) || errors.As(err, &recordHeaderErr) || errors.As(err, &unknownAuthorityErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &certificateInvalidErr) {
+		return false
+	}
+
+	return isTransportFailure(err)
+}
+
+// delay determines how long to wait before the next attempt. Retry-After takes precedence over the exponential
+// backoff, both are limited by MaxDelay.
+func (rm *RetryMiddleware) delay(attempt int, response *http.Response) time.Duration {
+	if response != nil {
+		if retryAfter, ok := parseRetryAfter(response.Header.Get("Retry-After")); ok {
+			return min(retryAfter, rm.options.MaxDelay)
+		}
+	}
+
+	backoff := rm.options.InitialDelay << (attempt - 1)
+	if backoff <= 0 || backoff > rm.options.MaxDelay {
+		backoff = rm.options.MaxDelay
+	}
+
+	// equal jitter: wait at least half of the backoff to avoid retry storms while still spreading clients
+	half := backoff / 2
+	//nolint:gosec // jitter does not require a cryptographically secure random number
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// parseRetryAfter supports both delay-seconds and HTTP-date values.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if len(value) == 0 {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+
+	return 0, false
+}
+
+// rewindRequest returns a request with a fresh body for every attempt after the first one.
+func rewindRequest(request *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 1 || request.GetBody == nil {
+		return request, nil
+	}
+
+	body, err := request.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	newRequest := request.Clone(request.Context())
+	newRequest.Body = body
+	return newRequest, nil
+}
@@ -0,0 +1,192 @@
+package middleware_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/snyk/error-catalog-golang-public/snyk"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/snyk/go-application-framework/pkg/networking/middleware"
+)
+
+func getTestRetryOptions() middleware.RetryOptions {
+	options := middleware.DefaultRetryOptions()
+	options.InitialDelay = time.Millisecond
+	options.MaxDelay = 10 * time.Millisecond
+	return options
+}
+
+func Test_RetryMiddleware(t *testing.T) {
+	var requests atomic.Int32
+	var bodies []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count := requests.Add(1)
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		bodies = append(bodies, string(body))
+
+		switch r.URL.Path {
+		case "/flaky":
+			if count < 3 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		case "/unavailable":
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		case "/slow-down":
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	reset := func() {
+		requests.Store(0)
+		bodies = nil
+	}
+
+	t.Run("retries retryable status codes", func(t *testing.T) {
+		reset()
+		var retries []int
+		options := getTestRetryOptions()
+		options.OnRetry = func(attempt int, request *http.Request, response *http.Response, err error, delay time.Duration) {
+			retries = append(retries, attempt)
+			assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+		}
+		rt := middleware.NewRetryMiddleware(http.DefaultTransport, options)
+
+		res, err := rt.RoundTrip(buildRequest(server.URL + "/flaky"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, int32(3), requests.Load())
+		assert.Equal(t, []int{1, 2}, retries)
+	})
+
+	t.Run("returns the last response after max attempts", func(t *testing.T) {
+		reset()
+		rt := middleware.NewRetryMiddleware(http.DefaultTransport, getTestRetryOptions())
+
+		res, err := rt.RoundTrip(buildRequest(server.URL + "/unavailable"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadGateway, res.StatusCode)
+		assert.Equal(t, int32(3), requests.Load())
+	})
+
+	t.Run("does not retry other status codes", func(t *testing.T) {
+		reset()
+		rt := middleware.NewRetryMiddleware(http.DefaultTransport, getTestRetryOptions())
+
+		res, err := rt.RoundTrip(buildRequest(server.URL + "/error"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		assert.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("does not retry non idempotent requests", func(t *testing.T) {
+		reset()
+		rt := middleware.NewRetryMiddleware(http.DefaultTransport, getTestRetryOptions())
+
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/unavailable", strings.NewReader("payload"))
+		assert.NoError(t, err)
+		_, err = rt.RoundTrip(req)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("rewinds the body of requests with idempotency key", func(t *testing.T) {
+		reset()
+		rt := middleware.NewRetryMiddleware(http.DefaultTransport, getTestRetryOptions())
+
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/flaky", strings.NewReader("payload"))
+		assert.NoError(t, err)
+		req.Header.Set("Idempotency-Key", "123")
+		res, err := rt.RoundTrip(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, []string{"payload", "payload", "payload"}, bodies)
+	})
+
+	t.Run("does not retry bodies that can't be rewound", func(t *testing.T) {
+		reset()
+		rt := middleware.NewRetryMiddleware(http.DefaultTransport, getTestRetryOptions())
+
+		req, err := http.NewRequest(http.MethodPut, server.URL+"/unavailable", io.NopCloser(strings.NewReader("payload")))
+		assert.NoError(t, err)
+		_, err = rt.RoundTrip(req)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("does not wait beyond the deadline", func(t *testing.T) {
+		reset()
+		options := getTestRetryOptions()
+		options.MaxDelay = time.Minute
+		rt := middleware.NewRetryMiddleware(http.DefaultTransport, options)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		req := buildRequest(server.URL + "/slow-down").WithContext(ctx)
+
+		start := time.Now()
+		res, err := rt.RoundTrip(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+		assert.Equal(t, int32(1), requests.Load())
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("retries network errors", func(t *testing.T) {
+		closedServer := httptest.NewServer(handler)
+		closedServer.Close()
+
+		var retries atomic.Int32
+		options := getTestRetryOptions()
+		options.OnRetry = func(attempt int, request *http.Request, response *http.Response, err error, delay time.Duration) {
+			retries.Add(1)
+			assert.Error(t, err)
+		}
+		rt := middleware.NewRetryMiddleware(http.DefaultTransport, options)
+
+		_, err := rt.RoundTrip(buildRequest(closedServer.URL))
+		assert.Error(t, err)
+		assert.Equal(t, int32(2), retries.Load())
+	})
+
+	t.Run("doesn't retry permanent network errors", func(t *testing.T) {
+		tlsServer := httptest.NewTLSServer(handler)
+		defer tlsServer.Close()
+
+		for name, next := range map[string]http.RoundTripper{
+			"untrusted certificate": http.DefaultTransport,
+			"unknown host":          errorRoundTripper{err: &net.DNSError{Err: "no such host", Name: "unknown.invalid", IsNotFound: true}},
+			"rate limit":            errorRoundTripper{err: snyk.NewTooManyRequestsError("rate limited")},
+		} {
+			var retries atomic.Int32
+			options := getTestRetryOptions()
+			options.OnRetry = func(int, *http.Request, *http.Response, error, time.Duration) {
+				retries.Add(1)
+			}
+			rt := middleware.NewRetryMiddleware(next, options)
+
+			_, err := rt.RoundTrip(buildRequest(tlsServer.URL)) //nolint:bodyclose // the request fails
+			assert.Error(t, err, name)
+			assert.Equal(t, int32(0), retries.Load(), name)
+		}
+	})
+}
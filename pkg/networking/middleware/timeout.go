@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/snyk/error-catalog-golang-public/cli"
+	"github.com/snyk/error-catalog-golang-public/snyk_errors"
+)
+
+// TransportTimeouts limits the phases of establishing a connection and receiving the response, zero values keep the
+// default of the transport.
+type TransportTimeouts struct {
+	Connect        time.Duration
+	TlsHandshake   time.Duration
+	ResponseHeader time.Duration
+}
+
+// ApplyTransportTimeouts configures the timeouts on a copy of the transport. The connect timeout wraps the dialer
+// of the transport, so ConfigureProxy needs to be applied before.
+func ApplyTransportTimeouts(transport *http.Transport, timeouts TransportTimeouts) *http.Transport {
+	transport = transport.Clone()
+
+	if timeouts.Connect > 0 {
+		dial := transport.DialContext
+		if dial == nil {
+			dial = (&net.Dialer{}).DialContext
+		}
+
+		transport.DialContext = func(ctx context.Context, network string, address string) (net.Conn, error) {
+			// the context only limits establishing the connection, not its usage
+			ctx, cancel := context.WithTimeout(ctx, timeouts.Connect)
+			defer cancel()
+			return dial(ctx, network, address)
+		}
+	}
+
+	if timeouts.TlsHandshake > 0 {
+		transport.TLSHandshakeTimeout = timeouts.TlsHandshake
+	}
+
+	if timeouts.ResponseHeader > 0 {
+		transport.ResponseHeaderTimeout = timeouts.ResponseHeader
+	}
+	return transport
+}
+
+// TimeoutMiddleware limits the overall duration of requests, including retries and reading the response body, and
+// converts timeouts of all phases into a catalog error that names the endpoint.
+type TimeoutMiddleware struct {
+	next         http.RoundTripper
+	timeout      time.Duration
+	hostTimeouts map[string]time.Duration
+}
+
+// NewTimeoutMiddleware creates a TimeoutMiddleware with the overall timeout and per host overrides, a timeout of zero
+// doesn't limit the duration, but timeouts of the transport are still converted.
+func NewTimeoutMiddleware(roundTripper http.RoundTripper, timeout time.Duration, hostTimeouts map[string]time.Duration) *TimeoutMiddleware {
+	normalizedHostTimeouts := make(map[string]time.Duration, len(hostTimeouts))
+	for host, hostTimeout := range hostTimeouts {
+		normalizedHostTimeouts[strings.ToLower(host)] = hostTimeout
+	}
+
+	return &TimeoutMiddleware{
+		next:         roundTripper,
+		timeout:      timeout,
+		hostTimeouts: normalizedHostTimeouts,
+	}
+}
+
+func (tm *TimeoutMiddleware) getTimeout(host string) time.Duration {
+	if timeout, ok := tm.hostTimeouts[strings.ToLower(host)]; ok {
+		return timeout
+	}
+	return tm.timeout
+}
+
+func (tm *TimeoutMiddleware) RoundTrip(request *http.Request) (*http.Response, error) {
+	timeout := tm.getTimeout(request.URL.Hostname())
+	if timeout <= 0 {
+		response, err := tm.next.RoundTrip(request)
+		return response, toTimeoutError(request, err, 0)
+	}
+
+	ctx, cancel := context.WithTimeout(request.Context(), timeout)
+	response, err := tm.next.RoundTrip(request.WithContext(ctx))
+	if err != nil {
+		if ctx.Err() != nil && request.Context().Err() == nil {
+			err = &overallTimeoutError{err: err}
+		}
+		cancel()
+		return nil, toTimeoutError(request, err, timeout)
+	}
+
+	// the deadline applies until the body was read
+	response.Body = &cancelOnCloseBody{ReadCloser: response.Body, cancel: cancel}
+	return response, nil
+}
+
+// overallTimeoutError marks requests that were canceled by the TimeoutMiddleware.
+type overallTimeoutError struct {
+	err error
+}
+
+func (e *overallTimeoutError) Error() string {
+	return e.err.Error()
+}
+
+func (e *overallTimeoutError) Unwrap() error {
+	return e.err
+}
+
+// toTimeoutError converts timeout errors into a cli.NewConnectionTimeoutError, other errors are returned unchanged.
+// Requests that were canceled by the caller's context are not considered as timeouts of the networking layer.
+func toTimeoutError(request *http.Request, err error, timeout time.Duration) error {
+	if err == nil {
+		return nil
+	}
+
+	var phase string
+	var netErr net.Error
+	var opErr *net.OpError
+	var overallErr *overallTimeoutError
+	switch {
+	case errors.As(err, &overallErr):
+		phase = fmt.Sprintf("the request didn't complete within %s", timeout)
+	case request.Context().Err() != nil:
+		return err
+	case errors.As(err, &opErr) && opErr.Op == "dial" && opErr.Timeout():
+		phase = "establishing the connection timed out"
+	case strings.Contains(err.Error(), "TLS handshake timeout"):
+		phase = "the TLS handshake timed out"
+	case strings.Contains(err.Error(), "timeout awaiting response headers"):
+		phase = "waiting for the response headers timed out"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		phase = "the connection timed out"
+	default:
+		return err
+	}
+
+	endpoint := *request.URL
+	endpoint.User = nil
+	endpoint.RawQuery = ""
+	endpoint.Fragment = ""
+
+	return cli.NewConnectionTimeoutError(
+		fmt.Sprintf("The request to %s failed, %s.", endpoint.String(), phase),
+		snyk_errors.WithCause(err),
+		snyk_errors.WithMeta("endpoint", endpoint.String()),
+		snyk_errors.WithMeta("timeout-phase", phase),
+	)
+}
+
+// cancelOnCloseBody releases the context of a request once its body is closed.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,103 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/snyk/error-catalog-golang-public/snyk_errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/go-application-framework/pkg/networking/middleware"
+)
+
+func newSlowServer(t *testing.T, delay time.Duration) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func requireTimeoutError(t *testing.T, err error) snyk_errors.Error {
+	t.Helper()
+	catalogErr := snyk_errors.Error{}
+	require.True(t, errors.As(err, &catalogErr), err)
+	assert.Equal(t, "SNYK-OS-7001", catalogErr.ErrorCode)
+	return catalogErr
+}
+
+func Test_TimeoutMiddleware_OverallTimeout(t *testing.T) {
+	server := newSlowServer(t, 2*time.Second)
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert // test setup
+
+	t.Run("times out", func(t *testing.T) {
+		rt := middleware.NewTimeoutMiddleware(transport, 100*time.Millisecond, nil)
+		request := buildRequest(server.URL + "/rest/orgs?version=2024-01-01")
+
+		_, err := rt.RoundTrip(request)
+		catalogErr := requireTimeoutError(t, err)
+		assert.Equal(t, server.URL+"/rest/orgs", catalogErr.Meta["endpoint"])
+		assert.Contains(t, catalogErr.Detail, server.URL+"/rest/orgs")
+		assert.Contains(t, catalogErr.Detail, "didn't complete within 100ms")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("host override", func(t *testing.T) {
+		fastServer := newSlowServer(t, 200*time.Millisecond)
+		rt := middleware.NewTimeoutMiddleware(transport, 100*time.Millisecond, map[string]time.Duration{"127.0.0.1": 5 * time.Second})
+
+		response, err := rt.RoundTrip(buildRequest(fastServer.URL))
+		require.NoError(t, err)
+		assert.NoError(t, response.Body.Close())
+	})
+
+	t.Run("caller cancellation is not a timeout", func(t *testing.T) {
+		rt := middleware.NewTimeoutMiddleware(transport, 5*time.Second, nil)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		_, err := rt.RoundTrip(buildRequest(server.URL).WithContext(ctx))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.False(t, errors.As(err, &snyk_errors.Error{}))
+	})
+}
+
+func Test_TimeoutMiddleware_TransportTimeouts(t *testing.T) {
+	t.Run("response headers", func(t *testing.T) {
+		server := newSlowServer(t, 2*time.Second)
+		transport := middleware.ApplyTransportTimeouts(http.DefaultTransport.(*http.Transport), middleware.TransportTimeouts{ //nolint:forcetypeassert // test setup
+			ResponseHeader: 100 * time.Millisecond,
+		})
+		rt := middleware.NewTimeoutMiddleware(transport, 0, nil)
+
+		_, err := rt.RoundTrip(buildRequest(server.URL))
+		catalogErr := requireTimeoutError(t, err)
+		assert.Contains(t, catalogErr.Detail, "waiting for the response headers timed out")
+	})
+
+	t.Run("connect", func(t *testing.T) {
+		transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert // test setup
+		transport.DialContext = func(ctx context.Context, network string, address string) (net.Conn, error) {
+			<-ctx.Done()
+			return nil, &net.OpError{Op: "dial", Net: network, Err: ctx.Err()}
+		}
+		transport = middleware.ApplyTransportTimeouts(transport, middleware.TransportTimeouts{Connect: 100 * time.Millisecond})
+		rt := middleware.NewTimeoutMiddleware(transport, 0, nil)
+
+		_, err := rt.RoundTrip(buildRequest("http://unreachable.invalid/path"))
+		catalogErr := requireTimeoutError(t, err)
+		assert.Equal(t, "http://unreachable.invalid/path", catalogErr.Meta["endpoint"])
+		assert.Contains(t, catalogErr.Detail, "establishing the connection timed out")
+	})
+}
@@ -1,13 +1,36 @@
 package middleware
 
 import (
+	"crypto/tls"
 	"crypto/x509"
 	"net/http"
 )
 
-func ApplyTlsConfig(transport *http.Transport, insecure bool, caPool *x509.CertPool) *http.Transport {
+// TlsOption modifies the TLS configuration of a transport, see ApplyTlsConfig.
+type TlsOption func(config *tls.Config)
+
+// WithClientCertificate presents the certificate returned by the given function, e.g.
+// certs.ClientCertificateLoader.GetClientCertificate, if the server requests one.
+func WithClientCertificate(getClientCertificate func(*tls.CertificateRequestInfo) (*tls.Certificate, error)) TlsOption {
+	return func(config *tls.Config) {
+		config.GetClientCertificate = getClientCertificate
+	}
+}
+
+// WithVerifyConnection adds a verification that runs after the certificate verification, e.g.
+// certs.NewSpkiPinVerifier.
+func WithVerifyConnection(verifyConnection func(tls.ConnectionState) error) TlsOption {
+	return func(config *tls.Config) {
+		config.VerifyConnection = verifyConnection
+	}
+}
+
+func ApplyTlsConfig(transport *http.Transport, insecure bool, caPool *x509.CertPool, options ...TlsOption) *http.Transport {
 	transport = transport.Clone()
 	transport.TLSClientConfig.InsecureSkipVerify = insecure
 	transport.TLSClientConfig.RootCAs = caPool
+	for _, option := range options {
+		option(transport.TLSClientConfig)
+	}
 	return transport
 }
@@ -2,11 +2,18 @@ package networking
 
 import (
 	"bytes"
+	"crypto/tls"
 	"crypto/x509"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 
 	"github.com/snyk/go-httpauth/pkg/httpauth"
@@ -43,6 +50,25 @@ type NetworkAccess interface {
 	GetErrorHandler() networktypes.ErrorHandlerFunc
 	// GetAuthenticator returns the authenticator.
 	GetAuthenticator() auth.Authenticator
+	// AddErrorMapper maps the error responses of Snyk endpoints below the given URL path prefix, e.g. of an extension, to
+	// errors. The mappers are shared with all clones.
+	AddErrorMapper(pathPrefix string, mapper middleware.ErrorMapperFunc)
+	// AddRateLimit limits the requests to a host or URL prefix, the limit is shared with all clones.
+	AddRateLimit(prefix string, requestsPerSecond float64, burst int)
+	// SetPacEvaluatorFactory replaces the evaluator used for PAC scripts configured via PROXY_PAC.
+	SetPacEvaluatorFactory(factory middleware.PacEvaluatorFactory)
+	// GetCircuitBreakerStatus returns the circuit state of all hosts with recently failed requests.
+	GetCircuitBreakerStatus() []middleware.CircuitStatus
+	// AddRequestHook registers a hook that is called, in the order of registration, before each request attempt.
+	AddRequestHook(hook RequestHookFunc)
+	// AddResponseHook registers a hook that is called, in the order of registration, for each received response.
+	AddResponseHook(hook ResponseHookFunc)
+	// AddMiddleware wraps the round tripper of each request attempt, the first registered middleware is the outermost.
+	AddMiddleware(middlewareFunc MiddlewareFunc)
+	// SetCorrelationId sets the ID that is sent with all requests to correlate them, e.g. with a workflow invocation.
+	SetCorrelationId(correlationId string)
+	// GetCorrelationId returns the ID that is sent with all requests, clones inherit it.
+	GetCorrelationId() string
 
 	SetLogger(logger *zerolog.Logger)
 	SetConfiguration(configuration configuration.Configuration)
@@ -61,12 +87,34 @@ type networkImpl struct {
 	dynamicHeaders map[string]DynamicHeaderFunc
 	proxy          func(req *http.Request) (*url.URL, error)
 	errorHandler   networktypes.ErrorHandlerFunc
+	errorMappers   *middleware.ErrorMappers
 	caPool         *x509.CertPool
 	logger         *zerolog.Logger
+	rateLimiter    *middleware.RateLimiter
+	circuitBreaker *middleware.CircuitBreaker
+	recorders      *middleware.RecorderPool
+	harWriters     *middleware.HarWriterPool
+	proxyResolver  *proxyResolver
+	// clientCertificates provides the client certificate for mutual TLS
+	clientCertificates *clientCertificateProvider
+	hooks              hooks
+	// transports are shared by all clones to reuse connections
+	transports    *transportPool
+	correlationId string
 }
 
 const defaultNetworkLogLevel = zerolog.DebugLevel
 
+const (
+	// CorrelationIdHeader carries the ID shared by all requests of a NetworkAccess and its clones.
+	CorrelationIdHeader = "snyk-correlation-id"
+	// RequestIdHeader carries an ID that is unique per request and kept across retries.
+	RequestIdHeader = "snyk-request-id"
+)
+
+// httpCacheDirectory is the directory below CACHE_PATH used by the response cache.
+const httpCacheDirectory = "http"
+
 func LogRequest(r *http.Request, logger *zerolog.Logger) {
 	if logger.GetLevel() > defaultNetworkLogLevel { // Don't log if logger level is above the threshold
 		return
@@ -115,7 +163,8 @@ func (rt *defaultHeadersRoundTripper) logRoundTrip(request *http.Request, respon
 	LogResponse(response, rt.networkAccess.logger)
 
 	if err != nil {
-		rt.networkAccess.logger.WithLevel(rt.logLevel).Msgf("< error: %s", err.Error())
+		rt.networkAccess.logger.WithLevel(rt.logLevel).Msgf("< error [%p]: %s (%s: %s, %s: %s)", request, err.Error(),
+			CorrelationIdHeader, request.Header.Get(CorrelationIdHeader), RequestIdHeader, request.Header.Get(RequestIdHeader))
 	}
 }
 
@@ -140,20 +189,33 @@ func NewNetworkAccess(config configuration.Configuration) NetworkAccess {
 	logger := zerolog.New(io.Discard)
 
 	n := &networkImpl{
-		config:         config,
-		staticHeader:   http.Header{},
-		logger:         &logger,
-		proxy:          http.ProxyFromEnvironment,
-		dynamicHeaders: map[string]DynamicHeaderFunc{},
+		config:             config,
+		staticHeader:       http.Header{},
+		logger:             &logger,
+		proxy:              http.ProxyFromEnvironment,
+		dynamicHeaders:     map[string]DynamicHeaderFunc{},
+		errorMappers:       middleware.NewErrorMappers(),
+		rateLimiter:        middleware.NewRateLimiter(),
+		circuitBreaker:     middleware.NewCircuitBreaker(),
+		recorders:          middleware.NewRecorderPool(),
+		harWriters:         middleware.NewHarWriterPool(),
+		proxyResolver:      &proxyResolver{},
+		clientCertificates: &clientCertificateProvider{},
+		transports:         &transportPool{},
+		correlationId:      uuid.NewString(),
 	}
 
-	extraCaCertFile := config.GetString(configuration.ADD_TRUSTED_CA_FILE)
-	if len(extraCaCertFile) > 0 {
-		err := n.AddRootCAs(extraCaCertFile)
+	extraCaLocations := append([]string{config.GetString(configuration.ADD_TRUSTED_CA_FILE)}, config.GetStringSlice(configuration.ADD_TRUSTED_CA_LOCATIONS)...)
+	for _, extraCaLocation := range extraCaLocations {
+		if len(extraCaLocation) == 0 {
+			continue
+		}
+
+		err := n.AddRootCAs(extraCaLocation)
 		if err != nil {
-			logger.Printf("Failed to AddRootCAs from '%s' (%v)\n", extraCaCertFile, err)
+			logger.Printf("Failed to AddRootCAs from '%s' (%v)\n", extraCaLocation, err)
 		} else {
-			logger.Print("Using additional CAs from file:", extraCaCertFile)
+			logger.Print("Using additional CAs from:", extraCaLocation)
 		}
 	}
 
@@ -190,6 +252,12 @@ func (n *networkImpl) AddHeaders(request *http.Request) error {
 
 // addDefaultHeader adds the default headers request.
 func (n *networkImpl) addDefaultHeader(request *http.Request) {
+	// correlation headers are added first, so that dynamic headers can rely on them
+	request.Header.Set(CorrelationIdHeader, n.correlationId)
+	if len(request.Header.Get(RequestIdHeader)) == 0 {
+		request.Header.Set(RequestIdHeader, uuid.NewString())
+	}
+
 	// add/replace request headers by dynamic headers
 	for k, determineHeader := range n.dynamicHeaders {
 		existingValues := request.Header.Values(k)
@@ -209,13 +277,17 @@ func (n *networkImpl) addDefaultHeader(request *http.Request) {
 	}
 }
 
-func (n *networkImpl) getUnauthorizedRoundTripper() http.RoundTripper {
-	//nolint:errcheck // breaking api change needed to fix this
-	transport := http.DefaultTransport.(*http.Transport) //nolint:forcetypeassert // panic here is reasonable
-	var crt http.RoundTripper = n.configureRoundTripper(transport)
-	if n.errorHandler != nil {
-		crt = middleware.NewReponseMiddleware(crt, n.config, n.errorHandler)
-	}
+func (n *networkImpl) getDefaultHeadersRoundTripper() http.RoundTripper {
+	var crt http.RoundTripper = n.getTransport()
+	crt = middleware.NewRecordReplayMiddleware(crt, n.config, n.recorders)
+	crt = middleware.NewHarMiddleware(crt, n.config, n.harWriters)
+	crt = n.hooks.wrap(crt)
+	crt = middleware.NewRateLimitMiddleware(crt, n.config, n.rateLimiter, n.getRateLimitRules()...)
+	crt = n.configureRetries(crt)
+	crt = n.configureCircuitBreaker(crt)
+	crt, cache := n.configureCache(crt)
+	crt = n.configureOffline(crt, cache)
+	crt = n.configureTimeout(crt)
 	rt := defaultHeadersRoundTripper{
 		networkAccess:            n,
 		encapsulatedRoundTripper: crt,
@@ -224,21 +296,203 @@ func (n *networkImpl) getUnauthorizedRoundTripper() http.RoundTripper {
 	return &rt
 }
 
+func (n *networkImpl) getUnauthorizedRoundTripper() http.RoundTripper {
+	return n.configureResponseHandling(n.getDefaultHeadersRoundTripper())
+}
+
+// configureResponseHandling maps failed responses to errors via the error handler, if one is set. It needs to be the
+// outermost middleware, so that other middlewares, e.g. the retry of rejected authentication, still see the responses.
+func (n *networkImpl) configureResponseHandling(roundTripper http.RoundTripper) http.RoundTripper {
+	if n.errorHandler == nil {
+		return roundTripper
+	}
+	return middleware.NewReponseMiddleware(roundTripper, n.config, n.errorHandler).WithErrorMappers(n.errorMappers)
+}
+
+// configureRetries adds the retry middleware if more than one attempt is configured via MAX_RETRY_ATTEMPTS.
+func (n *networkImpl) configureRetries(roundTripper http.RoundTripper) http.RoundTripper {
+	maxAttempts := n.config.GetInt(configuration.MAX_RETRY_ATTEMPTS)
+	if maxAttempts < 2 {
+		return roundTripper
+	}
+
+	options := middleware.DefaultRetryOptions()
+	options.MaxAttempts = maxAttempts
+	options.OnRetry = func(attempt int, request *http.Request, response *http.Response, err error, delay time.Duration) {
+		LogRequest(request, n.logger)
+		LogResponse(response, n.logger)
+		if err != nil {
+			n.logger.WithLevel(defaultNetworkLogLevel).Msgf("< error: %s", err.Error())
+		}
+		n.logger.WithLevel(defaultNetworkLogLevel).Msgf("< request [%p]: attempt %d/%d failed, retrying in %s", request, attempt, maxAttempts, delay)
+	}
+	return middleware.NewRetryMiddleware(roundTripper, options)
+}
+
+// configureCircuitBreaker adds the circuit breaker if a threshold is configured via CIRCUIT_BREAKER_THRESHOLD. It wraps
+// the retry middleware, so that a request that failed after all retries counts as a single failure.
+func (n *networkImpl) configureCircuitBreaker(roundTripper http.RoundTripper) http.RoundTripper {
+	threshold := n.config.GetInt(configuration.CIRCUIT_BREAKER_THRESHOLD)
+	if threshold < 1 {
+		return roundTripper
+	}
+
+	cooldown := time.Duration(n.config.GetInt(configuration.CIRCUIT_BREAKER_COOLDOWN_SECS)) * time.Second
+	return middleware.NewCircuitBreakerMiddleware(roundTripper, n.circuitBreaker, threshold, cooldown)
+}
+
+// configureCache adds the response cache if enabled via HTTP_CACHE_ENABLED and returns it, nil if disabled. Cached
+// responses are served without consuming rate limits or affecting the circuit breaker.
+func (n *networkImpl) configureCache(roundTripper http.RoundTripper) (http.RoundTripper, *middleware.CacheMiddleware) {
+	cacheDirectory, enabled := n.getCacheDirectory()
+	if !enabled {
+		return roundTripper, nil
+	}
+
+	cache := middleware.NewCacheMiddleware(roundTripper, cacheDirectory)
+	return cache, cache
+}
+
+// configureOffline fails requests fast while OFFLINE is enabled, without consuming rate limits or affecting the
+// circuit breaker. GET requests are answered with stale responses of the given cache instead, if it is enabled. It
+// wraps the cache, so that the stale responses are not stored again as if they had just been received.
+func (n *networkImpl) configureOffline(roundTripper http.RoundTripper, cache *middleware.CacheMiddleware) http.RoundTripper {
+	return middleware.NewOfflineMiddleware(roundTripper, n.config, cache)
+}
+
+// getCacheDirectory returns the directory of the response cache and whether the cache is enabled.
+func (n *networkImpl) getCacheDirectory() (string, bool) {
+	cachePath := n.config.GetString(configuration.CACHE_PATH)
+	if !n.config.GetBool(configuration.HTTP_CACHE_ENABLED) || len(cachePath) == 0 {
+		return "", false
+	}
+	return filepath.Join(cachePath, httpCacheDirectory), true
+}
+
+// configureTimeout limits the overall duration of requests via TIMEOUT and HOST_TIMEOUTS and converts timeouts into
+// catalog errors naming the endpoint.
+func (n *networkImpl) configureTimeout(roundTripper http.RoundTripper) http.RoundTripper {
+	hostTimeouts := map[string]time.Duration{}
+	for _, entry := range n.config.GetStringSlice(configuration.HOST_TIMEOUTS) {
+		host, secondsString, found := strings.Cut(entry, "=")
+		seconds, err := strconv.Atoi(strings.TrimSpace(secondsString))
+		if !found || err != nil || len(strings.TrimSpace(host)) == 0 {
+			n.logger.Printf("Ignoring invalid host timeout '%s', expected host=seconds", entry)
+			continue
+		}
+		hostTimeouts[strings.TrimSpace(host)] = time.Duration(seconds) * time.Second
+	}
+
+	timeout := time.Duration(n.config.GetInt(configuration.TIMEOUT)) * time.Second
+	return middleware.NewTimeoutMiddleware(roundTripper, timeout, hostTimeouts)
+}
+
+// getRateLimitRules returns the rate limits configured via RATE_LIMITS, they take precedence over rules added via
+// AddRateLimit for the same prefix.
+func (n *networkImpl) getRateLimitRules() []middleware.RateLimitRule {
+	burst := n.config.GetInt(configuration.RATE_LIMIT_BURST)
+	rules := []middleware.RateLimitRule{}
+	for _, entry := range n.config.GetStringSlice(configuration.RATE_LIMITS) {
+		rule, err := middleware.ParseRateLimitRule(entry, burst)
+		if err != nil {
+			n.logger.Printf("Ignoring %v", err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
 func (n *networkImpl) GetRoundTripper() http.RoundTripper {
-	rt := n.getUnauthorizedRoundTripper()
-	return middleware.NewAuthHeaderMiddleware(n.config, n.GetAuthenticator(), rt)
+	rt := middleware.NewAuthHeaderMiddleware(n.config, n.GetAuthenticator(), n.getDefaultHeadersRoundTripper())
+	return n.configureResponseHandling(rt)
 }
 
 func (n *networkImpl) configureRoundTripper(base *http.Transport) *http.Transport {
-	// configure insecure
-	insecure := n.config.GetBool(configuration.INSECURE_HTTPS)
-	authenticationMechanism := httpauth.AuthenticationMechanismFromString(n.config.GetString(configuration.PROXY_AUTHENTICATION_MECHANISM))
+	proxy, _ := n.getProxy() //nolint:errcheck // the returned proxy fails requests if it couldn't be configured
+	return n.createTransport(base, n.getTransportSettings(), proxy)
+}
+
+// getTransport returns the shared transport of the current configuration, so that connections are reused by all
+// clones and clients. A transport whose proxy couldn't be configured isn't shared, to retry the configuration.
+func (n *networkImpl) getTransport() *http.Transport {
+	//nolint:errcheck // breaking api change needed to fix this
+	base := http.DefaultTransport.(*http.Transport) //nolint:forcetypeassert // panic here is reasonable
+	proxy, err := n.getProxy()
+	if err != nil {
+		return n.createTransport(base, n.getTransportSettings(), proxy)
+	}
+
+	return n.transports.get(n.getTransportSettings(), func(settings transportSettings) *http.Transport {
+		return n.createTransport(base, settings, proxy)
+	})
+}
+
+// getTransportSettings collects all configuration values that affect the transport.
+func (n *networkImpl) getTransportSettings() transportSettings {
+	proxyOptions := n.getProxyOptions()
+	return transportSettings{
+		insecure:          n.config.GetBool(configuration.INSECURE_HTTPS),
+		caPool:            n.caPool,
+		clientCertificate: n.getClientCertificateLoader(),
+		options: transportOptions{
+			proxyUrl:            proxyOptions.ProxyUrl,
+			noProxy:             strings.Join(proxyOptions.NoProxy, ","),
+			pacLocation:         proxyOptions.PacLocation,
+			proxyAuthentication: n.config.GetString(configuration.PROXY_AUTHENTICATION_MECHANISM),
+			apiUrl:              n.config.GetString(configuration.API_URL),
+			spkiPins:            strings.Join(n.config.GetStringSlice(configuration.API_SPKI_PINS), ","),
+			timeouts: middleware.TransportTimeouts{
+				Connect:        time.Duration(n.config.GetInt(configuration.CONNECT_TIMEOUT_SECS)) * time.Second,
+				TlsHandshake:   time.Duration(n.config.GetInt(configuration.TLS_HANDSHAKE_TIMEOUT_SECS)) * time.Second,
+				ResponseHeader: time.Duration(n.config.GetInt(configuration.RESPONSE_HEADER_TIMEOUT_SECS)) * time.Second,
+			},
+			connectionPoolSettings: middleware.ConnectionPoolSettings{
+				MaxIdleConnections:        n.config.GetInt(configuration.MAX_IDLE_CONNECTIONS),
+				MaxIdleConnectionsPerHost: n.config.GetInt(configuration.MAX_IDLE_CONNECTIONS_PER_HOST),
+				IdleConnectionTimeout:     time.Duration(n.config.GetInt(configuration.IDLE_CONNECTION_TIMEOUT_SECS)) * time.Second,
+				DisableHttp2:              n.config.GetBool(configuration.HTTP2_DISABLED),
+				Http2ReadIdleTimeout:      time.Duration(n.config.GetInt(configuration.HTTP2_READ_IDLE_TIMEOUT_SECS)) * time.Second,
+				Http2PingTimeout:          time.Duration(n.config.GetInt(configuration.HTTP2_PING_TIMEOUT_SECS)) * time.Second,
+			},
+		},
+	}
+}
+
+func (n *networkImpl) createTransport(base *http.Transport, settings transportSettings, proxy func(*http.Request) (*url.URL, error)) *http.Transport {
+	authenticationMechanism := httpauth.AuthenticationMechanismFromString(settings.options.proxyAuthentication)
 	transport := base.Clone()
-	transport = middleware.ApplyTlsConfig(transport, insecure, n.caPool)
-	transport = middleware.ConfigureProxy(transport, n.logger, n.proxy, authenticationMechanism)
+	tlsOptions := []middleware.TlsOption{}
+	if settings.clientCertificate != nil {
+		tlsOptions = append(tlsOptions, middleware.WithClientCertificate(settings.clientCertificate.GetClientCertificate))
+	}
+	if verifyPins := n.getSpkiPinVerifier(); verifyPins != nil {
+		tlsOptions = append(tlsOptions, middleware.WithVerifyConnection(verifyPins))
+	}
+	transport = middleware.ApplyTlsConfig(transport, settings.insecure, settings.caPool, tlsOptions...)
+	transport = middleware.ConfigureProxy(transport, n.logger, proxy, authenticationMechanism)
+	transport = middleware.ApplyTransportTimeouts(transport, settings.options.timeouts)
+	transport = middleware.ApplyConnectionPoolSettings(transport, settings.options.connectionPoolSettings)
 	return transport
 }
 
+// getSpkiPinVerifier returns the verification of the API_SPKI_PINS for the API host, or nil if no pins are configured.
+func (n *networkImpl) getSpkiPinVerifier() func(tls.ConnectionState) error {
+	pins := n.config.GetStringSlice(configuration.API_SPKI_PINS)
+	if len(pins) == 0 {
+		return nil
+	}
+
+	apiUrl, err := url.Parse(n.config.GetString(configuration.API_URL))
+	if err != nil || len(apiUrl.Hostname()) == 0 {
+		n.logger.Printf("Failed to determine the API host for public key pinning (%v)", err)
+		return func(tls.ConnectionState) error {
+			return fmt.Errorf("public key pins are configured, but the API host is unknown")
+		}
+	}
+	return certs.NewSpkiPinVerifier(apiUrl.Hostname(), pins)
+}
+
 func (n *networkImpl) GetHttpClient() *http.Client {
 	client := *http.DefaultClient
 	client.Transport = n.GetRoundTripper()
@@ -251,12 +505,18 @@ func (n *networkImpl) GetUnauthorizedHttpClient() *http.Client {
 	return &client
 }
 
+// AddRootCAs adds the CAs of the given PEM file or directory to the system CAs, or, if TRUSTED_CA_EXCLUSIVE is set,
+// to a pool that only contains the explicitly added CAs. Valid certificates are added even if others fail to parse.
 func (n *networkImpl) AddRootCAs(pemFileLocation string) error {
 	var err error
 
 	if len(pemFileLocation) > 0 {
 		if n.caPool == nil {
-			n.caPool, err = x509.SystemCertPool()
+			if n.config.GetBool(configuration.TRUSTED_CA_EXCLUSIVE) {
+				n.caPool = x509.NewCertPool()
+			} else {
+				n.caPool, err = x509.SystemCertPool()
+			}
 		}
 
 		if err == nil {
@@ -272,6 +532,44 @@ func (n *networkImpl) GetAuthenticator() auth.Authenticator {
 	return auth.CreateAuthenticator(n.config, authClient)
 }
 
+// AddRateLimit limits the requests to the given host, e.g. "deeproxy.snyk.io", or URL prefix. Requests to Snyk hosts
+// without a more specific limit are limited by RATE_LIMIT_REQUESTS_PER_SECOND.
+func (n *networkImpl) AddErrorMapper(pathPrefix string, mapper middleware.ErrorMapperFunc) {
+	n.errorMappers.Register(pathPrefix, mapper)
+}
+
+func (n *networkImpl) AddRateLimit(prefix string, requestsPerSecond float64, burst int) {
+	n.rateLimiter.AddRule(middleware.RateLimitRule{Prefix: prefix, RequestsPerSecond: requestsPerSecond, Burst: burst})
+}
+
+func (n *networkImpl) SetPacEvaluatorFactory(factory middleware.PacEvaluatorFactory) {
+	n.proxyResolver.setPacEvaluatorFactory(factory)
+}
+
+func (n *networkImpl) GetCircuitBreakerStatus() []middleware.CircuitStatus {
+	return n.circuitBreaker.GetStatus()
+}
+
+func (n *networkImpl) AddRequestHook(hook RequestHookFunc) {
+	n.hooks.requestHooks = append(n.hooks.requestHooks, hook)
+}
+
+func (n *networkImpl) AddResponseHook(hook ResponseHookFunc) {
+	n.hooks.responseHooks = append(n.hooks.responseHooks, hook)
+}
+
+func (n *networkImpl) AddMiddleware(middlewareFunc MiddlewareFunc) {
+	n.hooks.middlewares = append(n.hooks.middlewares, middlewareFunc)
+}
+
+func (n *networkImpl) SetCorrelationId(correlationId string) {
+	n.correlationId = correlationId
+}
+
+func (n *networkImpl) GetCorrelationId() string {
+	return n.correlationId
+}
+
 func (n *networkImpl) SetLogger(logger *zerolog.Logger) {
 	n.logger = logger
 }
@@ -290,12 +588,22 @@ func (n *networkImpl) GetConfiguration() configuration.Configuration {
 
 func (n *networkImpl) Clone() NetworkAccess {
 	clone := &networkImpl{
-		config:         n.config.Clone(),
-		logger:         n.logger,
-		staticHeader:   n.staticHeader.Clone(),
-		dynamicHeaders: map[string]DynamicHeaderFunc{},
-		proxy:          n.proxy,
-		errorHandler:   n.errorHandler,
+		config:             n.config.Clone(),
+		logger:             n.logger,
+		staticHeader:       n.staticHeader.Clone(),
+		dynamicHeaders:     map[string]DynamicHeaderFunc{},
+		proxy:              n.proxy,
+		errorHandler:       n.errorHandler,
+		errorMappers:       n.errorMappers,
+		rateLimiter:        n.rateLimiter,
+		circuitBreaker:     n.circuitBreaker,
+		recorders:          n.recorders,
+		harWriters:         n.harWriters,
+		proxyResolver:      n.proxyResolver,
+		clientCertificates: n.clientCertificates,
+		hooks:              n.hooks.clone(),
+		transports:         n.transports,
+		correlationId:      n.correlationId,
 	}
 
 	for key, dynHeaderFuncs := range n.dynamicHeaders {
@@ -3,7 +3,11 @@ package networking
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -12,11 +16,14 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/snyk/error-catalog-golang-public/snyk"
+	"github.com/snyk/error-catalog-golang-public/snyk_errors"
 	"github.com/snyk/go-httpauth/pkg/httpauth"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/oauth2"
@@ -25,6 +32,7 @@ import (
 	"github.com/snyk/go-application-framework/pkg/auth"
 	"github.com/snyk/go-application-framework/pkg/configuration"
 	"github.com/snyk/go-application-framework/pkg/networking/certs"
+	"github.com/snyk/go-application-framework/pkg/networking/middleware"
 )
 
 func getConfig() configuration.Configuration {
@@ -288,6 +296,12 @@ func Test_AddHeaders_AddsDefaultAndAuthHeaders(t *testing.T) {
 	err = net.AddHeaders(request)
 	assert.NoError(t, err)
 
+	// correlation headers are added to all requests
+	assert.Equal(t, net.GetCorrelationId(), request.Header.Get(CorrelationIdHeader))
+	assert.NotEmpty(t, request.Header.Get(RequestIdHeader))
+	request.Header.Del(CorrelationIdHeader)
+	request.Header.Del(RequestIdHeader)
+
 	assert.Equal(t, expectedHeader, request.Header)
 }
 
@@ -558,3 +572,756 @@ func TestNetworkImpl_ErrorHandler(t *testing.T) {
 		assert.ErrorAs(t, err, &expectedErr)
 	})
 }
+
+func Test_HttpClient_ErrorMappersAreSharedWithClones(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	config := getConfig()
+	config.Set(configuration.AUTHENTICATION_ADDITIONAL_URLS, []string{server.URL})
+	expectedErr := snyk.NewNotImplementedError("extension endpoint")
+
+	net := NewNetworkAccess(config)
+	net.AddErrorHandler(func(err error, ctx context.Context) error {
+		return err
+	})
+	clone := net.Clone()
+	net.AddErrorMapper("/extension", func(res *http.Response) error {
+		return expectedErr
+	})
+
+	var snykError snyk_errors.Error
+	_, err := clone.GetUnauthorizedHttpClient().Get(server.URL + "/extension/resource")
+	assert.ErrorAs(t, err, &snykError)
+	assert.Equal(t, expectedErr.ErrorCode, snykError.ErrorCode)
+
+	// mappers of other network accesses are not applied
+	other := NewNetworkAccess(config)
+	other.AddErrorHandler(func(err error, ctx context.Context) error {
+		return err
+	})
+	_, err = other.GetUnauthorizedHttpClient().Get(server.URL + "/extension/resource")
+	assert.ErrorAs(t, err, &snykError)
+	assert.Equal(t, http.StatusNotFound, snykError.StatusCode)
+	assert.NotEqual(t, expectedErr.ErrorCode, snykError.ErrorCode)
+}
+
+func Test_HttpClient_RetriesFailedRequests(t *testing.T) {
+	requests := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	logBuffer := bytes.NewBuffer([]byte{})
+	logger := zerolog.New(logBuffer).Level(zerolog.DebugLevel)
+
+	config := getConfig()
+	net := NewNetworkAccess(config)
+	net.SetLogger(&logger)
+
+	t.Run("retries are disabled by default", func(t *testing.T) {
+		res, err := net.GetUnauthorizedHttpClient().Get(server.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	})
+
+	t.Run("retries if configured", func(t *testing.T) {
+		requests = 0
+		config.Set(configuration.MAX_RETRY_ATTEMPTS, 3)
+
+		res, err := net.GetUnauthorizedHttpClient().Get(server.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, 2, requests)
+		assert.Contains(t, logBuffer.String(), "attempt 1/3 failed")
+	})
+}
+
+func Test_HttpClient_RateLimitIsSharedWithClones(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	serverUrl, err := url.Parse(server.URL)
+	assert.NoError(t, err)
+
+	net := NewNetworkAccess(getConfig())
+	net.AddRateLimit(serverUrl.Host, 0.001, 1)
+	clone := net.Clone()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, http.NoBody)
+	assert.NoError(t, err)
+
+	res, err := net.GetUnauthorizedHttpClient().Do(request)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	_ = res.Body.Close()
+
+	_, err = clone.GetUnauthorizedHttpClient().Do(request)
+	var snykError snyk_errors.Error
+	assert.ErrorAs(t, err, &snykError)
+	assert.Equal(t, http.StatusTooManyRequests, snykError.StatusCode)
+}
+
+func Test_HttpClient_ConfiguredRateLimits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := getConfig()
+	config.Set(configuration.RATE_LIMITS, []string{"invalid", server.URL + "/limited=0.001"})
+	net := NewNetworkAccess(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for _, path := range []string{"/other", "/other", "/limited"} {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path, http.NoBody)
+		assert.NoError(t, err)
+		res, err := net.GetUnauthorizedHttpClient().Do(request)
+		assert.NoError(t, err)
+		if res != nil {
+			_ = res.Body.Close()
+		}
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/limited/orgs", http.NoBody)
+	assert.NoError(t, err)
+	_, err = net.GetUnauthorizedHttpClient().Do(request)
+	var snykError snyk_errors.Error
+	assert.ErrorAs(t, err, &snykError)
+	assert.Equal(t, http.StatusTooManyRequests, snykError.StatusCode)
+}
+
+func Test_HttpClient_CircuitBreakerIsSharedWithClones(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	config := getConfig()
+	config.Set(configuration.CIRCUIT_BREAKER_THRESHOLD, 1)
+	config.Set(configuration.CIRCUIT_BREAKER_COOLDOWN_SECS, 60)
+	net := NewNetworkAccess(config)
+	clone := net.Clone()
+
+	res, err := net.GetUnauthorizedHttpClient().Get(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, res.StatusCode)
+	_ = res.Body.Close()
+
+	_, err = clone.GetUnauthorizedHttpClient().Get(server.URL)
+	var snykError snyk_errors.Error
+	assert.ErrorAs(t, err, &snykError)
+
+	status := clone.GetCircuitBreakerStatus()
+	assert.Len(t, status, 1)
+	assert.Equal(t, middleware.CircuitOpen, status[0].State)
+}
+
+func Test_HttpClient_ReplaysRecordedInteractions(t *testing.T) {
+	fixture := filepath.Join(t.TempDir(), "fixture.json")
+	err := os.WriteFile(fixture, []byte(`{"interactions":[{"request":{"method":"GET","url":"https://api.snyk.io/rest/self"},"response":{"status_code":200,"body":"recorded"}}]}`), 0600)
+	assert.NoError(t, err)
+
+	config := getConfig()
+	config.Set(configuration.RECORD_REPLAY_MODE, middleware.RecordReplayModeReplay)
+	config.Set(configuration.RECORD_REPLAY_FILE, fixture)
+	net := NewNetworkAccess(config)
+
+	res, err := net.GetUnauthorizedHttpClient().Get("https://api.snyk.io/rest/self")
+	assert.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "recorded", string(body))
+
+	t.Run("replays are independent per network access", func(t *testing.T) {
+		err = os.WriteFile(fixture, []byte(`{"interactions":[`+
+			`{"request":{"method":"GET","url":"https://api.snyk.io/rest/orgs"},"response":{"status_code":200,"body":"first"}},`+
+			`{"request":{"method":"GET","url":"https://api.snyk.io/rest/orgs"},"response":{"status_code":200,"body":"second"}}]}`), 0600)
+		assert.NoError(t, err)
+
+		for range 2 {
+			res, err = NewNetworkAccess(config).GetUnauthorizedHttpClient().Get("https://api.snyk.io/rest/orgs")
+			assert.NoError(t, err)
+			body, err = io.ReadAll(res.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, "first", string(body))
+		}
+	})
+}
+
+func Test_HttpClient_CachesResponsesIfEnabled(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := getConfig()
+	config.Set(configuration.CACHE_PATH, t.TempDir())
+	net := NewNetworkAccess(config)
+
+	for range 2 {
+		res, err := net.GetUnauthorizedHttpClient().Get(server.URL)
+		assert.NoError(t, err)
+		_ = res.Body.Close()
+	}
+	assert.Equal(t, 2, requests)
+
+	requests = 0
+	config.Set(configuration.HTTP_CACHE_ENABLED, true)
+	for range 2 {
+		res, err := net.GetUnauthorizedHttpClient().Get(server.URL)
+		assert.NoError(t, err)
+		_ = res.Body.Close()
+	}
+	assert.Equal(t, 1, requests)
+	assert.DirExists(t, filepath.Join(config.GetString(configuration.CACHE_PATH), httpCacheDirectory))
+}
+
+func Test_HttpClient_UsesConfiguredProxy(t *testing.T) {
+	var proxiedUrl string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedUrl = r.URL.String()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	config := getConfig()
+	config.Set(configuration.PROXY_URL, proxy.URL)
+	config.Set(configuration.PROXY_NO_PROXY, []string{"direct.invalid"})
+	net := NewNetworkAccess(config)
+
+	res, err := net.Clone().GetUnauthorizedHttpClient().Get("http://proxied.invalid/path")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "http://proxied.invalid/path", proxiedUrl)
+
+	// excluded hosts are connected to directly, which fails for the invalid domain
+	_, err = net.GetUnauthorizedHttpClient().Get("http://direct.invalid/path")
+	assert.Error(t, err)
+
+	t.Run("invalid PAC script fails requests", func(t *testing.T) {
+		config.Unset(configuration.PROXY_URL)
+		config.Set(configuration.PROXY_PAC, filepath.Join(t.TempDir(), "missing.pac"))
+
+		_, err = net.GetUnauthorizedHttpClient().Get("http://proxied.invalid/path")
+		assert.ErrorContains(t, err, "failed to load PAC script")
+	})
+}
+
+func Test_ResolveProxy_LoadsPacScript(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hanging.pac" {
+			<-release
+			return
+		}
+		_, err := w.Write([]byte(`function FindProxyForURL(url, host) { return "PROXY proxy.example.com:8080"; }`))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	request, err := http.NewRequest(http.MethodGet, "https://api.snyk.io", http.NoBody)
+	assert.NoError(t, err)
+
+	// the PAC script is loaded with the configured TLS settings
+	config := getConfig()
+	config.Set(configuration.INSECURE_HTTPS, true)
+	config.Set(configuration.PROXY_PAC, server.URL+"/proxy.pac")
+	net := NewNetworkAccess(config)
+
+	proxyUrl, err := ResolveProxy(net, request)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "http://proxy.example.com:8080", proxyUrl.String())
+	}
+
+	t.Run("loading a PAC script doesn't block other clones", func(t *testing.T) {
+		hangingConfig := config.Clone()
+		hangingConfig.Set(configuration.PROXY_PAC, server.URL+"/hanging.pac")
+		hangingNet := net.Clone()
+		hangingNet.SetConfiguration(hangingConfig)
+		go func() {
+			_, _ = ResolveProxy(hangingNet, request) //nolint:errcheck // the result doesn't matter
+		}()
+
+		proxyConfig := config.Clone()
+		proxyConfig.Set(configuration.PROXY_URL, "http://other-proxy.example.com:3128")
+		proxyNet := net.Clone()
+		proxyNet.SetConfiguration(proxyConfig)
+
+		resolved := make(chan *url.URL)
+		go func() {
+			proxyUrl, _ := ResolveProxy(proxyNet, request) //nolint:errcheck // checked via the result
+			resolved <- proxyUrl
+		}()
+
+		select {
+		case proxyUrl = <-resolved:
+			assert.Equal(t, "http://other-proxy.example.com:3128", proxyUrl.String())
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "resolving the proxy is blocked by loading the PAC script")
+		}
+	})
+}
+
+func Test_HttpClient_UsesClientCertificate(t *testing.T) {
+	var clientSubject string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientSubject = r.TLS.PeerCertificates[0].Subject.CommonName
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	config := getConfig()
+	config.Set(configuration.INSECURE_HTTPS, true)
+	net := NewNetworkAccess(config)
+
+	// without client certificate the handshake fails
+	_, err := net.GetUnauthorizedHttpClient().Get(server.URL)
+	assert.Error(t, err)
+
+	certPem, keyPem, err := certs.MakeSelfSignedCert("client", []string{"localhost"}, log.Default())
+	assert.NoError(t, err)
+	certFile := filepath.Join(t.TempDir(), "client.crt")
+	keyFile := filepath.Join(t.TempDir(), "client.key")
+	assert.NoError(t, os.WriteFile(certFile, certPem, 0o600))
+	assert.NoError(t, os.WriteFile(keyFile, keyPem, 0o600))
+
+	config.Set(configuration.TLS_CLIENT_CERTIFICATE_FILE, certFile)
+	config.Set(configuration.TLS_CLIENT_KEY_FILE, keyFile)
+
+	res, err := net.Clone().GetUnauthorizedHttpClient().Get(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "client", clientSubject)
+	_ = res.Body.Close()
+}
+
+func Test_HttpClient_TrustsExclusiveCaBundleAndPins(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	caDir := t.TempDir()
+	serverCertPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.NoError(t, os.WriteFile(filepath.Join(caDir, "server.pem"), serverCertPem, 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(caDir, "broken.pem"), []byte("invalid"), 0o600))
+
+	config := getConfig()
+	config.Set(configuration.API_URL, server.URL)
+	config.Set(configuration.TRUSTED_CA_EXCLUSIVE, true)
+	config.Set(configuration.ADD_TRUSTED_CA_LOCATIONS, []string{caDir})
+	net := NewNetworkAccess(config)
+
+	// the broken file doesn't prevent the valid certificate from being trusted
+	res, err := net.GetUnauthorizedHttpClient().Get(server.URL)
+	assert.NoError(t, err)
+	_ = res.Body.Close()
+
+	t.Run("matching pin", func(t *testing.T) {
+		config.Set(configuration.API_SPKI_PINS, []string{certs.SpkiHash(server.Certificate())})
+		res, err = net.GetUnauthorizedHttpClient().Get(server.URL)
+		assert.NoError(t, err)
+		_ = res.Body.Close()
+	})
+
+	t.Run("mismatching pin", func(t *testing.T) {
+		config.Set(configuration.API_SPKI_PINS, []string{"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="})
+		_, err = net.GetUnauthorizedHttpClient().Get(server.URL)
+		assert.ErrorContains(t, err, "public key pins")
+	})
+
+	t.Run("exclusive bundle doesn't contain system CAs", func(t *testing.T) {
+		exclusiveConfig := getConfig()
+		exclusiveConfig.Set(configuration.TRUSTED_CA_EXCLUSIVE, true)
+		exclusiveNet := NewNetworkAccess(exclusiveConfig)
+		assert.NoError(t, exclusiveNet.AddRootCAs(filepath.Join(caDir, "server.pem")))
+
+		impl, ok := exclusiveNet.(*networkImpl)
+		assert.True(t, ok)
+		systemPool, poolErr := x509.SystemCertPool()
+		assert.NoError(t, poolErr)
+		assert.False(t, impl.caPool.Equal(systemPool))
+		assert.Len(t, impl.caPool.Subjects(), 1) //nolint:staticcheck // only used to count the certificates
+	})
+}
+
+func Test_HttpClient_WritesHarFileIfConfigured(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	harFile := filepath.Join(t.TempDir(), "traffic.har")
+	config := getConfig()
+	config.Set(configuration.HAR_FILE, harFile)
+	net := NewNetworkAccess(config)
+
+	for i := 0; i < 2; i++ {
+		res, err := net.Clone().GetUnauthorizedHttpClient().Get(server.URL)
+		assert.NoError(t, err)
+		_ = res.Body.Close()
+	}
+
+	data, err := os.ReadFile(harFile)
+	assert.NoError(t, err)
+	har := middleware.HarFile{}
+	assert.NoError(t, json.Unmarshal(data, &har))
+	assert.Len(t, har.Log.Entries, 2)
+}
+
+func Test_HttpClient_HooksAndMiddlewares(t *testing.T) {
+	var receivedSignature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedSignature = r.Header.Get("X-Signature")
+		w.Header().Set("X-Server", "test")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	calls := []string{}
+	net := NewNetworkAccess(getConfig())
+	net.AddMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			calls = append(calls, "outer middleware")
+			return next.RoundTrip(request)
+		})
+	})
+	net.AddMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			calls = append(calls, "inner middleware")
+			return next.RoundTrip(request)
+		})
+	})
+	net.AddRequestHook(func(request *http.Request) error {
+		calls = append(calls, "first request hook")
+		request.Header.Set("X-Signature", "signed")
+		return nil
+	})
+	net.AddRequestHook(func(request *http.Request) error {
+		calls = append(calls, "second request hook")
+		return nil
+	})
+	net.AddResponseHook(func(response *http.Response) error {
+		calls = append(calls, "response hook "+response.Header.Get("X-Server"))
+		return nil
+	})
+
+	clone := net.Clone()
+	clone.AddResponseHook(func(response *http.Response) error {
+		return fmt.Errorf("rejected by clone")
+	})
+
+	request, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+	res, err := net.GetUnauthorizedHttpClient().Do(request)
+	assert.NoError(t, err)
+	_ = res.Body.Close()
+
+	assert.Equal(t, "signed", receivedSignature)
+	assert.Empty(t, request.Header.Get("X-Signature"))
+	assert.Equal(t, []string{"outer middleware", "inner middleware", "first request hook", "second request hook", "response hook test"}, calls)
+
+	// clones keep the hooks of the original, but hooks added to a clone don't affect the original
+	calls = []string{}
+	_, err = clone.GetHttpClient().Get(server.URL)
+	assert.ErrorContains(t, err, "rejected by clone")
+	assert.Len(t, calls, 5)
+
+	t.Run("failing request hook prevents the request", func(t *testing.T) {
+		receivedSignature = ""
+		failing := net.Clone()
+		failing.AddRequestHook(func(request *http.Request) error {
+			return fmt.Errorf("not signed")
+		})
+		_, err = failing.GetUnauthorizedHttpClient().Get(server.URL)
+		assert.ErrorContains(t, err, "not signed")
+		assert.Empty(t, receivedSignature)
+	})
+}
+
+type roundTripperFunc func(request *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+func Test_HttpClient_AppliesConfiguredTimeouts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(1500 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := getConfig()
+	config.Set(configuration.TIMEOUT, 1)
+	config.Set(configuration.MAX_RETRY_ATTEMPTS, 1)
+	net := NewNetworkAccess(config)
+
+	_, err := net.GetUnauthorizedHttpClient().Get(server.URL + "/slow")
+	catalogErr := snyk_errors.Error{}
+	assert.True(t, errors.As(err, &catalogErr))
+	assert.Equal(t, server.URL+"/slow", catalogErr.Meta["endpoint"])
+
+	config.Set(configuration.HOST_TIMEOUTS, []string{"127.0.0.1=5", "invalid"})
+	res, err := net.GetUnauthorizedHttpClient().Get(server.URL + "/slow")
+	assert.NoError(t, err)
+	_ = res.Body.Close()
+}
+
+func Test_HttpClient_SharesConnectionsAcrossClones(t *testing.T) {
+	var newConnections atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			newConnections.Add(1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	config := getConfig()
+	config.Set(configuration.INSECURE_HTTPS, true)
+	networkAccess := NewNetworkAccess(config)
+
+	get := func(networkAccess NetworkAccess) {
+		t.Helper()
+		res, err := networkAccess.GetUnauthorizedHttpClient().Get(server.URL)
+		assert.NoError(t, err)
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+	}
+
+	// clones with equal settings, e.g. one per workflow invocation, reuse the connection
+	for range 3 {
+		get(networkAccess.Clone())
+	}
+	get(networkAccess)
+	assert.Equal(t, int32(1), newConnections.Load())
+
+	// a clone with different transport settings uses its own connection
+	clone := networkAccess.Clone()
+	clone.GetConfiguration().Set(configuration.TLS_HANDSHAKE_TIMEOUT_SECS, 5)
+	get(clone)
+	get(clone)
+	assert.Equal(t, int32(2), newConnections.Load())
+
+	// adding CAs after the transport was created doesn't affect the shared transport
+	certPem, _, err := certs.MakeSelfSignedCert("extra", []string{"localhost"}, log.Default())
+	assert.NoError(t, err)
+	caFile := filepath.Join(t.TempDir(), "extra.pem")
+	assert.NoError(t, os.WriteFile(caFile, certPem, 0o600))
+	assert.NoError(t, clone.AddRootCAs(caFile))
+	get(clone)
+	assert.Equal(t, int32(3), newConnections.Load())
+	get(networkAccess)
+	assert.Equal(t, int32(3), newConnections.Load())
+}
+
+func Test_HttpClient_AppliesHttp2Settings(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	config := getConfig()
+	config.Set(configuration.INSECURE_HTTPS, true)
+	config.Set(configuration.HTTP2_READ_IDLE_TIMEOUT_SECS, 30)
+	networkAccess := NewNetworkAccess(config)
+
+	getProto := func() string {
+		t.Helper()
+		res, err := networkAccess.GetUnauthorizedHttpClient().Get(server.URL)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		assert.NoError(t, err)
+		return string(body)
+	}
+
+	assert.Equal(t, "HTTP/2.0", getProto())
+
+	config.Set(configuration.HTTP2_DISABLED, true)
+	assert.Equal(t, "HTTP/1.1", getProto())
+}
+
+func Test_HttpClient_AddsCorrelationAndRequestIds(t *testing.T) {
+	requests := []http.Header{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Clone())
+		if r.URL.Path == "/retry" && len(requests) == 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := getConfig()
+	config.Set(configuration.MAX_RETRY_ATTEMPTS, 2)
+	networkAccess := NewNetworkAccess(config)
+	clone := networkAccess.Clone()
+
+	for _, path := range []string{"/", "/retry"} {
+		res, err := clone.GetUnauthorizedHttpClient().Get(server.URL + path)
+		assert.NoError(t, err)
+		_ = res.Body.Close()
+	}
+
+	assert.Len(t, requests, 3)
+	for _, header := range requests {
+		assert.Equal(t, networkAccess.GetCorrelationId(), header.Get(CorrelationIdHeader))
+	}
+	assert.NotEmpty(t, requests[0].Get(RequestIdHeader))
+	assert.NotEqual(t, requests[0].Get(RequestIdHeader), requests[1].Get(RequestIdHeader))
+	// retries keep the ID of the request
+	assert.Equal(t, requests[1].Get(RequestIdHeader), requests[2].Get(RequestIdHeader))
+
+	clone.SetCorrelationId("invocation-id")
+	request, err := http.NewRequest(http.MethodGet, server.URL, http.NoBody)
+	assert.NoError(t, err)
+	assert.NoError(t, clone.AddHeaders(request))
+	assert.Equal(t, "invocation-id", request.Header.Get(CorrelationIdHeader))
+	assert.NotEqual(t, "invocation-id", networkAccess.GetCorrelationId())
+}
+
+func Test_HttpClient_FailsFastWhileOffline(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := getConfig()
+	config.Set(configuration.OFFLINE, true)
+	config.Set(configuration.PROXY_PAC, "http://127.0.0.1:1/proxy.pac")
+	networkAccess := NewNetworkAccess(config)
+
+	_, err := networkAccess.GetHttpClient().Get(server.URL)
+	assert.ErrorIs(t, err, middleware.ErrOffline)
+	assert.Equal(t, int32(0), requests.Load())
+
+	config.Set(configuration.OFFLINE, false)
+	config.Unset(configuration.PROXY_PAC)
+	res, err := networkAccess.GetHttpClient().Get(server.URL)
+	assert.NoError(t, err)
+	_ = res.Body.Close()
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func Test_HttpClient_ServesStaleResponsesWhileOffline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=0")
+		w.Header().Set("ETag", `"v1"`)
+		_, err := w.Write([]byte("cached"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	config := getConfig()
+	config.Set(configuration.CACHE_PATH, t.TempDir())
+	config.Set(configuration.HTTP_CACHE_ENABLED, true)
+	networkAccess := NewNetworkAccess(config)
+
+	res, err := networkAccess.GetUnauthorizedHttpClient().Get(server.URL)
+	assert.NoError(t, err)
+	_ = res.Body.Close()
+
+	entries, err := filepath.Glob(filepath.Join(config.GetString(configuration.CACHE_PATH), httpCacheDirectory, "*.json"))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	stored, err := os.ReadFile(entries[0])
+	assert.NoError(t, err)
+
+	config.Set(configuration.OFFLINE, true)
+	res, err = networkAccess.GetUnauthorizedHttpClient().Get(server.URL)
+	assert.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+	_ = res.Body.Close()
+	assert.Equal(t, "cached", string(body))
+
+	// the stale response is not stored again as a fresh one
+	storedWhileOffline, err := os.ReadFile(entries[0])
+	assert.NoError(t, err)
+	assert.Equal(t, string(stored), string(storedWhileOffline))
+}
+
+func Test_HttpClient_RetriesRejectedAuthenticationWithErrorHandler(t *testing.T) {
+	var authorizations []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"access_token":"renewed","token_type":"Bearer","refresh_token":"refresh","expires_in":3600}`)
+	})
+	mux.HandleFunc("/rest/resource", func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") != "Bearer renewed" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	revokedToken, err := json.Marshal(oauth2.Token{
+		AccessToken:  "revoked",
+		TokenType:    "Bearer",
+		RefreshToken: "refresh",
+		Expiry:       time.Now().Add(time.Hour),
+	})
+	assert.NoError(t, err)
+
+	config := getConfig()
+	config.Set(configuration.API_URL, server.URL)
+	config.Set(auth.CONFIG_KEY_OAUTH_TOKEN, string(revokedToken))
+
+	net := NewNetworkAccess(config)
+	handledErrors := 0
+	net.AddErrorHandler(func(err error, ctx context.Context) error {
+		if err != nil {
+			handledErrors++
+		}
+		return err
+	})
+
+	res, err := net.GetHttpClient().Get(server.URL + "/rest/resource")
+	if assert.NoError(t, err) {
+		_ = res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+	assert.Equal(t, []string{"Bearer revoked", "Bearer renewed"}, authorizations)
+	assert.Equal(t, 0, handledErrors)
+}
@@ -0,0 +1,129 @@
+package networking
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/snyk/go-application-framework/pkg/configuration"
+	"github.com/snyk/go-application-framework/pkg/networking/middleware"
+)
+
+// proxyResolver caches the proxy function for the configured proxy options, so that PAC scripts are only loaded
+// once. It is shared by all clones of a NetworkAccess.
+type proxyResolver struct {
+	mutex      sync.Mutex
+	optionsKey string
+	proxy      middleware.ProxyFunc
+	pacFactory middleware.PacEvaluatorFactory
+	// generation changes with the PAC evaluator factory, to discard proxy functions created with the previous one
+	generation int
+	group      singleflight.Group
+}
+
+func (r *proxyResolver) setPacEvaluatorFactory(factory middleware.PacEvaluatorFactory) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.pacFactory = factory
+	r.proxy = nil
+	r.generation++
+}
+
+// resolve returns the proxy function for the options, it is only recreated if the options changed. Loading a PAC
+// script happens outside the lock, concurrent calls with the same options share the result.
+func (r *proxyResolver) resolve(options middleware.ProxyOptions) (middleware.ProxyFunc, error) {
+	key := options.ProxyUrl + "\n" + strings.Join(options.NoProxy, ",") + "\n" + options.PacLocation
+
+	r.mutex.Lock()
+	if r.proxy != nil && r.optionsKey == key {
+		proxy := r.proxy
+		r.mutex.Unlock()
+		return proxy, nil
+	}
+	options.PacEvaluatorFactory = r.pacFactory
+	generation := r.generation
+	r.mutex.Unlock()
+
+	result, err, _ := r.group.Do(fmt.Sprintf("%d\n%s", generation, key), func() (interface{}, error) {
+		return middleware.NewProxyFunc(options)
+	})
+	if err != nil {
+		return nil, err
+	}
+	proxy := result.(middleware.ProxyFunc) //nolint:forcetypeassert // the group only returns proxy functions
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.generation == generation {
+		r.proxy = proxy
+		r.optionsKey = key
+	}
+	return proxy, nil
+}
+
+// getProxy returns the proxy configured via PROXY_URL, PROXY_NO_PROXY and PROXY_PAC, or the default proxy, i.e. the
+// environment, if none of them is set. If the configured proxy can't be used, the returned proxy fails all requests
+// instead of silently bypassing the proxy, and the error is returned as well. The same applies to the error of
+// middleware.NewOfflineError while OFFLINE is enabled.
+func (n *networkImpl) getProxy() (func(req *http.Request) (*url.URL, error), error) {
+	options := n.getProxyOptions()
+	if len(options.ProxyUrl) == 0 && len(options.NoProxy) == 0 && len(options.PacLocation) == 0 {
+		return n.proxy, nil
+	}
+
+	// requests aren't sent while offline, so loading a PAC script would only delay them
+	if n.config.GetBool(configuration.OFFLINE) {
+		err := middleware.NewOfflineError("The offline mode is enabled, the proxy configuration is not loaded.")
+		return func(*http.Request) (*url.URL, error) {
+			return nil, err
+		}, err
+	}
+
+	options.PacClient = n.getPacClient()
+	proxy, err := n.proxyResolver.resolve(options)
+	if err != nil {
+		n.logger.Printf("Failed to configure proxy (%v)", err)
+		return func(*http.Request) (*url.URL, error) {
+			return nil, err
+		}, err
+	}
+	return proxy, nil
+}
+
+// ResolveProxy returns the proxy that the clients of the NetworkAccess use for the request, nil means a direct
+// connection. Other implementations of NetworkAccess are assumed to use the environment.
+func ResolveProxy(networkAccess NetworkAccess, request *http.Request) (*url.URL, error) {
+	impl, ok := networkAccess.(*networkImpl)
+	if !ok {
+		return http.ProxyFromEnvironment(request)
+	}
+
+	proxy, err := impl.getProxy()
+	if err != nil {
+		return nil, err
+	}
+	return proxy(request)
+}
+
+func (n *networkImpl) getProxyOptions() middleware.ProxyOptions {
+	return middleware.ProxyOptions{
+		ProxyUrl:    n.config.GetString(configuration.PROXY_URL),
+		NoProxy:     n.config.GetStringSlice(configuration.PROXY_NO_PROXY),
+		PacLocation: n.config.GetString(configuration.PROXY_PAC),
+	}
+}
+
+// getPacClient returns the client that loads PAC scripts. It connects directly and uses the configured TLS settings,
+// e.g. to load the script from an intranet server with a custom CA.
+func (n *networkImpl) getPacClient() *http.Client {
+	//nolint:errcheck // breaking api change needed to fix this
+	base := http.DefaultTransport.(*http.Transport) //nolint:forcetypeassert // panic here is reasonable
+	transport := middleware.ApplyTlsConfig(base, n.config.GetBool(configuration.INSECURE_HTTPS), n.caPool)
+	transport.Proxy = nil
+	return &http.Client{Transport: transport, Timeout: middleware.DefaultPacLoadTimeout}
+}
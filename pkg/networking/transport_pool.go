@@ -0,0 +1,79 @@
+package networking
+
+import (
+	"crypto/x509"
+	"net/http"
+	"sync"
+
+	"github.com/snyk/go-application-framework/pkg/networking/certs"
+	"github.com/snyk/go-application-framework/pkg/networking/middleware"
+)
+
+// maxPooledTransports limits the number of distinct transport configurations that keep connections open.
+const maxPooledTransports = 16
+
+// transportSettings identify a transport, transports with equal settings can share their connections.
+type transportSettings struct {
+	insecure          bool
+	caPool            *x509.CertPool
+	clientCertificate *certs.ClientCertificateLoader
+	options           transportOptions
+}
+
+// transportOptions are the comparable settings of a transport.
+type transportOptions struct {
+	proxyUrl               string
+	noProxy                string
+	pacLocation            string
+	proxyAuthentication    string
+	apiUrl                 string
+	spkiPins               string
+	timeouts               middleware.TransportTimeouts
+	connectionPoolSettings middleware.ConnectionPoolSettings
+}
+
+func (s *transportSettings) equal(other *transportSettings) bool {
+	return s.insecure == other.insecure &&
+		s.clientCertificate == other.clientCertificate &&
+		s.options == other.options &&
+		s.caPool.Equal(other.caPool)
+}
+
+type pooledTransport struct {
+	settings  transportSettings
+	transport *http.Transport
+}
+
+// transportPool shares transports, and thereby their idle connections, between all clones of a NetworkAccess and
+// between the clients created from them. Without it, every client would establish and authenticate new connections.
+type transportPool struct {
+	mutex sync.Mutex
+	// transports are ordered by their last usage, the most recently used one is the last
+	transports []*pooledTransport
+}
+
+// get returns the transport for the settings, create is only called if no such transport exists. The CA pool of
+// the settings is copied before create is called, so that later changes to it don't affect the pooled transport.
+func (p *transportPool) get(settings transportSettings, create func(settings transportSettings) *http.Transport) *http.Transport {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for i, pooled := range p.transports {
+		if pooled.settings.equal(&settings) {
+			p.transports = append(append(p.transports[:i:i], p.transports[i+1:]...), pooled)
+			return pooled.transport
+		}
+	}
+
+	if settings.caPool != nil {
+		settings.caPool = settings.caPool.Clone()
+	}
+
+	pooled := &pooledTransport{settings: settings, transport: create(settings)}
+	p.transports = append(p.transports, pooled)
+	if len(p.transports) > maxPooledTransports {
+		p.transports[0].transport.CloseIdleConnections()
+		p.transports = p.transports[1:]
+	}
+	return pooled.transport
+}
@@ -3,6 +3,8 @@ package workflow
 import (
 	"fmt"
 	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -11,6 +13,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/snyk/go-application-framework/pkg/configuration"
+	"github.com/snyk/go-application-framework/pkg/networking/middleware"
 	"github.com/snyk/go-application-framework/pkg/runtimeinfo"
 )
 
@@ -159,6 +162,174 @@ func Test_Engine_SetterGlobalValues(t *testing.T) {
 	assert.NotEqual(t, logger, engine.GetLogger())
 }
 
+func Test_Engine_LoadsProjectConfiguration(t *testing.T) {
+	projectDir := t.TempDir()
+	projectConfigFile := filepath.Join(projectDir, ".snyk", "config.yaml")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(projectConfigFile), 0755))
+	assert.NoError(t, os.WriteFile(projectConfigFile, []byte("severity-threshold: high\n"), 0644))
+
+	inputDir := filepath.Join(projectDir, "src")
+	assert.NoError(t, os.MkdirAll(inputDir, 0755))
+
+	config := configuration.NewInMemory()
+	config.Set(configuration.INPUT_DIRECTORY, inputDir)
+	engine := NewWorkFlowEngine(config)
+
+	err := engine.Init()
+	assert.NoError(t, err)
+
+	assert.Equal(t, projectConfigFile, config.GetProjectConfigFile())
+	assert.Equal(t, "high", config.GetString(configuration.FLAG_SEVERITY_THRESHOLD))
+}
+
+func Test_Engine_IgnoresUnsupportedProjectConfiguration(t *testing.T) {
+	projectDir := t.TempDir()
+	projectConfigFile := filepath.Join(projectDir, ".snyk", "config.yaml")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(projectConfigFile), 0755))
+	content := "severity-threshold: high\nsnyk_api: https://attacker.example.com\ninsecure: true\n"
+	assert.NoError(t, os.WriteFile(projectConfigFile, []byte(content), 0644))
+
+	config := configuration.NewInMemory()
+	config.Set(configuration.INPUT_DIRECTORY, projectDir)
+	engine := NewWorkFlowEngine(config)
+	assert.NoError(t, engine.Init())
+
+	assert.Equal(t, projectConfigFile, config.GetProjectConfigFile())
+	assert.Equal(t, "high", config.GetString(configuration.FLAG_SEVERITY_THRESHOLD))
+	assert.NotEqual(t, "https://attacker.example.com", config.GetString(configuration.API_URL))
+	assert.False(t, config.GetBool(configuration.INSECURE_HTTPS))
+}
+
+func Test_Engine_RegisterBindsScopedFlags(t *testing.T) {
+	config := configuration.NewInMemory()
+	engine := NewWorkFlowEngine(config)
+
+	callback := func(invocation InvocationContext, input []Data) ([]Data, error) {
+		return input, nil
+	}
+
+	codeFlags := pflag.NewFlagSet("code test", pflag.ContinueOnError)
+	codeFlags.String(configuration.FLAG_SEVERITY_THRESHOLD, "low", "")
+	_, err := engine.Register(NewWorkflowIdentifier("code test"), ConfigurationOptionsFromFlagset(codeFlags), callback)
+	assert.NoError(t, err)
+
+	iacFlags := pflag.NewFlagSet("iac test", pflag.ContinueOnError)
+	iacFlags.String(configuration.FLAG_SEVERITY_THRESHOLD, "medium", "")
+	_, err = engine.Register(NewWorkflowIdentifier("iac test"), ConfigurationOptionsFromFlagset(iacFlags), callback)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "low", config.Scope("code.test").GetString(configuration.FLAG_SEVERITY_THRESHOLD))
+	assert.Equal(t, "medium", config.Scope("iac.test").GetString(configuration.FLAG_SEVERITY_THRESHOLD))
+}
+
+func Test_Engine_InvocationsUseScopedConfiguration(t *testing.T) {
+	config := configuration.NewInMemory()
+	engine := NewWorkFlowEngine(config)
+	codeId := NewWorkflowIdentifier("code test")
+	legacyId := NewWorkflowIdentifier("legacycli")
+
+	codeFlags := pflag.NewFlagSet("code test", pflag.ContinueOnError)
+	codeFlags.String(configuration.FLAG_SEVERITY_THRESHOLD, "low", "")
+	_, err := engine.Register(codeId, ConfigurationOptionsFromFlagset(codeFlags), func(invocation InvocationContext, input []Data) ([]Data, error) {
+		invocationConfig := invocation.GetConfiguration()
+		assert.Equal(t, "high", invocationConfig.GetString(configuration.FLAG_SEVERITY_THRESHOLD))
+		invocationConfig.Set(configuration.RAW_CMD_ARGS, []string{"--severity-threshold=high"})
+		return invocation.GetEngine().InvokeWithConfig(legacyId, invocationConfig)
+	})
+	assert.NoError(t, err)
+
+	legacyFlags := pflag.NewFlagSet("legacycli", pflag.ContinueOnError)
+	legacyFlags.String(configuration.FLAG_SEVERITY_THRESHOLD, "medium", "")
+	expectedLegacySeverity := "medium"
+	_, err = engine.Register(legacyId, ConfigurationOptionsFromFlagset(legacyFlags), func(invocation InvocationContext, input []Data) ([]Data, error) {
+		invocationConfig := invocation.GetConfiguration()
+		assert.Equal(t, expectedLegacySeverity, invocationConfig.GetString(configuration.FLAG_SEVERITY_THRESHOLD))
+		return input, nil
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, engine.Init())
+
+	// flags are bound globally as well, the last registration determines the global default
+	assert.Equal(t, "medium", config.GetString(configuration.FLAG_SEVERITY_THRESHOLD))
+
+	// invoked on its own, the workflow uses its local default
+	_, err = engine.Invoke(legacyId)
+	assert.NoError(t, err)
+
+	// flags set for the invoking workflow are visible to the invoked workflow
+	assert.NoError(t, codeFlags.Parse([]string{"--severity-threshold=high"}))
+	expectedLegacySeverity = "high"
+	_, err = engine.Invoke(codeId)
+	assert.NoError(t, err)
+	assert.Nil(t, config.Get(configuration.RAW_CMD_ARGS))
+}
+
+func Test_Engine_InvokeFailsForOverriddenLockedValues(t *testing.T) {
+	config := configuration.NewInMemory()
+	engine := NewWorkFlowEngine(config)
+
+	invoked := false
+	workflowId := NewWorkflowIdentifier("locked")
+	_, err := engine.Register(workflowId, ConfigurationOptionsFromFlagset(pflag.NewFlagSet("locked", pflag.ContinueOnError)), func(invocation InvocationContext, input []Data) ([]Data, error) {
+		invoked = true
+		return input, nil
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, engine.Init())
+
+	config.LockValue(configuration.INSECURE_HTTPS, false)
+	_, err = engine.Invoke(workflowId)
+	assert.NoError(t, err)
+	assert.True(t, invoked)
+
+	invoked = false
+	config.Set(configuration.INSECURE_HTTPS, true)
+	_, err = engine.Invoke(workflowId)
+	var lockedErr *configuration.LockedValueError
+	assert.ErrorAs(t, err, &lockedErr)
+	assert.False(t, invoked)
+}
+
+func Test_Engine_InvokeFailsForOverriddenLockedFlags(t *testing.T) {
+	config := configuration.NewInMemory()
+	engine := NewWorkFlowEngine(config)
+
+	var severityThreshold string
+	workflowId := NewWorkflowIdentifier("locked flags")
+	flags := pflag.NewFlagSet("locked flags", pflag.ContinueOnError)
+	flags.String(configuration.FLAG_SEVERITY_THRESHOLD, "", "")
+	_, err := engine.Register(workflowId, ConfigurationOptionsFromFlagset(flags), func(invocation InvocationContext, input []Data) ([]Data, error) {
+		severityThreshold = invocation.GetConfiguration().GetString(configuration.FLAG_SEVERITY_THRESHOLD)
+		return input, nil
+	})
+	assert.NoError(t, err)
+	// another workflow binds the same flag globally
+	otherFlags := pflag.NewFlagSet("other", pflag.ContinueOnError)
+	otherFlags.String(configuration.FLAG_SEVERITY_THRESHOLD, "", "")
+	_, err = engine.Register(NewWorkflowIdentifier("other"), ConfigurationOptionsFromFlagset(otherFlags), func(invocation InvocationContext, input []Data) ([]Data, error) {
+		return input, nil
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, engine.Init())
+	config.LockValue(configuration.FLAG_SEVERITY_THRESHOLD, "high", "critical")
+
+	_, err = engine.Invoke(workflowId)
+	assert.NoError(t, err)
+	assert.Equal(t, "high", severityThreshold)
+
+	severityThreshold = ""
+	assert.NoError(t, flags.Set(configuration.FLAG_SEVERITY_THRESHOLD, "low"))
+	_, err = engine.Invoke(workflowId)
+	var lockedErr *configuration.LockedValueError
+	assert.ErrorAs(t, err, &lockedErr)
+	assert.Empty(t, severityThreshold)
+
+	assert.NoError(t, flags.Set(configuration.FLAG_SEVERITY_THRESHOLD, "critical"))
+	_, err = engine.Invoke(workflowId)
+	assert.NoError(t, err)
+	assert.Equal(t, "critical", severityThreshold)
+}
+
 func Test_Engine_SetterRuntimeInfo(t *testing.T) {
 	ri := runtimeinfo.New()
 	config := configuration.NewInMemory()
@@ -204,6 +375,89 @@ func Test_Engine_ClonedNetworkAccess(t *testing.T) {
 	assert.Equal(t, expected, actual)
 }
 
+func Test_Engine_InvocationsUseDistinctCorrelationIds(t *testing.T) {
+	engine := NewWorkFlowEngine(configuration.NewInMemory())
+
+	correlationIds := []string{}
+	workflowId := NewWorkflowIdentifier("cmd")
+	_, err := engine.Register(workflowId, ConfigurationOptionsFromFlagset(pflag.NewFlagSet("1", pflag.ExitOnError)), func(invocation InvocationContext, input []Data) ([]Data, error) {
+		correlationIds = append(correlationIds, invocation.GetNetworkAccess().GetCorrelationId())
+		return []Data{}, nil
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, engine.Init())
+
+	for range 2 {
+		_, err = engine.Invoke(workflowId)
+		assert.NoError(t, err)
+	}
+
+	assert.Len(t, correlationIds, 2)
+	assert.NotEmpty(t, correlationIds[0])
+	assert.NotEqual(t, correlationIds[0], correlationIds[1])
+	assert.NotEqual(t, engine.GetNetworkAccess().GetCorrelationId(), correlationIds[0])
+}
+
+func Test_Engine_InvocationsPrefetchDefaultValues(t *testing.T) {
+	config := configuration.NewInMemory()
+	engine := NewWorkFlowEngine(config)
+
+	prefetched := make(chan string, 1)
+	config.AddDefaultValue(configuration.ORGANIZATION, func(existingValue interface{}) (interface{}, error) {
+		prefetched <- "org"
+		return "org", nil
+	}, configuration.WithMemoization(0), configuration.WithPrefetch())
+
+	workflowId := NewWorkflowIdentifier("prefetch")
+	callback := func(invocation InvocationContext, input []Data) ([]Data, error) {
+		return []Data{}, nil
+	}
+	_, err := engine.Register(workflowId, ConfigurationOptionsFromFlagset(pflag.NewFlagSet("1", pflag.ExitOnError)), callback)
+	assert.NoError(t, err)
+	assert.NoError(t, engine.Init())
+
+	_, err = engine.Invoke(workflowId)
+	assert.NoError(t, err)
+
+	select {
+	case value := <-prefetched:
+		assert.Equal(t, "org", value)
+	case <-time.After(5 * time.Second):
+		t.Error("default values were not prefetched")
+	}
+}
+
+func Test_Engine_OfflineInvocations(t *testing.T) {
+	config := configuration.NewInMemory()
+	config.Set(configuration.OFFLINE, true)
+	engine := NewWorkFlowEngine(config)
+
+	callback := func(invocation InvocationContext, input []Data) ([]Data, error) {
+		return []Data{}, nil
+	}
+	offlineWorkflowId := NewWorkflowIdentifier("offline")
+	_, err := engine.Register(offlineWorkflowId, ConfigurationOptionsFromFlagset(pflag.NewFlagSet("1", pflag.ExitOnError)), callback)
+	assert.NoError(t, err)
+	onlineWorkflowId := NewWorkflowIdentifier("online")
+	entry, err := engine.Register(onlineWorkflowId, ConfigurationOptionsFromFlagset(pflag.NewFlagSet("2", pflag.ExitOnError)), callback)
+	assert.NoError(t, err)
+	offlineEntry, ok := entry.(OfflineCapableEntry)
+	assert.True(t, ok)
+	if !ok {
+		return
+	}
+	// workflows can run offline unless they declare otherwise
+	assert.True(t, offlineEntry.IsOfflineCapable())
+	offlineEntry.SetOfflineCapable(false)
+	assert.NoError(t, engine.Init())
+
+	_, err = engine.Invoke(onlineWorkflowId)
+	assert.ErrorIs(t, err, middleware.ErrOffline)
+
+	_, err = engine.Invoke(offlineWorkflowId)
+	assert.NoError(t, err)
+}
+
 func Test_EngineInvocationConcurrent(t *testing.T) {
 	configuration := configuration.NewInMemory()
 	engine := NewWorkFlowEngine(configuration)
@@ -1,12 +1,15 @@
 package workflow
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 	"sync"
 
+	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 	zlog "github.com/rs/zerolog/log"
 	"github.com/spf13/pflag"
@@ -14,6 +17,7 @@ import (
 	"github.com/snyk/go-application-framework/pkg/analytics"
 	"github.com/snyk/go-application-framework/pkg/configuration"
 	"github.com/snyk/go-application-framework/pkg/networking"
+	"github.com/snyk/go-application-framework/pkg/networking/middleware"
 	"github.com/snyk/go-application-framework/pkg/runtimeinfo"
 	"github.com/snyk/go-application-framework/pkg/ui"
 )
@@ -118,6 +122,8 @@ func (e *EngineImpl) Init() error {
 
 	_ = e.GetNetworkAccess()
 
+	e.loadProjectConfiguration()
+
 	for i := range e.extensionInitializer {
 		err = e.extensionInitializer[i](e)
 		if err != nil {
@@ -127,6 +133,13 @@ func (e *EngineImpl) Init() error {
 
 	// later scan here for extension binaries
 
+	e.warnAboutUnrecognizedEnvVars()
+
+	// dependency cycles are programming errors, accessing the affected values doesn't fail
+	if dependencyErr := configuration.ValidateDependencies(e.config); dependencyErr != nil {
+		e.logger.Error().Err(dependencyErr).Msg("Failed to add the dependencies of default values")
+	}
+
 	if e.analytics == nil {
 		e.analytics = e.initAnalytics()
 	}
@@ -138,6 +151,37 @@ func (e *EngineImpl) Init() error {
 	return err
 }
 
+// loadProjectConfiguration looks up a project configuration file, e.g. .snyk/config.yaml, starting in the input
+// directory and walking up the directory tree.
+func (e *EngineImpl) loadProjectConfiguration() {
+	file := configuration.FindProjectConfigFile(e.config.GetString(configuration.INPUT_DIRECTORY))
+	if len(file) == 0 {
+		return
+	}
+
+	err := e.config.SetProjectConfigFile(file)
+	if errors.Is(err, configuration.ErrUnsupportedProjectConfigKeys) {
+		e.logger.Warn().Err(err).Str("file", file).Msg("Ignoring values of the project configuration")
+	} else if err != nil {
+		e.logger.Warn().Err(err).Str("file", file).Msg("Failed to read project configuration")
+		return
+	}
+
+	e.logger.Debug().Str("file", file).Msg("Using project configuration")
+}
+
+// warnAboutUnrecognizedEnvVars logs SNYK_ env vars that are not known to the configuration, e.g. due to typos.
+// It is only active once env var mappings have been registered.
+func (e *EngineImpl) warnAboutUnrecognizedEnvVars() {
+	if len(e.config.GetEnvVarMappings()) == 0 {
+		return
+	}
+
+	for _, envVar := range configuration.FindUnrecognizedEnvVars(e.config, os.Environ()) {
+		e.logger.Warn().Str("env", envVar).Msg("Unrecognized environment variable, it is not used by the configuration")
+	}
+}
+
 func (e *EngineImpl) initAnalytics() analytics.Analytics {
 	a := analytics.New()
 	a.SetIntegration(e.config.GetString(configuration.INTEGRATION_NAME), e.config.GetString(configuration.INTEGRATION_VERSION))
@@ -170,6 +214,7 @@ func (e *EngineImpl) Register(id Identifier, config ConfigurationOptions, entryP
 
 	entry := &EntryImpl{
 		visible:        true,
+		offlineCapable: true,
 		expectedConfig: config,
 		entryPoint:     entryPoint,
 	}
@@ -183,6 +228,13 @@ func (e *EngineImpl) Register(id Identifier, config ConfigurationOptions, entryP
 		if err != nil {
 			return nil, err
 		}
+
+		// additionally bind the flags local to the workflow, so that flags with the same name can have different
+		// defaults. Invocations read them via the scope of the workflow, see Configuration.Scope.
+		err = e.config.Scope(id.Host).AddFlagSet(flagset)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return entry, nil
@@ -249,16 +301,35 @@ func (e *EngineImpl) InvokeWithInputAndConfig(
 			prefix := fmt.Sprintf("%s:%d", id.Host, e.invocationCounter)
 			e.mu.Unlock()
 
-			zlogger := e.logger.With().Str("ext", prefix).Logger()
+			// correlate all requests of the invocation
+			correlationId := uuid.NewString()
+			zlogger := e.logger.With().Str("ext", prefix).Str("correlation-id", correlationId).Logger()
 
-			// prepare configuration
+			// prepare configuration, the workflow reads its local flags and values via its scope. If it is invoked
+			// with the configuration of another workflow, the flags set for that workflow remain visible.
 			if config == nil {
 				config = e.config.Clone()
 			}
+			config = configuration.ScopeFrom(config, id.Host)
+
+			// values enforced by a policy must not be overridden
+			if err = configuration.ValidateLockedValues(config); err != nil {
+				return output, err
+			}
+
+			// workflows that need network access would only fail later
+			if offlineEntry, isOfflineEntry := workflow.(OfflineCapableEntry); isOfflineEntry && config.GetBool(configuration.OFFLINE) && !offlineEntry.IsOfflineCapable() {
+				return output, middleware.NewOfflineError(fmt.Sprintf("The offline mode is enabled, but workflow '%v' requires network access.", id))
+			}
+
+			// determine expensive default values, e.g. the organization, in the background once flags and
+			// credentials are known, memoized values are not determined again
+			go config.PrefetchDefaultValues()
 
 			// prepare networkAccess
 			networkAccess := e.networkAccess.Clone()
 			networkAccess.SetConfiguration(config)
+			networkAccess.SetCorrelationId(correlationId)
 
 			// create a context object for the invocation
 			context := NewInvocationContext(id, config, e, networkAccess, zlogger, e.analytics, e.ui)
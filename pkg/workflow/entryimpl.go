@@ -3,10 +3,13 @@ package workflow
 // EntryImpl is the default implementation of the Entry interface.
 type EntryImpl struct {
 	visible        bool
+	offlineCapable bool
 	expectedConfig ConfigurationOptions
 	entryPoint     Callback
 }
 
+var _ OfflineCapableEntry = (*EntryImpl)(nil)
+
 // GetEntryPoint returns the entry point callback for the workflow entry.
 func (e *EntryImpl) GetEntryPoint() Callback {
 	return e.entryPoint
@@ -26,3 +29,14 @@ func (e *EntryImpl) IsVisible() bool {
 func (e *EntryImpl) SetVisibility(visible bool) {
 	e.visible = visible
 }
+
+// IsOfflineCapable returns true if the workflow entry can be invoked while the offline mode is enabled, which is the
+// default.
+func (e *EntryImpl) IsOfflineCapable() bool {
+	return e.offlineCapable
+}
+
+// SetOfflineCapable declares whether the workflow entry can run without network access, see OfflineCapableEntry.
+func (e *EntryImpl) SetOfflineCapable(offlineCapable bool) {
+	e.offlineCapable = offlineCapable
+}
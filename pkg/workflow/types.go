@@ -62,6 +62,13 @@ type Entry interface {
 	SetVisibility(visible bool)
 }
 
+// OfflineCapableEntry is optionally implemented by an Entry to declare whether the workflow can run without network
+// access, see configuration.OFFLINE. Entries that don't implement it are invoked while offline.
+type OfflineCapableEntry interface {
+	IsOfflineCapable() bool
+	SetOfflineCapable(offlineCapable bool)
+}
+
 // Engine is the interface that wraps the methods that are used to manage workflows.
 type Engine interface {
 	Init() error